@@ -0,0 +1,147 @@
+package inspect
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// ErrNotFound is an error that the inspector or scanner not found.
+var ErrNotFound = errors.New("inspect: not found")
+
+// ScanResult the scan result.
+type ScanResult struct {
+	Keys   []string `json:"keys"`   // keys in this iteration
+	Cursor uint64   `json:"cursor"` // next cursor, 0 means the iteration is complete.
+}
+
+type scanner struct {
+	keyPrefix string
+	Scanner
+}
+
+// Handler a read-only http.Handler that exposes the inspectors and scanners as JSON.
+//
+//   - GET /                                       list the registered names.
+//   - GET /{name}?scene=xx&id=xx                  inspect the state of the id in the scene.
+//   - GET /{name}/scan?prefix=xx&cursor=0&count=100 scan the keys with keyPrefix + prefix.
+//
+// mount it with http.StripPrefix when serving under a sub path.
+type Handler struct {
+	mux        *http.ServeMux
+	names      []string
+	inspectors map[string]Inspector
+	scanners   map[string]scanner
+}
+
+// NewHandler new a read-only inspect handler.
+func NewHandler() *Handler {
+	h := &Handler{
+		mux:        http.NewServeMux(),
+		names:      make([]string, 0),
+		inspectors: make(map[string]Inspector),
+		scanners:   make(map[string]scanner),
+	}
+	h.mux.HandleFunc("GET /{$}", h.list)
+	h.mux.HandleFunc("GET /{name}", h.inspect)
+	h.mux.HandleFunc("GET /{name}/scan", h.scan)
+	return h
+}
+
+// Register registers the inspector with the name.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (h *Handler) Register(name string, i Inspector) *Handler {
+	h.addName(name)
+	h.inspectors[name] = i
+	return h
+}
+
+// RegisterScanner registers the scanner with the name, the keyPrefix is the key prefix of the limiter or verifier.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (h *Handler) RegisterScanner(name, keyPrefix string, s Scanner) *Handler {
+	h.addName(name)
+	h.scanners[name] = scanner{keyPrefix: keyPrefix, Scanner: s}
+	return h
+}
+
+func (h *Handler) addName(name string) {
+	if !slices.Contains(h.names, name) {
+		h.names = append(h.names, name)
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) list(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.names)
+}
+
+func (h *Handler) inspect(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.inspectors[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrNotFound)
+		return
+	}
+	query := r.URL.Query()
+	scene, id := query.Get("scene"), query.Get("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, errors.New("inspect: id is required"))
+		return
+	}
+	v, err := i.Inspect(r.Context(), scene, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (h *Handler) scan(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.scanners[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrNotFound)
+		return
+	}
+	query := r.URL.Query()
+	cursor, count := uint64(0), int64(100)
+	if v := query.Get("cursor"); v != "" {
+		c, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("inspect: invalid cursor"))
+			return
+		}
+		cursor = c
+	}
+	if v := query.Get("count"); v != "" {
+		c, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || c <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("inspect: invalid count"))
+			return
+		}
+		count = c
+	}
+	keys, next, err := s.ScanPrefix(r.Context(), s.keyPrefix+query.Get("prefix"), cursor, count)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	writeJSON(w, http.StatusOK, &ScanResult{Keys: keys, Cursor: next})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
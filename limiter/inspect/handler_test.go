@@ -0,0 +1,88 @@
+package inspect_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thinkgos/proc-extra/limiter/inspect"
+	"github.com/thinkgos/proc-extra/limiter/window_limiter"
+	redisV9 "github.com/thinkgos/proc-extra/limiter/window_limiter/redis/v9"
+)
+
+type testScene string
+
+func (s testScene) Value() string { return string(s) }
+
+func Test_MatchPrefix(t *testing.T) {
+	require.Equal(t, "limiter:login:*", inspect.MatchPrefix("limiter:login:"))
+	require.Equal(t, `limiter:\*\?\[a\]\\*`, inspect.MatchPrefix(`limiter:*?[a]\`))
+}
+
+func Test_Handler(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	store := redisV9.NewLimitRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	l := window_limiter.NewSlidingWindowLimiter[testScene](store)
+	for _, id := range []string{"u1", "u2"} {
+		_, err = l.Take(context.Background(), "login", id)
+		require.NoError(t, err)
+	}
+	_, err = l.Lock(context.Background(), "login", "u3")
+	require.NoError(t, err)
+
+	h := inspect.NewHandler().
+		Register("window", inspect.Adapt(l.Inspect)).
+		RegisterScanner("window", l.KeyPrefix(), store).
+		Register("broken", inspect.InspectorFunc(func(ctx context.Context, scene, id string) (any, error) {
+			return nil, errors.New("broken")
+		}))
+
+	do := func(method, target string, v any) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		if v != nil {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v))
+		}
+		return rec.Code
+	}
+
+	var names []string
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/", &names))
+	require.Equal(t, []string{"window", "broken"}, names)
+
+	var st window_limiter.InspectResult
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/window?scene=login&id=u1", &st))
+	require.Equal(t, 1, st.Count)
+	require.False(t, st.Locked)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/window?scene=login&id=u3", &st))
+	require.Equal(t, 0, st.Count)
+	require.True(t, st.Locked)
+
+	var sr inspect.ScanResult
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/window/scan?prefix=login:&count=100", &sr))
+	require.ElementsMatch(t, []string{
+		"window:limiter:login:u1",
+		"window:limiter:login:u2",
+		"window:limiter:login:u3:_locked",
+	}, sr.Keys)
+	require.Zero(t, sr.Cursor)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/window/scan?prefix=register:", &sr))
+	require.Empty(t, sr.Keys)
+
+	require.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/window?scene=login", nil))
+	require.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/window/scan?cursor=x", nil))
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/unknown?scene=login&id=u1", nil))
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/broken/scan", nil))
+	require.Equal(t, http.StatusInternalServerError, do(http.MethodGet, "/broken?scene=login&id=u1", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPost, "/window?scene=login&id=u1", nil))
+}
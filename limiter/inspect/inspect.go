@@ -0,0 +1,63 @@
+// Package inspect provides a read-only admin api for the limiter and verifier state,
+// such as why is this user blocked.
+package inspect
+
+import (
+	"context"
+	"strings"
+)
+
+// Inspector inspect the current state of the id in the scene, it never modifies any data.
+type Inspector interface {
+	Inspect(ctx context.Context, scene, id string) (any, error)
+}
+
+// InspectorFunc an adapter to allow the use of ordinary functions as Inspector.
+type InspectorFunc func(ctx context.Context, scene, id string) (any, error)
+
+// Inspect implements Inspector.
+func (f InspectorFunc) Inspect(ctx context.Context, scene, id string) (any, error) {
+	return f(ctx, scene, id)
+}
+
+// Adapt adapts the typed Inspect method of the limiter or verifier, which scene underlying type is string, to Inspector.
+//
+//	inspect.Adapt(limiter.Inspect)
+func Adapt[S ~string, R any](inspect func(ctx context.Context, scene S, id string) (R, error)) Inspector {
+	return AdaptParse(func(scene string) (S, error) { return S(scene), nil }, inspect)
+}
+
+// AdaptParse adapts the typed Inspect method of the limiter or verifier to Inspector,
+// the scene is parsed from string by parse.
+func AdaptParse[S, R any](parse func(scene string) (S, error), inspect func(ctx context.Context, scene S, id string) (R, error)) Inspector {
+	return InspectorFunc(func(ctx context.Context, scene, id string) (any, error) {
+		s, err := parse(scene)
+		if err != nil {
+			return nil, err
+		}
+		return inspect(ctx, s, id)
+	})
+}
+
+// Scanner scan the keys with the prefix, implemented by redis backends.
+type Scanner interface {
+	// ScanPrefix scan the keys with the prefix, the cursor 0 starts a new iteration,
+	// the returned cursor 0 means the iteration is complete.
+	ScanPrefix(ctx context.Context, prefix string, cursor uint64, count int64) ([]string, uint64, error)
+}
+
+// MatchPrefix returns the redis glob-style pattern matching the keys with the prefix.
+func MatchPrefix(prefix string) string {
+	var b strings.Builder
+
+	b.Grow(len(prefix) + 1)
+	for _, c := range prefix {
+		switch c {
+		case '*', '?', '[', ']', '\\', '^', '-':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteByte('*')
+	return b.String()
+}
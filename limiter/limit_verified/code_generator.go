@@ -0,0 +1,98 @@
+package limit_verified
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+const (
+	// NumericAlphabet numeric code alphabet.
+	NumericAlphabet = "0123456789"
+	// AlphanumericAlphabet alphanumeric code alphabet without ambiguous characters, such as 0/O, 1/I/L.
+	AlphanumericAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+)
+
+// CodeGenerator the verification code generator.
+type CodeGenerator interface {
+	Generate() (string, error)
+}
+
+var (
+	_ CodeGenerator = (*AlphabetCodeGenerator)(nil)
+	_ CodeGenerator = (*TokenCodeGenerator)(nil)
+)
+
+// DefaultCodeGenerator default code generator, 6-digit numeric code.
+var DefaultCodeGenerator CodeGenerator = NewNumericCodeGenerator(6)
+
+// AlphabetCodeGenerator generate a random code with the alphabet use crypto/rand.
+type AlphabetCodeGenerator struct {
+	alphabet string
+	length   int
+}
+
+// NewAlphabetCodeGenerator new a code generator with the alphabet and length.
+// alphabet must not be empty and at most 256 bytes, length must be positive, otherwise it panics.
+func NewAlphabetCodeGenerator(alphabet string, length int) *AlphabetCodeGenerator {
+	if len(alphabet) == 0 || len(alphabet) > 256 {
+		panic(fmt.Sprintf("limit_verified: code generator alphabet length %d out of range [1, 256]", len(alphabet)))
+	}
+	if length <= 0 {
+		panic(fmt.Sprintf("limit_verified: code generator length %d must be positive", length))
+	}
+	return &AlphabetCodeGenerator{alphabet: alphabet, length: length}
+}
+
+// NewNumericCodeGenerator new a numeric code generator with length.
+func NewNumericCodeGenerator(length int) *AlphabetCodeGenerator {
+	return NewAlphabetCodeGenerator(NumericAlphabet, length)
+}
+
+// NewAlphanumericCodeGenerator new a alphanumeric code generator without ambiguous characters with length.
+func NewAlphanumericCodeGenerator(length int) *AlphabetCodeGenerator {
+	return NewAlphabetCodeGenerator(AlphanumericAlphabet, length)
+}
+
+// Generate implements CodeGenerator.
+func (g *AlphabetCodeGenerator) Generate() (string, error) {
+	n := len(g.alphabet)
+	limit := 256 - 256%n // 拒绝采样, 保证均匀分布
+	b := make([]byte, g.length)
+	buf := make([]byte, g.length+g.length/2+1)
+	for i := 0; i < g.length; {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, c := range buf {
+			if int(c) >= limit {
+				continue
+			}
+			b[i] = g.alphabet[int(c)%n]
+			i++
+			if i == g.length {
+				break
+			}
+		}
+	}
+	return string(b), nil
+}
+
+// TokenCodeGenerator generate a url safe random token use crypto/rand, such as magic link token.
+type TokenCodeGenerator struct {
+	size int
+}
+
+// NewTokenCodeGenerator new a token code generator with the random bytes size.
+func NewTokenCodeGenerator(size int) *TokenCodeGenerator {
+	return &TokenCodeGenerator{size: size}
+}
+
+// Generate implements CodeGenerator.
+func (g *TokenCodeGenerator) Generate() (string, error) {
+	b := make([]byte, g.size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
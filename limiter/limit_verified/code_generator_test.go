@@ -0,0 +1,58 @@
+package limit_verified_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thinkgos/proc-extra/limiter/limit_verified"
+)
+
+func Test_CodeGenerator(t *testing.T) {
+	t.Run("numeric", func(t *testing.T) {
+		g := limit_verified.NewNumericCodeGenerator(6)
+		for range 100 {
+			code, err := g.Generate()
+			require.NoError(t, err)
+			require.Len(t, code, 6)
+			for _, c := range code {
+				require.True(t, strings.ContainsRune(limit_verified.NumericAlphabet, c))
+			}
+		}
+	})
+	t.Run("alphanumeric", func(t *testing.T) {
+		g := limit_verified.NewAlphanumericCodeGenerator(8)
+		for range 100 {
+			code, err := g.Generate()
+			require.NoError(t, err)
+			require.Len(t, code, 8)
+			require.False(t, strings.ContainsAny(code, "01OIL"))
+			for _, c := range code {
+				require.True(t, strings.ContainsRune(limit_verified.AlphanumericAlphabet, c))
+			}
+		}
+	})
+	t.Run("invalid alphabet", func(t *testing.T) {
+		require.Panics(t, func() { limit_verified.NewAlphabetCodeGenerator("", 6) })
+		require.Panics(t, func() { limit_verified.NewAlphabetCodeGenerator(strings.Repeat("a", 257), 6) })
+		require.Panics(t, func() { limit_verified.NewAlphabetCodeGenerator(limit_verified.NumericAlphabet, 0) })
+		// 256 symbols, every byte is accepted.
+		alphabet := make([]byte, 256)
+		for i := range alphabet {
+			alphabet[i] = byte(i)
+		}
+		code, err := limit_verified.NewAlphabetCodeGenerator(string(alphabet), 16).Generate()
+		require.NoError(t, err)
+		require.Len(t, code, 16)
+	})
+	t.Run("token", func(t *testing.T) {
+		g := limit_verified.NewTokenCodeGenerator(32)
+		code1, err := g.Generate()
+		require.NoError(t, err)
+		require.Len(t, code1, 43)
+		require.False(t, strings.ContainsAny(code1, "+/="))
+		code2, err := g.Generate()
+		require.NoError(t, err)
+		require.NotEqual(t, code1, code2)
+	})
+}
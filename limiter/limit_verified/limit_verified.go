@@ -12,6 +12,12 @@ import (
 // ErrReachMaximumQuota is an error that reach the maximum quota.
 var ErrReachMaximumQuota = errors.New("limit_verified: reach the maximum quota")
 
+// ErrInspectUnsupported the backend does not implement InspectorBackend.
+var ErrInspectUnsupported = errors.New("limit_verified: backend not support inspect")
+
+// ErrInvalidateUnsupported the backend does not implement InvalidatorBackend.
+var ErrInvalidateUnsupported = errors.New("limit_verified: backend not support invalidate")
+
 type SendCodeResult = EvaluateResult
 
 type SceneValuer interface {
@@ -36,6 +42,8 @@ type Param struct {
 	WindowTiers     []WindowTier  // 子窗口限制, 从小到大排列, 如 [{1min,1}, {4h,5}]
 	CodeExpires     int           // 验证码有效期, 300秒
 	CodeMaxAttempts int           // 验证码最大尝试次数, 3次
+	CodeGenerator   CodeGenerator // 验证码生成器, 默认6位数字, 仅 GenerateAndSendCode 有效
+	Dimensions      []Dimension   // 附加配额维度, 如客户端ip, 设备, 租户, 维度值通过 WithDimension 设置, 未设置值的维度不生效
 }
 
 func NewParam() *Param {
@@ -45,6 +53,7 @@ func NewParam() *Param {
 		WindowTiers:     []WindowTier{{time.Second * 60, 1}},
 		CodeExpires:     300,
 		CodeMaxAttempts: 3,
+		CodeGenerator:   DefaultCodeGenerator,
 	}
 }
 
@@ -60,6 +69,7 @@ type LimitVerified[S SceneValuer, P LimitVerifiedProvider, B LimitVerifiedBacken
 	keyPrefix string                // key prefix
 	param     *Param                // general param
 	scenes    []SceneParam[S]       // scene param.
+	renderer  *MessageRenderer      // message renderer, nil means not render message body.
 }
 
 // NewLimitVerified  new a limit verified
@@ -80,6 +90,13 @@ func (v *LimitVerified[S, P, B]) SetKeyPrefix(keyPrefix string) *LimitVerified[S
 	return v
 }
 
+// SetMessageRenderer sets the message renderer, the message body is rendered before sending code.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (v *LimitVerified[S, P, B]) SetMessageRenderer(r *MessageRenderer) *LimitVerified[S, P, B] {
+	v.renderer = r
+	return v
+}
+
 // SetGeneralParam sets the general param.
 // NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
 func (v *LimitVerified[S, P, B]) SetGeneralParam(p *Param) *LimitVerified[S, P, B] {
@@ -113,11 +130,25 @@ func (v *LimitVerified[S, P, B]) useScene(scene S) *Param {
 func (v *LimitVerified[S, P, B]) Name() string { return v.p.Name() }
 
 // SendCode send code and backend.
+// if the message renderer is set, the message body is rendered with the locale from context, see WithLocale.
 func (v *LimitVerified[S, P, B]) SendCode(ctx context.Context, scene S, target, code string) (*EvaluateResult, error) {
 	p := v.useScene(scene)
+	msg := &Message{
+		Scene:  scene.Value(),
+		Locale: LocaleFromContext(ctx),
+		Code:   code,
+	}
+	if v.renderer != nil {
+		body, err := v.renderer.Render(msg.Scene, msg.Locale, code, p.CodeExpires)
+		if err != nil {
+			return nil, err
+		}
+		msg.Body = body
+	}
 	key := v.formatKey(target)
 	codeKey := v.formatCodeKey(target, scene.Value())
 	uniqueId := UniqueId()
+	dimensions := v.useDimensions(ctx, p)
 	result, err := v.backend.Evaluate(ctx, &EvaluateRequest{
 		Key:             key,
 		CodeKey:         codeKey,
@@ -128,6 +159,7 @@ func (v *LimitVerified[S, P, B]) SendCode(ctx context.Context, scene S, target,
 		CodeMaxAttempts: p.CodeMaxAttempts,
 		Code:            code,
 		UniqueId:        uniqueId,
+		Dimensions:      dimensions,
 	})
 	if err != nil {
 		return nil, err
@@ -139,20 +171,39 @@ func (v *LimitVerified[S, P, B]) SendCode(ctx context.Context, scene S, target,
 	// 当 provider 达到最大配额时返回 ErrReachMaximumQuota, 此时不需要回滚
 	defer func() {
 		if err != nil && !errors.Is(err, ErrReachMaximumQuota) {
+			dimensionKeys := make([]string, 0, len(dimensions))
+			for _, d := range dimensions {
+				dimensionKeys = append(dimensionKeys, d.Key)
+			}
 			_ = v.backend.Rollback(ctx, &RollbackRequest{
-				Key:      key,
-				CodeKey:  codeKey,
-				UniqueId: uniqueId,
+				Key:           key,
+				CodeKey:       codeKey,
+				UniqueId:      uniqueId,
+				DimensionKeys: dimensionKeys,
 			})
 		}
 	}()
-	err = v.p.SendCode(ctx, target, code)
+	result.Provider, err = deliver(ctx, v.p, target, msg)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
+// GenerateAndSendCode generate code with the scene code generator, then send code and backend.
+// the code is never returned to the caller. it is not part of LimitVerifier, use the concrete *LimitVerified.
+func (v *LimitVerified[S, P, B]) GenerateAndSendCode(ctx context.Context, scene S, target string) (*EvaluateResult, error) {
+	g := v.useScene(scene).CodeGenerator
+	if g == nil {
+		g = DefaultCodeGenerator
+	}
+	code, err := g.Generate()
+	if err != nil {
+		return nil, err
+	}
+	return v.SendCode(ctx, scene, target, code)
+}
+
 // VerifyCode verify code from cache.
 func (v *LimitVerified[S, P, B]) VerifyCode(ctx context.Context, scene S, target, code string) (*VerifyResult, error) {
 	return v.backend.Verify(ctx, &VerifyRequest{
@@ -162,9 +213,91 @@ func (v *LimitVerified[S, P, B]) VerifyCode(ctx context.Context, scene S, target
 	})
 }
 
+// InvalidateCode invalidate the code of the scene and target, such as the user changes the target mid-flow.
+// the sent quota is not restored.
+// return ErrInvalidateUnsupported if the backend does not implement InvalidatorBackend.
+func (v *LimitVerified[S, P, B]) InvalidateCode(ctx context.Context, scene S, target string) error {
+	ib, ok := any(v.backend).(InvalidatorBackend)
+	if !ok {
+		return ErrInvalidateUnsupported
+	}
+	return ib.Invalidate(ctx, &InvalidateRequest{
+		Key:     v.formatKey(target),
+		CodeKey: v.formatCodeKey(target, scene.Value()),
+	})
+}
+
+// useDimensions returns the additional dimension limits which value is set in the context.
+func (v *LimitVerified[S, P, B]) useDimensions(ctx context.Context, p *Param) []DimensionLimit {
+	if len(p.Dimensions) == 0 {
+		return nil
+	}
+	dimensions := make([]DimensionLimit, 0, len(p.Dimensions))
+	for _, d := range p.Dimensions {
+		if value, ok := DimensionFromContext(ctx, d.Name); ok {
+			dimensions = append(dimensions, DimensionLimit{
+				Dimension: d,
+				Key:       v.formatDimensionKey(d.Name, value),
+			})
+		}
+	}
+	return dimensions
+}
+
+// Inspect the current state of the target in the scene, include the sent count of
+// each window and the active code without its value, it never modifies any data.
+// return ErrInspectUnsupported if the backend does not implement InspectorBackend.
+func (v *LimitVerified[S, P, B]) Inspect(ctx context.Context, scene S, target string) (*InspectResult, error) {
+	ib, ok := any(v.backend).(InspectorBackend)
+	if !ok {
+		return nil, ErrInspectUnsupported
+	}
+	p := v.useScene(scene)
+	result, err := ib.Inspect(ctx, &InspectRequest{
+		Key:     v.formatKey(target),
+		CodeKey: v.formatCodeKey(target, scene.Value()),
+		Window:  p.Window,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Target = target
+	if result.Code != nil {
+		result.Code.Scene = scene.Value()
+	}
+	result.Window = newTierState(result.Now, result.Records, WindowTier{Window: p.Window, Quota: p.Quota})
+	result.Tiers = make([]TierState, 0, len(p.WindowTiers))
+	for _, tier := range p.WindowTiers {
+		result.Tiers = append(result.Tiers, newTierState(result.Now, result.Records, tier))
+	}
+	return result, nil
+}
+
+// KeyPrefix returns the key prefix.
+func (v *LimitVerified[S, P, B]) KeyPrefix() string { return v.keyPrefix }
+
+func newTierState(now int64, records []int64, tier WindowTier) TierState {
+	window := int64(tier.Window / time.Second)
+	count := 0
+	for _, record := range records {
+		if record >= now-window {
+			count++
+		}
+	}
+	return TierState{
+		Window:    window,
+		Quota:     tier.Quota,
+		Count:     count,
+		Remaining: max(tier.Quota-count, 0),
+	}
+}
+
 func (v *LimitVerified[S, P, B]) formatKey(target string) string {
 	return v.keyPrefix + target
 }
+func (v *LimitVerified[S, P, B]) formatDimensionKey(name, value string) string {
+	return v.keyPrefix + "_dim_:" + name + ":" + value
+}
 func (v *LimitVerified[S, P, B]) formatCodeKey(target, scene string) string {
 	return v.keyPrefix + target + ":_code_:" + scene
 }
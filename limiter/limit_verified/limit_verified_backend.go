@@ -23,30 +23,48 @@ const (
 	VerifyStatus_Success VerifyStatus = iota
 	// VerifyStatus_Failure 验证失败
 	VerifyStatus_Failure
-	// VerifyStatus_Expired 验证码已失效
+	// VerifyStatus_Expired 验证码已失效(不存在, 已过期或已验证成功)
 	VerifyStatus_Expired
+	// VerifyStatus_AttemptsExhausted 验证码尝试次数已用尽
+	VerifyStatus_AttemptsExhausted
 )
 
 // EvaluateRequest store arguments
 type EvaluateRequest struct {
-	Key             string        // 滑动窗口配额key
-	CodeKey         string        // 验证码键
-	Window          time.Duration // 验证码最大滚动窗口时间, 24小时
-	Quota           int           // 验证码最大滚动窗口内配额, 30次
-	WindowTiers     []WindowTier  // 子窗口限制, 从小到大排列, 如 [{1min,3}, {4h,15}]
-	CodeExpires     int           // 验证码有效期, 300秒
-	CodeMaxAttempts int           // 验证码最大允许尝试次数, 3次
-	Code            string        // 验证码
-	UniqueId        string        // 唯一id
+	Key             string           // 滑动窗口配额key
+	CodeKey         string           // 验证码键
+	Window          time.Duration    // 验证码最大滚动窗口时间, 24小时
+	Quota           int              // 验证码最大滚动窗口内配额, 30次
+	WindowTiers     []WindowTier     // 子窗口限制, 从小到大排列, 如 [{1min,3}, {4h,15}]
+	CodeExpires     int              // 验证码有效期, 300秒
+	CodeMaxAttempts int              // 验证码最大允许尝试次数, 3次
+	Code            string           // 验证码
+	UniqueId        string           // 唯一id
+	Dimensions      []DimensionLimit // 附加维度限制
+}
+
+// DimensionLimit the additional dimension limit with its key.
+type DimensionLimit struct {
+	Dimension
+	Key string // 附加维度配额key
 }
 type EvaluateResult struct {
-	Status EvaluateStatus
+	Status        EvaluateStatus
+	Provider      string            // 实际发送验证码的 provider 名称, 仅 SendCode 发送成功时有效
+	Remaining     int               // 最大窗口内剩余配额, 成功时已扣除本次发送
+	TierRemaining []int             // 各子窗口内剩余配额, 与 WindowTiers 一一对应
+	TrippedTier   *WindowTier       // 触发限制的窗口, OverQuota 时为最大窗口, TooFrequently 时为对应的子窗口, 成功时为 nil
+	NextAt        time.Time         // 最早允许再次发送的时间
+	RetryAfter    time.Duration     // 距离最早允许再次发送的时长, 0 表示可以立即发送
+	Dimension     string            // 触发限制的附加维度名称, 为空表示 target 或未触发限制
+	Dimensions    []DimensionResult // 各附加维度内剩余配额, 与生效的附加维度一一对应
 }
 
 type RollbackRequest struct {
-	Key      string // 滑动窗口配额key
-	CodeKey  string // 验证码键
-	UniqueId string // 唯一id
+	Key           string   // 滑动窗口配额key
+	CodeKey       string   // 验证码键
+	UniqueId      string   // 唯一id
+	DimensionKeys []string // 附加维度配额key
 }
 
 type VerifyRequest struct {
@@ -55,7 +73,47 @@ type VerifyRequest struct {
 	Code    string // 验证码
 }
 type VerifyResult struct {
-	Status VerifyStatus
+	Status            VerifyStatus
+	RemainingAttempts int // 剩余尝试次数, 仅 VerifyStatus_Failure 时有效, 为 0 表示验证码已作废
+}
+
+type InvalidateRequest struct {
+	Key     string // 滑动窗口配额key
+	CodeKey string // 验证码键
+}
+
+type InspectRequest struct {
+	Key     string        // 滑动窗口配额key
+	CodeKey string        // 验证码键
+	Window  time.Duration // 验证码最大滚动窗口时间
+}
+
+// TierState the state of a window.
+type TierState struct {
+	Window    int64 `json:"window"`    // 窗口时间, 单位: 秒
+	Quota     int   `json:"quota"`     // 窗口内配额
+	Count     int   `json:"count"`     // 窗口内已发送次数
+	Remaining int   `json:"remaining"` // 窗口内剩余配额
+}
+
+// CodeState the state of the active code, it never contains the code value.
+type CodeState struct {
+	Scene       string `json:"scene"`       // 场景
+	Attempts    int    `json:"attempts"`    // 已尝试次数
+	MaxAttempts int    `json:"maxAttempts"` // 最大允许尝试次数
+	SentAt      int64  `json:"sentAt"`      // 发送时间, unix 时间戳, 单位: 秒
+	ExpireAt    int64  `json:"expireAt"`    // 过期时间, unix 时间戳, 单位: 秒
+}
+
+// InspectResult the current state of the target in a scene.
+// the backend fills Now, Records and Code, the others are filled by LimitVerified.
+type InspectResult struct {
+	Target  string      `json:"target"`  // 目标
+	Now     int64       `json:"now"`     // 当前时间, unix 时间戳, 单位: 秒
+	Window  TierState   `json:"window"`  // 最大窗口状态
+	Tiers   []TierState `json:"tiers"`   // 各子窗口状态, 与 WindowTiers 一一对应
+	Records []int64     `json:"records"` // 最大窗口内的发送时间, unix 时间戳, 单位: 秒
+	Code    *CodeState  `json:"code"`    // 当前有效的验证码, nil 表示没有有效的验证码
 }
 
 type LimitVerifiedBackend interface {
@@ -66,3 +124,15 @@ type LimitVerifiedBackend interface {
 	// Verify code
 	Verify(context.Context, *VerifyRequest) (*VerifyResult, error)
 }
+
+// InvalidatorBackend the optional interface of LimitVerifiedBackend.
+type InvalidatorBackend interface {
+	// Invalidate code, it does not restore the quota.
+	Invalidate(context.Context, *InvalidateRequest) error
+}
+
+// InspectorBackend the optional interface of LimitVerifiedBackend.
+type InspectorBackend interface {
+	// Inspect the current state, it never modifies any data.
+	Inspect(context.Context, *InspectRequest) (*InspectResult, error)
+}
@@ -0,0 +1,43 @@
+package limit_verified
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+// Dimension an additional quota dimension evaluated atomically alongside the target quota,
+// such as client ip, device, tenant.
+type Dimension struct {
+	Name        string        // 维度名称, 如 ip, device, tenant
+	Window      time.Duration // 最大滚动窗口时间
+	Quota       int           // 最大滚动窗口内配额
+	WindowTiers []WindowTier  // 子窗口限制, 从小到大排列
+}
+
+// DimensionResult the remaining quota of an additional dimension.
+type DimensionResult struct {
+	Name          string // 维度名称
+	Remaining     int    // 最大窗口内剩余配额
+	TierRemaining []int  // 各子窗口内剩余配额, 与 WindowTiers 一一对应
+}
+
+type ctxDimensionKey struct{}
+
+// WithDimension returns a copy of parent in which the dimension value is set,
+// the dimension without value is ignored when evaluate.
+func WithDimension(ctx context.Context, name, value string) context.Context {
+	values := make(map[string]string)
+	if parent, ok := ctx.Value(ctxDimensionKey{}).(map[string]string); ok {
+		maps.Copy(values, parent)
+	}
+	values[name] = value
+	return context.WithValue(ctx, ctxDimensionKey{}, values)
+}
+
+// DimensionFromContext returns the dimension value from context.
+func DimensionFromContext(ctx context.Context, name string) (string, bool) {
+	values, _ := ctx.Value(ctxDimensionKey{}).(map[string]string)
+	value, ok := values[name]
+	return value, ok && value != ""
+}
@@ -0,0 +1,129 @@
+package limit_verified
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// ErrMessageTemplateNotFound is an error that the message template not found.
+var ErrMessageTemplateNotFound = errors.New("limit_verified: message template not found")
+
+type ctxLocaleKey struct{}
+
+// WithLocale returns a copy of parent in which the locale value is set,
+// the locale is used to choose the message template.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, ctxLocaleKey{}, locale)
+}
+
+// LocaleFromContext returns the locale value from context.
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(ctxLocaleKey{}).(string)
+	return locale
+}
+
+// MessageData the message template data.
+type MessageData struct {
+	AppName        string // 应用名称
+	Scene          string // 场景
+	Locale         string // 语言区域
+	Code           string // 验证码
+	ExpiresMinutes int    // 验证码有效期, 单位: 分钟
+}
+
+// MessageRenderer the message template renderer keyed by scene and locale.
+type MessageRenderer struct {
+	appName       string
+	defaultLocale string
+	templates     map[string]*template.Template // scene + ":" + locale -> template
+}
+
+// NewMessageRenderer new a message renderer, the default locale is `zh-CN`.
+func NewMessageRenderer(appName string) *MessageRenderer {
+	return &MessageRenderer{
+		appName:       appName,
+		defaultLocale: "zh-CN",
+		templates:     make(map[string]*template.Template),
+	}
+}
+
+// SetDefaultLocale sets the default locale, used when the locale is empty or the template of the locale not found.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (r *MessageRenderer) SetDefaultLocale(locale string) *MessageRenderer {
+	r.defaultLocale = locale
+	return r
+}
+
+// Register parse and register the text template of the scene and locale.
+// template data see MessageData, such as: `【{{.AppName}}】您的验证码是{{.Code}}, {{.ExpiresMinutes}}分钟内有效.`
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (r *MessageRenderer) Register(scene, locale, text string) error {
+	key := r.formatKey(scene, locale)
+	tpl, err := template.New(key).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("limit_verified: parse message template '%s' failure, %w", key, err)
+	}
+	r.templates[key] = tpl
+	return nil
+}
+
+// RegisterFS parse and register the text templates from the file system matched the pattern.
+// the file name format: `{scene}.{locale}{.ext}`, such as `login.zh-CN.tmpl`.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (r *MessageRenderer) RegisterFS(fsys fs.FS, pattern string) error {
+	filenames, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	for _, filename := range filenames {
+		name := path.Base(filename)
+		scene, locale, ok := strings.Cut(strings.TrimSuffix(name, path.Ext(name)), ".")
+		if !ok || scene == "" || locale == "" {
+			return fmt.Errorf("limit_verified: invalid message template file name '%s'", filename)
+		}
+		b, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return err
+		}
+		if err = r.Register(scene, locale, string(b)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Render the message body of the scene and locale,
+// fallback to the default locale if the template of the locale not found.
+func (r *MessageRenderer) Render(scene, locale string, code string, expiresSeconds int) (string, error) {
+	if locale == "" {
+		locale = r.defaultLocale
+	}
+	tpl, ok := r.templates[r.formatKey(scene, locale)]
+	if !ok {
+		tpl, ok = r.templates[r.formatKey(scene, r.defaultLocale)]
+		if !ok {
+			return "", fmt.Errorf("%w, scene: %s, locale: %s", ErrMessageTemplateNotFound, scene, locale)
+		}
+	}
+	b := strings.Builder{}
+	err := tpl.Execute(&b, &MessageData{
+		AppName:        r.appName,
+		Scene:          scene,
+		Locale:         locale,
+		Code:           code,
+		ExpiresMinutes: (expiresSeconds + 59) / 60,
+	})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (r *MessageRenderer) formatKey(scene, locale string) string {
+	return scene + ":" + locale
+}
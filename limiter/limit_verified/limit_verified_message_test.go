@@ -0,0 +1,50 @@
+package limit_verified_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thinkgos/proc-extra/limiter/limit_verified"
+)
+
+func Test_MessageRenderer(t *testing.T) {
+	r := limit_verified.NewMessageRenderer("proc").SetDefaultLocale("en-US")
+	require.NoError(t, r.Register("login", "zh-CN", "【{{.AppName}}】您的登录验证码是{{.Code}}, {{.ExpiresMinutes}}分钟内有效."))
+	require.NoError(t, r.Register("login", "en-US", "[{{.AppName}}] Your login code is {{.Code}}, valid for {{.ExpiresMinutes}} minutes."))
+	require.Error(t, r.Register("login", "ja-JP", "{{.Code"))
+
+	body, err := r.Render("login", "zh-CN", "123456", 300)
+	require.NoError(t, err)
+	require.Equal(t, "【proc】您的登录验证码是123456, 5分钟内有效.", body)
+
+	// fallback to the default locale
+	body, err = r.Render("login", "ja-JP", "123456", 90)
+	require.NoError(t, err)
+	require.Equal(t, "[proc] Your login code is 123456, valid for 2 minutes.", body)
+	body, err = r.Render("login", "", "123456", 300)
+	require.NoError(t, err)
+	require.Equal(t, "[proc] Your login code is 123456, valid for 5 minutes.", body)
+
+	_, err = r.Render("register", "zh-CN", "123456", 300)
+	require.ErrorIs(t, err, limit_verified.ErrMessageTemplateNotFound)
+}
+
+func Test_MessageRenderer_RegisterFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/login.zh-CN.tmpl":    {Data: []byte("登录验证码: {{.Code}}")},
+		"templates/register.en-US.tmpl": {Data: []byte("Register code: {{.Code}}")},
+	}
+	r := limit_verified.NewMessageRenderer("proc")
+	require.NoError(t, r.RegisterFS(fsys, "templates/*.tmpl"))
+
+	body, err := r.Render("login", "zh-CN", "123456", 300)
+	require.NoError(t, err)
+	require.Equal(t, "登录验证码: 123456", body)
+	body, err = r.Render("register", "en-US", "654321", 300)
+	require.NoError(t, err)
+	require.Equal(t, "Register code: 654321", body)
+
+	fsys["templates/invalid.tmpl"] = &fstest.MapFile{Data: []byte("{{.Code}}")}
+	require.Error(t, r.RegisterFS(fsys, "templates/*.tmpl"))
+}
@@ -2,14 +2,57 @@ package limit_verified
 
 import (
 	"context"
+	"errors"
 )
 
+// ErrMessageUnsupported the message body is rendered but the provider does not implement MessageProvider.
+var ErrMessageUnsupported = errors.New("limit_verified: provider not support message")
+
 // LimitVerifiedProvider the provider
 type LimitVerifiedProvider interface {
 	Name() string
 	SendCode(ctx context.Context, target, code string) error
 }
 
+// Message the verification code message.
+type Message struct {
+	Scene  string // 场景
+	Locale string // 语言区域, 如 zh-CN, en-US
+	Code   string // 验证码
+	Body   string // 渲染后的消息正文, 未设置 MessageRenderer 时为空
+}
+
+// MessageProvider the provider which sends the rendered message body.
+type MessageProvider interface {
+	LimitVerifiedProvider
+	// SendMessage send the message to target.
+	SendMessage(ctx context.Context, target string, msg *Message) error
+}
+
+// DeliveryProvider the provider which reports the name of the provider actually delivered the code,
+// such as a composite provider.
+type DeliveryProvider interface {
+	LimitVerifiedProvider
+	// Deliver send the message, return the name of the provider which actually delivered the code.
+	Deliver(ctx context.Context, target string, msg *Message) (string, error)
+}
+
+// deliver send the message with the provider, return the name of the provider which actually delivered the code.
+// the rendered message body can not be sent by the plain provider, return ErrMessageUnsupported.
+func deliver(ctx context.Context, p LimitVerifiedProvider, target string, msg *Message) (string, error) {
+	switch pp := p.(type) {
+	case DeliveryProvider:
+		return pp.Deliver(ctx, target, msg)
+	case MessageProvider:
+		return pp.Name(), pp.SendMessage(ctx, target, msg)
+	default:
+		if msg.Body != "" {
+			return "", ErrMessageUnsupported
+		}
+		return p.Name(), p.SendCode(ctx, target, msg.Code)
+	}
+}
+
 var _ LimitVerifiedProvider = DummyDriver{}
 
 type DummyDriver struct{}
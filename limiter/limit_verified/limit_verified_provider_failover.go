@@ -0,0 +1,127 @@
+package limit_verified
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// WeightedProvider the provider with weight.
+type WeightedProvider struct {
+	Provider LimitVerifiedProvider
+	Weight   int
+}
+
+type failoverEntry struct {
+	p       LimitVerifiedProvider
+	weight  int // 权重, 仅加权轮询有效
+	current int // 当前权重, 仅加权轮询有效
+}
+
+var _ DeliveryProvider = (*FailoverProvider)(nil)
+
+// FailoverProvider composite provider, try providers one by one until one delivered the code.
+// a provider failed with any error (include ErrReachMaximumQuota) is skipped to the next.
+type FailoverProvider struct {
+	name     string
+	weighted bool
+	mu       sync.Mutex
+	entries  []*failoverEntry
+}
+
+// NewPriorityFailoverProvider new a composite provider which tries providers in priority order,
+// the former has higher priority.
+func NewPriorityFailoverProvider(name string, providers ...LimitVerifiedProvider) *FailoverProvider {
+	entries := make([]*failoverEntry, 0, len(providers))
+	for _, p := range providers {
+		entries = append(entries, &failoverEntry{p: p, weight: 1})
+	}
+	return &FailoverProvider{
+		name:     name,
+		weighted: false,
+		entries:  entries,
+	}
+}
+
+// NewWeightedFailoverProvider new a composite provider which picks the first provider by smooth weighted round-robin,
+// then tries the rest in the given order.
+func NewWeightedFailoverProvider(name string, providers ...WeightedProvider) *FailoverProvider {
+	entries := make([]*failoverEntry, 0, len(providers))
+	for _, p := range providers {
+		entries = append(entries, &failoverEntry{p: p.Provider, weight: max(p.Weight, 1)})
+	}
+	return &FailoverProvider{
+		name:     name,
+		weighted: true,
+		entries:  entries,
+	}
+}
+
+// Name implements LimitVerifiedProvider.
+func (f *FailoverProvider) Name() string { return f.name }
+
+// SendCode implements LimitVerifiedProvider.
+func (f *FailoverProvider) SendCode(ctx context.Context, target, code string) error {
+	_, err := f.Deliver(ctx, target, &Message{Code: code})
+	return err
+}
+
+// Deliver implements DeliveryProvider.
+// if all providers reach the maximum quota, return ErrReachMaximumQuota.
+func (f *FailoverProvider) Deliver(ctx context.Context, target string, msg *Message) (string, error) {
+	var errs []error
+
+	reachQuota := 0
+	for _, e := range f.sequence() {
+		name, err := deliver(ctx, e.p, target, msg)
+		if err == nil {
+			return name, nil
+		}
+		if errors.Is(err, ErrReachMaximumQuota) {
+			reachQuota++
+		} else {
+			errs = append(errs, fmt.Errorf("%s: %w", e.p.Name(), err))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		if reachQuota > 0 {
+			return "", ErrReachMaximumQuota
+		}
+		return "", errors.New("limit_verified: failover provider has no available provider")
+	}
+	return "", fmt.Errorf("limit_verified: failover provider '%s' all failure, %w", f.name, errors.Join(errs...))
+}
+
+// sequence returns the providers in the order to try.
+func (f *FailoverProvider) sequence() []*failoverEntry {
+	if !f.weighted || len(f.entries) <= 1 {
+		return f.entries
+	}
+
+	f.mu.Lock()
+	// smooth weighted round-robin
+	total := 0
+	var best *failoverEntry
+	for _, e := range f.entries {
+		e.current += e.weight
+		total += e.weight
+		if best == nil || e.current > best.current {
+			best = e
+		}
+	}
+	best.current -= total
+	f.mu.Unlock()
+
+	seq := make([]*failoverEntry, 0, len(f.entries))
+	seq = append(seq, best)
+	for _, e := range f.entries {
+		if e != best {
+			seq = append(seq, e)
+		}
+	}
+	return seq
+}
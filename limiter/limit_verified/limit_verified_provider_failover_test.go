@@ -0,0 +1,94 @@
+package limit_verified_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thinkgos/proc-extra/limiter/limit_verified"
+)
+
+type testNamedProvider struct {
+	name string
+	err  error
+}
+
+func (t testNamedProvider) Name() string { return t.name }
+func (t testNamedProvider) SendCode(ctx context.Context, target, code string) error {
+	return t.err
+}
+
+func Test_FailoverProvider_Priority(t *testing.T) {
+	quota := testNamedProvider{"quota", limit_verified.ErrReachMaximumQuota}
+	failure := testNamedProvider{"failure", errors.New("vendor unavailable")}
+	ok1 := testNamedProvider{"ok1", nil}
+	ok2 := testNamedProvider{"ok2", nil}
+
+	t.Run("skip to next", func(t *testing.T) {
+		p := limit_verified.NewPriorityFailoverProvider("failover", quota, failure, ok1, ok2)
+		require.Equal(t, "failover", p.Name())
+		for range 3 {
+			name, err := p.Deliver(context.Background(), "target", &limit_verified.Message{Code: "code"})
+			require.NoError(t, err)
+			require.Equal(t, "ok1", name)
+		}
+		require.NoError(t, p.SendCode(context.Background(), "target", "code"))
+	})
+	t.Run("all reach maximum quota", func(t *testing.T) {
+		p := limit_verified.NewPriorityFailoverProvider("failover", quota, quota)
+		_, err := p.Deliver(context.Background(), "target", &limit_verified.Message{Code: "code"})
+		require.ErrorIs(t, err, limit_verified.ErrReachMaximumQuota)
+	})
+	t.Run("all failure", func(t *testing.T) {
+		p := limit_verified.NewPriorityFailoverProvider("failover", quota, failure)
+		_, err := p.Deliver(context.Background(), "target", &limit_verified.Message{Code: "code"})
+		require.Error(t, err)
+		require.NotErrorIs(t, err, limit_verified.ErrReachMaximumQuota)
+	})
+	t.Run("no provider", func(t *testing.T) {
+		p := limit_verified.NewPriorityFailoverProvider("failover")
+		_, err := p.Deliver(context.Background(), "target", &limit_verified.Message{Code: "code"})
+		require.Error(t, err)
+	})
+	t.Run("nested", func(t *testing.T) {
+		p := limit_verified.NewPriorityFailoverProvider("failover",
+			limit_verified.NewPriorityFailoverProvider("sms", quota, failure),
+			limit_verified.NewPriorityFailoverProvider("email", failure, ok2),
+		)
+		name, err := p.Deliver(context.Background(), "target", &limit_verified.Message{Code: "code"})
+		require.NoError(t, err)
+		require.Equal(t, "ok2", name)
+	})
+}
+
+func Test_FailoverProvider_Weighted(t *testing.T) {
+	ok1 := testNamedProvider{"ok1", nil}
+	ok2 := testNamedProvider{"ok2", nil}
+	failure := testNamedProvider{"failure", errors.New("vendor unavailable")}
+
+	t.Run("weighted round-robin", func(t *testing.T) {
+		p := limit_verified.NewWeightedFailoverProvider("weighted",
+			limit_verified.WeightedProvider{Provider: ok1, Weight: 2},
+			limit_verified.WeightedProvider{Provider: ok2, Weight: 1},
+		)
+		got := make([]string, 0, 6)
+		for range 6 {
+			name, err := p.Deliver(context.Background(), "target", &limit_verified.Message{Code: "code"})
+			require.NoError(t, err)
+			got = append(got, name)
+		}
+		require.Equal(t, []string{"ok1", "ok2", "ok1", "ok1", "ok2", "ok1"}, got)
+	})
+	t.Run("skip to next", func(t *testing.T) {
+		p := limit_verified.NewWeightedFailoverProvider("weighted",
+			limit_verified.WeightedProvider{Provider: failure, Weight: 1},
+			limit_verified.WeightedProvider{Provider: ok2, Weight: 1},
+		)
+		for range 4 {
+			name, err := p.Deliver(context.Background(), "target", &limit_verified.Message{Code: "code"})
+			require.NoError(t, err)
+			require.Equal(t, "ok2", name)
+		}
+	})
+}
@@ -45,6 +45,18 @@ func Test_LimitVerifiedSuccess(t *testing.T) {
 	)
 }
 
+func Test_LimitVerifiedGenerateAndSendCode(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.Nil(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_GenerateAndSendCode(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
 func Test_LimitVerifiedSendCode_Failure(t *testing.T) {
 	mr, err := miniredis.Run()
 	require.Nil(t, err)
@@ -57,6 +69,30 @@ func Test_LimitVerifiedSendCode_Failure(t *testing.T) {
 	)
 }
 
+func Test_LimitVerifiedSendCode_Failover(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.Nil(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_SendCode_Failover(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_LimitVerifiedSendCode_MessageRenderer(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.Nil(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_SendCode_MessageRenderer(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
 func Test_LimitVerifiedSendCode_OverQuota(t *testing.T) {
 	mr, err := miniredis.Run()
 	require.Nil(t, err)
@@ -81,6 +117,30 @@ func Test_LimitVerifiedSendCode_ResendTooFrequently(t *testing.T) {
 	)
 }
 
+func Test_LimitVerifiedSendCode_QuotaDetails(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.Nil(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_SendCode_QuotaDetails(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_LimitVerifiedSendCode_Dimensions(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.Nil(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_SendCode_Dimensions(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
 func Test_LimitVerifiedSendCode_Rollback(t *testing.T) {
 	mr, err := miniredis.Run()
 	require.Nil(t, err)
@@ -116,3 +176,39 @@ func Test_LimitVerifiedVerifyCode_ReachMaxAttempt(t *testing.T) {
 		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
 	)
 }
+
+func Test_LimitVerifiedVerifyCode_RemainingAttempts(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.Nil(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_VerifyCode_RemainingAttempts(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_LimitVerifiedInvalidateCode(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.Nil(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_InvalidateCode(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_LimitVerifiedInspect(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.Nil(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_Inspect(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
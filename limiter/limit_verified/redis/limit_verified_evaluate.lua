@@ -1,38 +1,102 @@
-local key = KEYS[1]                         -- 滑动窗口配额key (keyPrefix + target)
 local code_key = KEYS[2]                    -- 验证码key (keyPrefix + target + ":" + scene + ":code")
-local window = tonumber(ARGV[1])            -- 最大滚动窗口时间, 单位: 秒
-local quota = tonumber(ARGV[2])             -- 最大滚动窗口内配额
-local code_expires = tonumber(ARGV[3])      -- 验证码有效期, 单位: 秒
-local code_max_attempts = tonumber(ARGV[4]) -- 验证码最大允许尝试次数
-local code = ARGV[5]                        -- 验证码
-local unique_id = ARGV[6]                   -- 唯一id
-local tier_count = tonumber(ARGV[7])        -- 子窗口数量
+local code_expires = tonumber(ARGV[1])      -- 验证码有效期, 单位: 秒
+local code_max_attempts = tonumber(ARGV[2]) -- 验证码最大允许尝试次数
+local code = ARGV[3]                        -- 验证码
+local unique_id = ARGV[4]                   -- 唯一id
+-- ARGV[5...] 依次为 KEYS[1] 滑动窗口配额key(keyPrefix + target) 及 KEYS[3...] 附加维度key 的限制:
+-- 最大滚动窗口时间(秒), 最大滚动窗口内配额, 子窗口数量, [子窗口时间(秒), 子窗口内配额]...
 
 local time_res = redis.call('TIME')         -- 获取redis节点当前时间.
 local now = tonumber(time_res[1])           -- 当前时间戳, 单位秒
 
--- 检查最大窗口
-redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window) -- 清除最大窗口之外的过期记录
-local current_count = redis.call('ZCARD', key)            -- 统计最大窗口内的记录
-if current_count >= quota then                            -- 超过最大窗口配额
-    return 1                                              -- 超过配额
+-- 解析限制
+local limits = {}
+local idx = 5
+for k = 1, #KEYS do
+    if k ~= 2 then
+        local limit = { key = KEYS[k], window = tonumber(ARGV[idx]), quota = tonumber(ARGV[idx + 1]), tiers = {} }
+        local tier_count = tonumber(ARGV[idx + 2])
+        idx = idx + 3
+        for i = 1, tier_count do
+            limit.tiers[i] = { window = tonumber(ARGV[idx]), quota = tonumber(ARGV[idx + 1]) }
+            idx = idx + 2
+        end
+        limits[#limits + 1] = limit
+    end
+end
+
+-- 统计最大窗口及各子窗口内的记录
+local function count_windows()
+    for _, limit in ipairs(limits) do
+        limit.count = redis.call('ZCARD', limit.key)
+        for _, tier in ipairs(limit.tiers) do
+            tier.count = redis.call('ZCOUNT', limit.key, now - tier.window, now)
+        end
+    end
+end
+
+-- 计算窗口内记录数降到配额以下的最早时间
+local function next_at()
+    local result = now
+    for _, limit in ipairs(limits) do
+        if limit.count >= limit.quota then
+            -- 最大窗口内记录 score > now - window, 第 (count - quota + 1) 条过期后可再次发送
+            local offset = limit.count - limit.quota
+            local res = redis.call('ZRANGE', limit.key, offset, offset, 'WITHSCORES')
+            if res[2] then
+                result = math.max(result, tonumber(res[2]) + limit.window)
+            end
+        end
+        for _, tier in ipairs(limit.tiers) do
+            if tier.count >= tier.quota then
+                -- 子窗口内记录 score >= now - tier_window
+                local res = redis.call('ZRANGEBYSCORE', limit.key, now - tier.window, now, 'WITHSCORES',
+                    'LIMIT', tier.count - tier.quota, 1)
+                if res[2] then
+                    result = math.max(result, tonumber(res[2]) + tier.window + 1)
+                end
+            end
+        end
+    end
+    return result
 end
 
--- 检查子窗口
-for i = 0, tier_count - 1 do
-    local tier_window = tonumber(ARGV[8 + i * 2])
-    local tier_quota = tonumber(ARGV[8 + i * 2 + 1])
-    local tier_count_in_window = redis.call('ZCOUNT', key, now - tier_window, now)
-    if tier_count_in_window >= tier_quota then
-        return 2 -- 发送过于频繁
+-- 返回: 状态, 触发限制的维度(0: target, 大于0: 附加维度), 触发限制的窗口(0: 最大窗口, 大于0: 子窗口),
+-- 最早允许再次发送的时间, 当前时间, [最大窗口剩余配额, 各子窗口剩余配额...]...
+local function reply(status, tripped_limit, tripped_tier)
+    local res = { status, tripped_limit, tripped_tier, next_at(), now }
+    for _, limit in ipairs(limits) do
+        res[#res + 1] = math.max(limit.quota - limit.count, 0)
+        for _, tier in ipairs(limit.tiers) do
+            res[#res + 1] = math.max(tier.quota - tier.count, 0)
+        end
     end
+    return res
 end
 
--- 3. 全部通过, 记录本次操作
-redis.call('ZADD', key, now, unique_id) -- 记录本次操作
-redis.call('EXPIRE', key, window)       -- 设置 Key 的过期时间为最大窗口
+for _, limit in ipairs(limits) do
+    redis.call('ZREMRANGEBYSCORE', limit.key, '-inf', now - limit.window) -- 清除最大窗口之外的过期记录
+end
+count_windows()
+for i, limit in ipairs(limits) do
+    if limit.count >= limit.quota then -- 超过最大窗口配额
+        return reply(1, i - 1, 0)      -- 超过配额
+    end
+    for j, tier in ipairs(limit.tiers) do
+        if tier.count >= tier.quota then
+            return reply(2, i - 1, j) -- 发送过于频繁
+        end
+    end
+end
+
+-- 全部通过, 记录本次操作
+for _, limit in ipairs(limits) do
+    redis.call('ZADD', limit.key, now, unique_id) -- 记录本次操作
+    redis.call('EXPIRE', limit.key, limit.window) -- 设置 Key 的过期时间为最大窗口
+end
 redis.call("HSET", code_key, "code", code, "max_attempts", code_max_attempts, "attempts", 0, "lasted", now,
     "id", unique_id)
 redis.call("EXPIRE", code_key, code_expires)
 
-return 0 -- 成功
+count_windows()
+return reply(0, -1, -1) -- 成功
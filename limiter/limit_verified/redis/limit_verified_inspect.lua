@@ -0,0 +1,18 @@
+local key = KEYS[1]                         -- 滑动窗口配额key (keyPrefix + target)
+local code_key = KEYS[2]                    -- 验证码key (keyPrefix + target + ":" + scene + ":code")
+local window = tonumber(ARGV[1])            -- 最大滚动窗口时间, 单位: 秒
+
+local time_res = redis.call('TIME')         -- 获取redis节点当前时间.
+local now = tonumber(time_res[1])           -- 当前时间戳, 单位秒
+
+-- 只读, 不清除过期记录, 不返回验证码值
+local code_ttl = redis.call('TTL', code_key)
+local vals = redis.call('HMGET', code_key, "attempts", "max_attempts", "lasted")
+local records = redis.call('ZRANGEBYSCORE', key, '(' .. (now - window), '+inf', 'WITHSCORES')
+
+-- 返回: 当前时间, 验证码剩余时间, 尝试次数, 最大允许尝试次数, 发送时间, [最大窗口内发送时间戳...]
+local res = { now, code_ttl, tonumber(vals[1]) or 0, tonumber(vals[2]) or 0, tonumber(vals[3]) or 0 }
+for i = 2, #records, 2 do
+    res[#res + 1] = tonumber(records[i])
+end
+return res
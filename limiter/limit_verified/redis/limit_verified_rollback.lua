@@ -1,8 +1,12 @@
 local key = KEYS[1]                             -- 验证目标key (keyPrefix + target)
 local code_key = KEYS[2]                        -- 验证码key (keyPrefix + target + ":" + scene + ":code")
 local unique_id = ARGV[1]                       -- 唯一id
+-- KEYS[3...] 附加维度key
 
 redis.call("ZREM", key, unique_id)
+for k = 3, #KEYS do
+	redis.call("ZREM", KEYS[k], unique_id)
+end
 local current_unique_id = redis.call("HGET", code_key, "id")
 if current_unique_id == unique_id then
 	redis.call("DEL", code_key)
@@ -3,7 +3,7 @@ local code_key = KEYS[2]                        -- 验证码key (keyPrefix + tar
 local code = ARGV[1]                            -- 验证码
 
 if redis.call("EXISTS", code_key) == 0 then
-    return 2 -- 验证码已失效
+    return { 2, 0 } -- 验证码已失效
 end
 
 local vals = redis.call('HMGET', code_key, "attempts", "max_attempts", "code")
@@ -12,13 +12,13 @@ local max_attempts = tonumber(vals[2])
 local current_code = vals[3]
 
 if attempts >= max_attempts then -- 尝试次数达到最大限制
-    return 2                     -- 验证码已失效
+    return { 3, 0 }              -- 尝试次数已用尽
 end
 
 if current_code == code then
-    redis.call("HSET", code_key, "attempts", max_attempts) -- 置失效
-    return 0                                               -- 成功
+    redis.call("DEL", code_key) -- 验证成功, 置失效
+    return { 0, 0 }             -- 成功
 else
-    redis.call('HINCRBY', code_key, "attempts", 1)         -- 递增尝试次数
-    return 1                                               -- 验证码错误
+    attempts = redis.call('HINCRBY', code_key, "attempts", 1) -- 递增尝试次数
+    return { 1, max_attempts - attempts }                      -- 验证码错误, 剩余尝试次数
 end
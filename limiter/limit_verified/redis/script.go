@@ -9,9 +9,16 @@ const (
 )
 
 const (
-	InnerLimitVerifiedVerify_Success = 0 // 成功
-	InnerLimitVerifiedVerify_Failure = 1 // 失败
-	InnerLimitVerifiedVerify_Expired = 2 // 已失效
+	InnerLimitVerifiedEvaluate_TrippedNone   = -1 // 未触发限制
+	InnerLimitVerifiedEvaluate_TrippedWindow = 0  // 触发最大窗口限制, 大于0表示触发对应的子窗口(从1开始)
+	InnerLimitVerifiedEvaluate_TrippedTarget = 0  // 触发 target 限制, 大于0表示触发对应的附加维度(从1开始)
+)
+
+const (
+	InnerLimitVerifiedVerify_Success           = 0 // 成功
+	InnerLimitVerifiedVerify_Failure           = 1 // 失败
+	InnerLimitVerifiedVerify_Expired           = 2 // 已失效
+	InnerLimitVerifiedVerify_AttemptsExhausted = 3 // 尝试次数已用尽
 )
 
 var (
@@ -21,4 +28,6 @@ var (
 	ScriptLimitVerifiedRollback string
 	//go:embed limit_verified_verify_code.lua
 	ScriptLimitVerifiedVerifyCode string
+	//go:embed limit_verified_inspect.lua
+	ScriptLimitVerifiedInspect string
 )
@@ -2,15 +2,23 @@ package v9
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/thinkgos/proc-extra/limiter/inspect"
 	"github.com/thinkgos/proc-extra/limiter/limit_verified"
 	redis_script "github.com/thinkgos/proc-extra/limiter/limit_verified/redis"
 )
 
+var (
+	_ limit_verified.LimitVerifiedBackend = (*RedisStore)(nil)
+	_ limit_verified.InvalidatorBackend   = (*RedisStore)(nil)
+	_ limit_verified.InspectorBackend     = (*RedisStore)(nil)
+)
+
 // RedisStore verified captcha limit
 type RedisStore struct {
 	store *redis.Client // store client
@@ -22,54 +30,110 @@ func NewRedisStore(store *redis.Client) *RedisStore {
 }
 
 func (v *RedisStore) Evaluate(ctx context.Context, p *limit_verified.EvaluateRequest) (*limit_verified.EvaluateResult, error) {
+	keys := []string{
+		p.Key,
+		p.CodeKey,
+	}
 	args := []string{
-		strconv.FormatInt(int64(p.Window/time.Second), 10),
-		strconv.Itoa(p.Quota),
 		strconv.Itoa(p.CodeExpires),
 		strconv.Itoa(p.CodeMaxAttempts),
 		p.Code,
 		p.UniqueId,
-		strconv.Itoa(len(p.WindowTiers)),
 	}
-	for _, tier := range p.WindowTiers {
-		args = append(args,
-			strconv.FormatInt(int64(tier.Window/time.Second), 10),
-			strconv.Itoa(tier.Quota),
-		)
+	args = appendLimitArgs(args, p.Window, p.Quota, p.WindowTiers)
+	for _, d := range p.Dimensions {
+		keys = append(keys, d.Key)
+		args = appendLimitArgs(args, d.Window, d.Quota, d.WindowTiers)
 	}
 
-	sts, err := v.store.Eval(
+	// reply: [status, tripped_limit, tripped_tier, next_at, now, [remaining, tier_remaining...]...]
+	vals, err := v.store.Eval(
 		ctx,
 		redis_script.ScriptLimitVerifiedEvaluate,
-		[]string{
-			p.Key,
-			p.CodeKey,
-		},
+		keys,
 		args,
-	).Int64()
+	).Int64Slice()
 	if err != nil {
 		return nil, err
 	}
-	return &limit_verified.EvaluateResult{
-		Status: limit_verified.EvaluateStatus(sts),
-	}, nil
+	want := 5 + 1 + len(p.WindowTiers)
+	for _, d := range p.Dimensions {
+		want += 1 + len(d.WindowTiers)
+	}
+	if len(vals) < want {
+		return nil, fmt.Errorf("limit_verified: unexpected evaluate reply length %d", len(vals))
+	}
+
+	trippedLimit, trippedTier := int(vals[1]), int(vals[2])
+	result := &limit_verified.EvaluateResult{
+		Status:     limit_verified.EvaluateStatus(vals[0]),
+		NextAt:     time.Unix(vals[3], 0),
+		RetryAfter: time.Duration(vals[3]-vals[4]) * time.Second,
+		Dimensions: make([]limit_verified.DimensionResult, 0, len(p.Dimensions)),
+	}
+	vals = vals[5:]
+	result.Remaining, result.TierRemaining, vals = parseRemaining(vals, len(p.WindowTiers))
+	for _, d := range p.Dimensions {
+		dr := limit_verified.DimensionResult{Name: d.Name}
+		dr.Remaining, dr.TierRemaining, vals = parseRemaining(vals, len(d.WindowTiers))
+		result.Dimensions = append(result.Dimensions, dr)
+	}
+
+	if trippedLimit == redis_script.InnerLimitVerifiedEvaluate_TrippedNone {
+		return result, nil
+	}
+	window, quota, tiers := p.Window, p.Quota, p.WindowTiers
+	if trippedLimit > redis_script.InnerLimitVerifiedEvaluate_TrippedTarget && trippedLimit <= len(p.Dimensions) {
+		d := p.Dimensions[trippedLimit-1]
+		result.Dimension = d.Name
+		window, quota, tiers = d.Window, d.Quota, d.WindowTiers
+	}
+	switch {
+	case trippedTier == redis_script.InnerLimitVerifiedEvaluate_TrippedWindow:
+		result.TrippedTier = &limit_verified.WindowTier{Window: window, Quota: quota}
+	case trippedTier > 0 && trippedTier <= len(tiers):
+		tier := tiers[trippedTier-1]
+		result.TrippedTier = &tier
+	}
+	return result, nil
+}
+
+func appendLimitArgs(args []string, window time.Duration, quota int, tiers []limit_verified.WindowTier) []string {
+	args = append(args,
+		strconv.FormatInt(int64(window/time.Second), 10),
+		strconv.Itoa(quota),
+		strconv.Itoa(len(tiers)),
+	)
+	for _, tier := range tiers {
+		args = append(args,
+			strconv.FormatInt(int64(tier.Window/time.Second), 10),
+			strconv.Itoa(tier.Quota),
+		)
+	}
+	return args
+}
+
+func parseRemaining(vals []int64, tierCount int) (int, []int, []int64) {
+	tierRemaining := make([]int, tierCount)
+	for i := range tierRemaining {
+		tierRemaining[i] = int(vals[1+i])
+	}
+	return int(vals[0]), tierRemaining, vals[1+tierCount:]
 }
 
 func (v *RedisStore) Rollback(ctx context.Context, p *limit_verified.RollbackRequest) error {
 	return v.store.Eval(
 		ctx,
 		redis_script.ScriptLimitVerifiedRollback,
-		[]string{
-			p.Key,
-			p.CodeKey,
-		},
+		append([]string{p.Key, p.CodeKey}, p.DimensionKeys...),
 		[]string{p.UniqueId},
 	).Err()
 }
 
 // VerifyCode verify code from redis cache.
 func (v *RedisStore) Verify(ctx context.Context, p *limit_verified.VerifyRequest) (*limit_verified.VerifyResult, error) {
-	sts, err := v.store.Eval(
+	// reply: [status, remaining_attempts]
+	vals, err := v.store.Eval(
 		ctx,
 		redis_script.ScriptLimitVerifiedVerifyCode,
 		[]string{
@@ -77,11 +141,58 @@ func (v *RedisStore) Verify(ctx context.Context, p *limit_verified.VerifyRequest
 			p.CodeKey,
 		},
 		[]string{p.Code},
-	).Int64()
+	).Int64Slice()
 	if err != nil {
 		return nil, err
 	}
+	if len(vals) < 2 {
+		return nil, fmt.Errorf("limit_verified: unexpected verify reply length %d", len(vals))
+	}
 	return &limit_verified.VerifyResult{
-		Status: limit_verified.VerifyStatus(sts),
+		Status:            limit_verified.VerifyStatus(vals[0]),
+		RemainingAttempts: int(vals[1]),
 	}, nil
 }
+
+// Invalidate implements [limit_verified.InvalidatorBackend], invalidate code from redis cache.
+func (v *RedisStore) Invalidate(ctx context.Context, p *limit_verified.InvalidateRequest) error {
+	return v.store.Del(ctx, p.CodeKey).Err()
+}
+
+// Inspect implements [limit_verified.InspectorBackend].
+func (v *RedisStore) Inspect(ctx context.Context, p *limit_verified.InspectRequest) (*limit_verified.InspectResult, error) {
+	// reply: [now, code_ttl, attempts, max_attempts, lasted, records...]
+	vals, err := v.store.Eval(
+		ctx,
+		redis_script.ScriptLimitVerifiedInspect,
+		[]string{
+			p.Key,
+			p.CodeKey,
+		},
+		[]string{strconv.FormatInt(int64(p.Window/time.Second), 10)},
+	).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) < 5 {
+		return nil, fmt.Errorf("limit_verified: unexpected inspect reply length %d", len(vals))
+	}
+	result := &limit_verified.InspectResult{
+		Now:     vals[0],
+		Records: vals[5:],
+	}
+	if vals[1] > 0 {
+		result.Code = &limit_verified.CodeState{
+			Attempts:    int(vals[2]),
+			MaxAttempts: int(vals[3]),
+			SentAt:      vals[4],
+			ExpireAt:    vals[0] + vals[1],
+		}
+	}
+	return result, nil
+}
+
+// ScanPrefix implements [inspect.Scanner].
+func (v *RedisStore) ScanPrefix(ctx context.Context, prefix string, cursor uint64, count int64) ([]string, uint64, error) {
+	return v.store.Scan(ctx, cursor, inspect.MatchPrefix(prefix), count).Result()
+}
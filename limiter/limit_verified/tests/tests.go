@@ -59,6 +59,51 @@ func (t TestErrProvider) SendCode(ctx context.Context, target, code string) erro
 	return errors.New("发送失败")
 }
 
+type TestCaptureProvider struct {
+	mu    sync.Mutex
+	codes map[string]string
+}
+
+func (t *TestCaptureProvider) Name() string { return "test_provider3" }
+func (t *TestCaptureProvider) SendCode(ctx context.Context, target, code string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.codes == nil {
+		t.codes = make(map[string]string)
+	}
+	t.codes[target] = code
+	return nil
+}
+func (t *TestCaptureProvider) Code(target string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.codes[target]
+}
+
+type TestMessageProvider struct {
+	mu   sync.Mutex
+	msgs map[string]*limit_verified.Message
+}
+
+func (t *TestMessageProvider) Name() string { return "test_provider4" }
+func (t *TestMessageProvider) SendCode(ctx context.Context, target, code string) error {
+	return errors.New("unexpected send code")
+}
+func (t *TestMessageProvider) SendMessage(ctx context.Context, target string, msg *limit_verified.Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.msgs == nil {
+		t.msgs = make(map[string]*limit_verified.Message)
+	}
+	t.msgs[target] = msg
+	return nil
+}
+func (t *TestMessageProvider) Message(target string) *limit_verified.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.msgs[target]
+}
+
 func GenericTest_Name[B limit_verified.LimitVerifiedBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
 	l := limit_verified.NewLimitVerified[testScene](new(TestProvider), backend).SetKeyPrefix(testKeyPrefix)
 	require.Equal(t, "test_provider1", l.Name())
@@ -71,6 +116,7 @@ func GenericTest_Work[B limit_verified.LimitVerifiedBackend](t *testing.T, _ *mi
 	result, err := l.SendCode(context.Background(), testSceneNormal, target, code)
 	require.NoError(t, err)
 	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	require.Equal(t, "test_provider1", result.Provider)
 
 	vr, err := l.VerifyCode(context.Background(), testSceneNormal, target, code)
 	require.NoError(t, err)
@@ -209,7 +255,7 @@ func GenericTest_GenericParam[B limit_verified.LimitVerifiedBackend](t *testing.
 }
 
 func GenericTest_VerifyCode_ReachMaxAttempt[B limit_verified.LimitVerifiedBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
-	var failedExpired uint32
+	var failedExhausted uint32
 	var failedVerify uint32
 
 	l := limit_verified.NewLimitVerified[testScene](new(TestProvider), backend).
@@ -230,9 +276,9 @@ func GenericTest_VerifyCode_ReachMaxAttempt[B limit_verified.LimitVerifiedBacken
 			switch vr.Status {
 			case limit_verified.VerifyStatus_Failure:
 				atomic.AddUint32(&failedVerify, 1)
-			case limit_verified.VerifyStatus_Expired:
-				atomic.AddUint32(&failedExpired, 1)
-			case limit_verified.VerifyStatus_Success:
+			case limit_verified.VerifyStatus_AttemptsExhausted:
+				atomic.AddUint32(&failedExhausted, 1)
+			case limit_verified.VerifyStatus_Success, limit_verified.VerifyStatus_Expired:
 				fallthrough
 			default:
 				require.Fail(t, "unexpected verify code")
@@ -241,5 +287,345 @@ func GenericTest_VerifyCode_ReachMaxAttempt[B limit_verified.LimitVerifiedBacken
 	}
 	wg.Wait()
 	require.Equal(t, uint32(3), failedVerify)
-	require.Equal(t, uint32(12), failedExpired)
+	require.Equal(t, uint32(12), failedExhausted)
+}
+
+func GenericTest_GenerateAndSendCode[B limit_verified.LimitVerifiedBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	p := new(TestCaptureProvider)
+	l := limit_verified.NewLimitVerified[testScene](p, backend).
+		SetSceneParam(testSceneNormal, testParamNormal).
+		SetSceneParam(testSceneTierLimit, &limit_verified.Param{
+			Window:          time.Hour * 24,
+			Quota:           30,
+			CodeExpires:     300,
+			CodeMaxAttempts: 3,
+			CodeGenerator:   limit_verified.NewAlphanumericCodeGenerator(8),
+		})
+
+	// default code generator
+	result, err := l.GenerateAndSendCode(context.Background(), testSceneNormal, target)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	gotCode := p.Code(target)
+	require.Len(t, gotCode, 6)
+
+	vr, err := l.VerifyCode(context.Background(), testSceneNormal, target, gotCode)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.VerifyStatus_Success, vr.Status)
+
+	// scene code generator
+	result, err = l.GenerateAndSendCode(context.Background(), testSceneTierLimit, target)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	gotCode = p.Code(target)
+	require.Len(t, gotCode, 8)
+
+	vr, err = l.VerifyCode(context.Background(), testSceneTierLimit, target, gotCode)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.VerifyStatus_Success, vr.Status)
+}
+
+func GenericTest_SendCode_Failover[B limit_verified.LimitVerifiedBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	p := limit_verified.NewPriorityFailoverProvider("failover", new(TestErrProvider), new(TestProvider))
+	l := limit_verified.NewLimitVerified[testScene](p, backend).
+		SetSceneParam(testSceneNormal, testParamNormal)
+	require.Equal(t, "failover", l.Name())
+
+	result, err := l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	require.Equal(t, "test_provider1", result.Provider)
+
+	vr, err := l.VerifyCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.VerifyStatus_Success, vr.Status)
+}
+
+func GenericTest_SendCode_MessageRenderer[B limit_verified.LimitVerifiedBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	renderer := limit_verified.NewMessageRenderer("proc")
+	require.NoError(t, renderer.Register(testSceneNormal.Value(), "zh-CN", "【{{.AppName}}】验证码{{.Code}}, {{.ExpiresMinutes}}分钟内有效."))
+	require.NoError(t, renderer.Register(testSceneNormal.Value(), "en-US", "[{{.AppName}}] code {{.Code}}, valid for {{.ExpiresMinutes}} minutes."))
+
+	p := new(TestMessageProvider)
+	l := limit_verified.NewLimitVerified[testScene](limit_verified.NewPriorityFailoverProvider("failover", p), backend).
+		SetMessageRenderer(renderer).
+		SetSceneParam(testSceneNormal, testParamNormal)
+
+	result, err := l.SendCode(limit_verified.WithLocale(context.Background(), "en-US"), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	require.Equal(t, "test_provider4", result.Provider)
+	require.Equal(t, &limit_verified.Message{
+		Scene:  testSceneNormal.Value(),
+		Locale: "en-US",
+		Code:   code,
+		Body:   "[proc] code 123456, valid for 5 minutes.",
+	}, p.Message(target))
+
+	// template not found, failed before evaluating the quota
+	_, err = l.SendCode(context.Background(), testSceneOverQuota, target, code)
+	require.ErrorIs(t, err, limit_verified.ErrMessageTemplateNotFound)
+
+	// the plain provider can not send the rendered message, the quota is rolled back
+	require.NoError(t, renderer.Register(testSceneOverQuota.Value(), "zh-CN", "验证码{{.Code}}"))
+	plain := limit_verified.NewLimitVerified[testScene](new(TestProvider), backend).
+		SetMessageRenderer(renderer).
+		SetSceneParam(testSceneOverQuota, testParamOverQuota)
+	_, err = plain.SendCode(context.Background(), testSceneOverQuota, target+"2", code)
+	require.ErrorIs(t, err, limit_verified.ErrMessageUnsupported)
+	result, err = limit_verified.NewLimitVerified[testScene](new(TestProvider), backend).
+		SetSceneParam(testSceneOverQuota, testParamOverQuota).
+		SendCode(context.Background(), testSceneOverQuota, target+"2", code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	// the failover provider falls back to the provider supports message
+	l = limit_verified.NewLimitVerified[testScene](limit_verified.NewPriorityFailoverProvider("failover", new(TestProvider), p), backend).
+		SetMessageRenderer(renderer).
+		SetSceneParam(testSceneNormal, testParamNormal)
+	result, err = l.SendCode(context.Background(), testSceneNormal, target+"1", code)
+	require.NoError(t, err)
+	require.Equal(t, "test_provider4", result.Provider)
+}
+
+func GenericTest_SendCode_QuotaDetails[B limit_verified.LimitVerifiedBackend](t *testing.T, mr *miniredis.Miniredis, backend B) {
+	now := time.Unix(time.Now().Unix(), 0)
+	mr.SetTime(now)
+	tiers := []limit_verified.WindowTier{
+		{Window: time.Minute, Quota: 1},
+		{Window: time.Hour, Quota: 2},
+	}
+	l := limit_verified.NewLimitVerified[testScene](new(TestProvider), backend).
+		SetGeneralParam(&limit_verified.Param{
+			Window:          time.Hour * 24,
+			Quota:           3,
+			WindowTiers:     tiers,
+			CodeExpires:     300,
+			CodeMaxAttempts: 3,
+		})
+
+	result, err := l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	require.Equal(t, 2, result.Remaining)
+	require.Equal(t, []int{0, 1}, result.TierRemaining)
+	require.Nil(t, result.TrippedTier)
+	require.Equal(t, 61*time.Second, result.RetryAfter)
+	require.Equal(t, now.Add(61*time.Second), result.NextAt)
+
+	// 子窗口 1min 限制
+	result, err = l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_TooFrequently, result.Status)
+	require.Equal(t, 2, result.Remaining)
+	require.Equal(t, &tiers[0], result.TrippedTier)
+	require.Equal(t, 61*time.Second, result.RetryAfter)
+
+	// 子窗口 1h 配额用尽
+	mr.SetTime(now.Add(2 * time.Minute))
+	result, err = l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	require.Equal(t, 1, result.Remaining)
+	require.Equal(t, []int{0, 0}, result.TierRemaining)
+	require.Equal(t, now.Add(time.Hour+time.Second), result.NextAt)
+
+	mr.SetTime(now.Add(4 * time.Minute))
+	result, err = l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_TooFrequently, result.Status)
+	require.Equal(t, &tiers[1], result.TrippedTier)
+	require.Equal(t, time.Hour+time.Second-4*time.Minute, result.RetryAfter)
+
+	// 最大窗口配额用尽
+	mr.SetTime(now.Add(2 * time.Hour))
+	result, err = l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, []int{0, 1}, result.TierRemaining)
+	require.Equal(t, now.Add(24*time.Hour), result.NextAt)
+
+	result, err = l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_OverQuota, result.Status)
+	require.Equal(t, &limit_verified.WindowTier{Window: time.Hour * 24, Quota: 3}, result.TrippedTier)
+	require.Equal(t, 22*time.Hour, result.RetryAfter)
+}
+
+func GenericTest_VerifyCode_RemainingAttempts[B limit_verified.LimitVerifiedBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	l := limit_verified.NewLimitVerified[testScene](new(TestProvider), backend).
+		SetSceneParam(testSceneNormal, testParamNormal)
+
+	result, err := l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+
+	for i := testParamNormal.CodeMaxAttempts - 1; i >= 0; i-- {
+		vr, err := l.VerifyCode(context.Background(), testSceneNormal, target, badCode)
+		require.NoError(t, err)
+		require.Equal(t, limit_verified.VerifyStatus_Failure, vr.Status)
+		require.Equal(t, i, vr.RemainingAttempts)
+	}
+	// 尝试次数已用尽, 正确的验证码也无法通过
+	vr, err := l.VerifyCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.VerifyStatus_AttemptsExhausted, vr.Status)
+
+	// 验证成功后验证码失效
+	otherTarget := "445566"
+	result, err = l.SendCode(context.Background(), testSceneNormal, otherTarget, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	vr, err = l.VerifyCode(context.Background(), testSceneNormal, otherTarget, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.VerifyStatus_Success, vr.Status)
+	vr, err = l.VerifyCode(context.Background(), testSceneNormal, otherTarget, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.VerifyStatus_Expired, vr.Status)
+}
+
+func GenericTest_InvalidateCode[B limit_verified.LimitVerifiedBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	l := limit_verified.NewLimitVerified[testScene](new(TestProvider), backend).
+		SetSceneParam(testSceneNormal, testParamNormal).
+		SetSceneParam(testSceneOverQuota, testParamOverQuota)
+
+	result, err := l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+
+	// 不存在的验证码
+	require.NoError(t, l.InvalidateCode(context.Background(), testSceneOverQuota, target))
+
+	require.NoError(t, l.InvalidateCode(context.Background(), testSceneNormal, target))
+	vr, err := l.VerifyCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.VerifyStatus_Expired, vr.Status)
+
+	// 作废验证码不恢复配额
+	result, err = l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_TooFrequently, result.Status)
+
+	// the backend does not implement InvalidatorBackend
+	unsupported := limit_verified.NewLimitVerified[testScene](new(TestProvider), struct {
+		limit_verified.LimitVerifiedBackend
+	}{backend})
+	err = unsupported.InvalidateCode(context.Background(), testSceneNormal, target)
+	require.ErrorIs(t, err, limit_verified.ErrInvalidateUnsupported)
+}
+
+func GenericTest_SendCode_Dimensions[B limit_verified.LimitVerifiedBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	param := &limit_verified.Param{
+		Window:          time.Hour * 24,
+		Quota:           30,
+		CodeExpires:     300,
+		CodeMaxAttempts: 3,
+		Dimensions: []limit_verified.Dimension{
+			{Name: "ip", Window: time.Hour * 24, Quota: 2},
+			{Name: "device", Window: time.Hour * 24, Quota: 10, WindowTiers: []limit_verified.WindowTier{{Window: time.Minute, Quota: 5}}},
+		},
+	}
+	l := limit_verified.NewLimitVerified[testScene](new(TestProvider), backend).
+		SetSceneParam(testSceneNormal, param)
+
+	ctx := limit_verified.WithDimension(context.Background(), "ip", "10.0.0.1")
+	for i, tg := range []string{"13800000001", "13800000002"} {
+		result, err := l.SendCode(ctx, testSceneNormal, tg, code)
+		require.NoError(t, err)
+		require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+		require.Equal(t, 29, result.Remaining)
+		require.Equal(t, []limit_verified.DimensionResult{{Name: "ip", Remaining: 1 - i, TierRemaining: []int{}}}, result.Dimensions)
+	}
+
+	// ip 配额用尽, 更换 target 也无法发送
+	result, err := l.SendCode(ctx, testSceneNormal, "13800000003", code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_OverQuota, result.Status)
+	require.Equal(t, "ip", result.Dimension)
+	require.Equal(t, &limit_verified.WindowTier{Window: time.Hour * 24, Quota: 2}, result.TrippedTier)
+	require.Equal(t, 30, result.Remaining)
+
+	// 其它 ip 或未设置维度值时不受影响
+	result, err = l.SendCode(limit_verified.WithDimension(ctx, "ip", "10.0.0.2"), testSceneNormal, "13800000003", code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	result, err = l.SendCode(context.Background(), testSceneNormal, "13800000004", code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	require.Empty(t, result.Dimensions)
+
+	// 多个维度同时生效
+	ctx = limit_verified.WithDimension(limit_verified.WithDimension(context.Background(), "ip", "10.0.0.3"), "device", "d1")
+	result, err = l.SendCode(ctx, testSceneNormal, "13800000005", code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	require.Equal(t, []limit_verified.DimensionResult{
+		{Name: "ip", Remaining: 1, TierRemaining: []int{}},
+		{Name: "device", Remaining: 9, TierRemaining: []int{4}},
+	}, result.Dimensions)
+
+	// 发送失败回滚附加维度配额
+	lErr := limit_verified.NewLimitVerified[testScene](new(TestErrProvider), backend).
+		SetSceneParam(testSceneNormal, param)
+	_, err = lErr.SendCode(ctx, testSceneNormal, "13800000006", code)
+	require.Error(t, err)
+	result, err = l.SendCode(ctx, testSceneNormal, "13800000006", code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	require.Equal(t, []limit_verified.DimensionResult{
+		{Name: "ip", Remaining: 0, TierRemaining: []int{}},
+		{Name: "device", Remaining: 8, TierRemaining: []int{3}},
+	}, result.Dimensions)
+}
+
+func GenericTest_Inspect[B limit_verified.LimitVerifiedBackend](t *testing.T, mr *miniredis.Miniredis, backend B) {
+	now := time.Unix(time.Now().Unix(), 0)
+	mr.SetTime(now)
+	l := limit_verified.NewLimitVerified[testScene](new(TestProvider), backend).
+		SetSceneParam(testSceneNormal, testParamNormal)
+
+	// 没有任何记录
+	st, err := l.Inspect(context.Background(), testSceneNormal, target)
+	require.NoError(t, err)
+	require.Equal(t, target, st.Target)
+	require.Equal(t, limit_verified.TierState{Window: 86400, Quota: 30, Count: 0, Remaining: 30}, st.Window)
+	require.Equal(t, []limit_verified.TierState{{Window: 60, Quota: 1, Count: 0, Remaining: 1}}, st.Tiers)
+	require.Empty(t, st.Records)
+	require.Nil(t, st.Code)
+
+	result, err := l.SendCode(context.Background(), testSceneNormal, target, code)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.EvaluateStatus_Success, result.Status)
+	vr, err := l.VerifyCode(context.Background(), testSceneNormal, target, badCode)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.VerifyStatus_Failure, vr.Status)
+
+	st, err = l.Inspect(context.Background(), testSceneNormal, target)
+	require.NoError(t, err)
+	require.Equal(t, limit_verified.TierState{Window: 86400, Quota: 30, Count: 1, Remaining: 29}, st.Window)
+	require.Equal(t, []limit_verified.TierState{{Window: 60, Quota: 1, Count: 1, Remaining: 0}}, st.Tiers)
+	require.Equal(t, []int64{now.Unix()}, st.Records)
+	require.Equal(t, &limit_verified.CodeState{
+		Scene:       testSceneNormal.Value(),
+		Attempts:    1,
+		MaxAttempts: 3,
+		SentAt:      now.Unix(),
+		ExpireAt:    now.Unix() + 300,
+	}, st.Code)
+
+	// 子窗口已过, 验证码已过期
+	mr.SetTime(now.Add(time.Minute * 6))
+	mr.FastForward(time.Minute * 6)
+	st, err = l.Inspect(context.Background(), testSceneNormal, target)
+	require.NoError(t, err)
+	require.Equal(t, 1, st.Window.Count)
+	require.Equal(t, []limit_verified.TierState{{Window: 60, Quota: 1, Count: 0, Remaining: 1}}, st.Tiers)
+	require.Nil(t, st.Code)
+
+	// the backend does not implement InspectorBackend
+	unsupported := limit_verified.NewLimitVerified[testScene](new(TestProvider), struct {
+		limit_verified.LimitVerifiedBackend
+	}{backend})
+	_, err = unsupported.Inspect(context.Background(), testSceneNormal, target)
+	require.ErrorIs(t, err, limit_verified.ErrInspectUnsupported)
 }
@@ -26,9 +26,17 @@ type ChallengeProvider interface {
 
 // CaptchaDriver the captcha driver
 type CaptchaDriver interface {
+	// Driver returns the challenge provider by driver name,
+	// if not found, should return UnsupportedChallengeProvider.
 	Driver(dName string) ChallengeProvider
 }
 
+// CaptchaDriverLister the optional interface of CaptchaDriver, which lists the available driver names.
+type CaptchaDriverLister interface {
+	// Drivers returns all available driver names.
+	Drivers() []string
+}
+
 // Captcha verified captcha limit
 type Captcha[S SceneValuer, P CaptchaDriver, B StorageBackend] struct {
 	p         P               // captcha provider
@@ -93,13 +101,26 @@ func (c *Captcha[S, P, B]) Name(driverName string) string {
 	return c.p.Driver(driverName).Name()
 }
 
+// Drivers returns all available driver names,
+// return nil if the captcha driver does not implement CaptchaDriverLister.
+func (c *Captcha[S, P, B]) Drivers() []string {
+	if l, ok := any(c.p).(CaptchaDriverLister); ok {
+		return l.Drivers()
+	}
+	return nil
+}
+
 // Generate generate id, question.
+// if driverName is empty, use the default driver of the scene param.
 func (c *Captcha[S, P, B]) Generate(ctx context.Context, driverName string, scene S, opts ...Option) (id, question string, err error) {
+	p := c.useScene(scene, opts...)
+	if driverName == "" {
+		driverName = p.Driver
+	}
 	qa, err := c.p.Driver(driverName).GenerateChallenge(ctx)
 	if err != nil {
 		return "", "", err
 	}
-	p := c.useScene(scene)
 	err = c.backend.Save(ctx, &SaveArgs{
 		Key:         c.formatKey(scene.Value(), qa.Id),
 		KeyExpires:  p.KeyExpires,
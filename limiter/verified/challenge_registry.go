@@ -0,0 +1,55 @@
+package verified
+
+import "slices"
+
+var (
+	_ CaptchaDriver       = (*ChallengeRegistry)(nil)
+	_ CaptchaDriverLister = (*ChallengeRegistry)(nil)
+)
+
+// ChallengeRegistry named challenge provider registry.
+type ChallengeRegistry struct {
+	names     []string                     // driver names in registration order
+	providers map[string]ChallengeProvider // driver name -> challenge provider
+}
+
+// NewChallengeRegistry new challenge provider registry.
+func NewChallengeRegistry() *ChallengeRegistry {
+	return &ChallengeRegistry{
+		names:     make([]string, 0),
+		providers: make(map[string]ChallengeProvider),
+	}
+}
+
+// Register a named challenge provider, it will overwrite the provider which has the same name.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (r *ChallengeRegistry) Register(name string, p ChallengeProvider) {
+	if _, ok := r.providers[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.providers[name] = p
+}
+
+// Unregister the named challenge provider.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (r *ChallengeRegistry) Unregister(name string) {
+	if _, ok := r.providers[name]; ok {
+		delete(r.providers, name)
+		r.names = slices.DeleteFunc(r.names, func(v string) bool { return v == name })
+	}
+}
+
+// Driver returns the challenge provider by driver name,
+// if not found, return UnsupportedChallengeProvider.
+func (r *ChallengeRegistry) Driver(name string) ChallengeProvider {
+	p, ok := r.providers[name]
+	if !ok {
+		return new(UnsupportedChallengeProvider)
+	}
+	return p
+}
+
+// Drivers returns all registered driver names in registration order.
+func (r *ChallengeRegistry) Drivers() []string {
+	return slices.Clone(r.names)
+}
@@ -1,43 +1,185 @@
 package driver
 
 import (
+	"encoding/json"
+	"fmt"
 	"image/color"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/mojocn/base64Captcha"
 	"github.com/thinkgos/proc-extra/limiter/verified"
 )
 
+// captcha driver type
+const (
+	CaptchaTypeString  = "string"  // 字符验证码
+	CaptchaTypeDigit   = "digit"   // 数字验证码
+	CaptchaTypeMath    = "math"    // 算术验证码
+	CaptchaTypeChinese = "chinese" // 中文验证码
+	CaptchaTypeAudio   = "audio"   // 语音验证码
+)
+
+// CaptchaConfig the base64Captcha challenge provider config.
+type CaptchaConfig struct {
+	Name            string   `json:"name"`            // 驱动名称
+	Type            string   `json:"type"`            // 驱动类型, string, digit, math, chinese, audio
+	Height          int      `json:"height"`          // 图片高度, 单位: 像素
+	Width           int      `json:"width"`           // 图片宽度, 单位: 像素
+	NoiseCount      int      `json:"noiseCount"`      // 干扰字符数量, string, math, chinese 有效
+	ShowLineOptions int      `json:"showLineOptions"` // 干扰线选项, string, math, chinese 有效
+	Length          int      `json:"length"`          // 验证码长度, string, digit, chinese, audio 有效
+	Source          string   `json:"source"`          // 字符集, string, chinese 有效
+	BgColor         string   `json:"bgColor"`         // 背景颜色, 格式: #RRGGBB 或 #RRGGBBAA, string, math, chinese 有效
+	Fonts           []string `json:"fonts"`           // 字体, string, math, chinese 有效
+	MaxSkew         float64  `json:"maxSkew"`         // 最大倾斜度, digit 有效
+	DotCount        int      `json:"dotCount"`        // 干扰点数量, digit 有效
+	Language        string   `json:"language"`        // 语言, audio 有效
+}
+
+// DefaultCaptchaConfigs the default captcha configs, include `AlphaDigit`, `Digit`, `Math`.
+func DefaultCaptchaConfigs() []CaptchaConfig {
+	return []CaptchaConfig{
+		{
+			Name:            "AlphaDigit",
+			Type:            CaptchaTypeString,
+			Height:          80,
+			Width:           240,
+			NoiseCount:      2,
+			ShowLineOptions: 2,
+			Length:          4,
+			Source:          "234567890abcdefghjkmnpqrstuvwxyz",
+			BgColor:         "#f0f0f6f6",
+			Fonts:           []string{"wqy-microhei.ttc"},
+		},
+		{
+			Name:     "Digit",
+			Type:     CaptchaTypeDigit,
+			Height:   80,
+			Width:    240,
+			Length:   4,
+			MaxSkew:  0.7,
+			DotCount: 80,
+		},
+		{
+			Name:            "Math",
+			Type:            CaptchaTypeMath,
+			Height:          80,
+			Width:           240,
+			NoiseCount:      2,
+			ShowLineOptions: 2,
+			BgColor:         "#f0f0f6f6",
+			Fonts:           []string{"wqy-microhei.ttc"},
+		},
+	}
+}
+
+// Build the base64Captcha driver.
+func (c *CaptchaConfig) Build() (base64Captcha.Driver, error) {
+	bgColor, err := parseColor(c.BgColor)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Type {
+	case CaptchaTypeString:
+		return base64Captcha.NewDriverString(c.Height, c.Width, c.NoiseCount, c.ShowLineOptions, c.Length, c.Source, bgColor, nil, c.Fonts).
+			ConvertFonts(), nil
+	case CaptchaTypeDigit:
+		return base64Captcha.NewDriverDigit(c.Height, c.Width, c.Length, c.MaxSkew, c.DotCount), nil
+	case CaptchaTypeMath:
+		return base64Captcha.NewDriverMath(c.Height, c.Width, c.NoiseCount, c.ShowLineOptions, bgColor, nil, c.Fonts).
+			ConvertFonts(), nil
+	case CaptchaTypeChinese:
+		return base64Captcha.NewDriverChinese(c.Height, c.Width, c.NoiseCount, c.ShowLineOptions, c.Length, c.Source, bgColor, nil, c.Fonts).
+			ConvertFonts(), nil
+	case CaptchaTypeAudio:
+		return base64Captcha.NewDriverAudio(c.Length, c.Language), nil
+	default:
+		return nil, fmt.Errorf("captcha driver: unsupported driver type '%s'", c.Type)
+	}
+}
+
+var (
+	_ verified.CaptchaDriver       = (*CaptchaDriver)(nil)
+	_ verified.CaptchaDriverLister = (*CaptchaDriver)(nil)
+)
+
+// CaptchaDriver the base64Captcha challenge provider registry.
 type CaptchaDriver struct {
-	alphaDigit verified.ChallengeProvider
-	digit      verified.ChallengeProvider
-	math       verified.ChallengeProvider
+	*verified.ChallengeRegistry
 }
 
+// NewCaptchaDriver new captcha driver with the default configs, see DefaultCaptchaConfigs.
 func NewCaptchaDriver() *CaptchaDriver {
-	alphaDigitDriver := base64Captcha.NewDriverString(80, 240, 2, 2, 4, "234567890abcdefghjkmnpqrstuvwxyz",
-		&color.RGBA{240, 240, 246, 246}, nil, []string{"wqy-microhei.ttc"}).
-		ConvertFonts()
-	digitDriver := base64Captcha.NewDriverDigit(80, 240, 4, 0.7, 80)
-	mathDriver := base64Captcha.NewDriverMath(80, 240, 2, 2,
-		&color.RGBA{240, 240, 246, 246}, nil, []string{"wqy-microhei.ttc"}).
-		ConvertFonts()
-
-	return &CaptchaDriver{
-		alphaDigit: NewCaptchaChallenge(alphaDigitDriver),
-		digit:      NewCaptchaChallenge(digitDriver),
-		math:       NewCaptchaChallenge(mathDriver),
-	}
-}
-
-func (v *CaptchaDriver) Driver(dname string) verified.ChallengeProvider {
-	switch dname {
-	case "AlphaDigit":
-		return v.alphaDigit
-	case "Digit":
-		return v.digit
-	case "Math":
-		return v.math
-	default:
-		return new(verified.UnsupportedChallengeProvider)
+	v, err := NewCaptchaDriverWithConfig(DefaultCaptchaConfigs()...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// NewCaptchaDriverWithConfig new captcha driver with the configs.
+func NewCaptchaDriverWithConfig(configs ...CaptchaConfig) (*CaptchaDriver, error) {
+	v := &CaptchaDriver{
+		ChallengeRegistry: verified.NewChallengeRegistry(),
+	}
+	for i := range configs {
+		if err := v.RegisterConfig(&configs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// NewCaptchaDriverFromFile new captcha driver with the configs from a json file.
+// the file content is a json array of CaptchaConfig.
+func NewCaptchaDriverFromFile(filename string) (*CaptchaDriver, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var configs []CaptchaConfig
+	if err = json.Unmarshal(b, &configs); err != nil {
+		return nil, err
+	}
+	return NewCaptchaDriverWithConfig(configs...)
+}
+
+// RegisterConfig build and register the challenge provider with the config.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (v *CaptchaDriver) RegisterConfig(c *CaptchaConfig) error {
+	if c.Name == "" {
+		return fmt.Errorf("captcha driver: driver name is empty")
+	}
+	d, err := c.Build()
+	if err != nil {
+		return err
+	}
+	v.Register(c.Name, NewCaptchaChallenge(d))
+	return nil
+}
+
+// parseColor parse hex color, format: #RRGGBB or #RRGGBBAA, empty return nil.
+func parseColor(s string) (*color.RGBA, error) {
+	if s == "" {
+		return nil, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return nil, fmt.Errorf("captcha driver: invalid color '%s'", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("captcha driver: invalid color '%s'", s)
 	}
+	return &color.RGBA{
+		R: uint8(v >> 24),
+		G: uint8(v >> 16),
+		B: uint8(v >> 8),
+		A: uint8(v),
+	}, nil
 }
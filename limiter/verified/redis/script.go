@@ -7,3 +7,15 @@ var ScriptSave string
 
 //go:embed verify.lua
 var ScriptVerify string
+
+//go:embed temp_grant_issue.lua
+var ScriptTempGrantIssue string
+
+//go:embed temp_grant_consume.lua
+var ScriptTempGrantConsume string
+
+//go:embed temp_grant_revoke.lua
+var ScriptTempGrantRevoke string
+
+//go:embed temp_grant_inspect.lua
+var ScriptTempGrantInspect string
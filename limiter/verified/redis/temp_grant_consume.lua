@@ -0,0 +1,28 @@
+local key = KEYS[1]   -- key
+local token = ARGV[1] -- 令牌
+
+if redis.call("EXISTS", key) == 0 then
+    return 1 -- 键不存在, 验证失败
+end
+
+local time_res = redis.call("TIME") -- 获取redis节点当前时间.
+local now = tonumber(time_res[1]) * 1000 + math.floor(tonumber(time_res[2]) / 1000) -- 当前时间戳, 单位毫秒
+
+local expires = redis.call("HGET", key, "e:" .. token)
+if expires and tonumber(expires) > now then
+    redis.call("HDEL", key, "t:" .. token, "e:" .. token)
+    if redis.call("HLEN", key) <= 3 then -- 仅剩 seq, max_attempts, attempts, 无有效令牌
+        redis.call("DEL", key)
+    end
+    return 0 -- 成功
+end
+
+-- 令牌不存在或已过期, 仅记录失败次数, 不影响同一键下的其它令牌
+if expires then
+    redis.call("HDEL", key, "t:" .. token, "e:" .. token)
+end
+redis.call("HINCRBY", key, "attempts", 1)
+if redis.call("HLEN", key) <= 3 then -- 仅剩 seq, max_attempts, attempts, 无有效令牌
+    redis.call("DEL", key)
+end
+return 1 -- 验证失败
@@ -0,0 +1,20 @@
+local key = KEYS[1] -- key
+
+local time_res = redis.call("TIME") -- 获取redis节点当前时间.
+local now = tonumber(time_res[1]) * 1000 + math.floor(tonumber(time_res[2]) / 1000) -- 当前时间戳, 单位毫秒
+
+-- 只读, 不清除过期令牌, 不返回令牌值
+local vals = redis.call("HMGET", key, "attempts", "max_attempts")
+local res = { now, tonumber(vals[1]) or 0, tonumber(vals[2]) or 0 }
+
+-- 返回: 当前时间, 尝试次数, 最大允许尝试次数, [序号, 过期时间]...
+local kvs = redis.call("HGETALL", key)
+for i = 1, #kvs, 2 do
+    local field = kvs[i]
+    if string.sub(field, 1, 2) == "e:" and tonumber(kvs[i + 1]) > now then
+        local t = string.sub(field, 3)
+        res[#res + 1] = tonumber(redis.call("HGET", key, "t:" .. t))
+        res[#res + 1] = tonumber(kvs[i + 1])
+    end
+end
+return res
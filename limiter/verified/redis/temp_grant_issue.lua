@@ -0,0 +1,37 @@
+local key = KEYS[1]                   -- key
+local token = ARGV[1]                 -- 令牌
+local maxAttempts = tonumber(ARGV[2]) -- 最大允许尝试次数
+local expires = tonumber(ARGV[3])     -- 过期时间, 单位: 毫秒
+local maxActive = tonumber(ARGV[4])   -- 最大同时有效令牌数
+
+local time_res = redis.call("TIME")   -- 获取redis节点当前时间.
+local now = tonumber(time_res[1]) * 1000 + math.floor(tonumber(time_res[2]) / 1000) -- 当前时间戳, 单位毫秒
+
+-- 收集有效令牌, 清除已过期的令牌
+local tokens = {}
+local kvs = redis.call("HGETALL", key)
+for i = 1, #kvs, 2 do
+    local field = kvs[i]
+    if string.sub(field, 1, 2) == "e:" then
+        local t = string.sub(field, 3)
+        if tonumber(kvs[i + 1]) <= now then
+            redis.call("HDEL", key, "t:" .. t, field)
+        else
+            table.insert(tokens, { t, tonumber(redis.call("HGET", key, "t:" .. t)) })
+        end
+    end
+end
+
+-- 超过最大同时有效令牌数, 撤销最旧的令牌
+table.sort(tokens, function(a, b) return a[2] < b[2] end)
+for i = 1, #tokens - maxActive + 1 do
+    redis.call("HDEL", key, "t:" .. tokens[i][1], "e:" .. tokens[i][1])
+end
+
+local seq = redis.call("HINCRBY", key, "seq", 1)
+redis.call("HSET", key, "t:" .. token, seq, "e:" .. token, now + expires, "max_attempts", maxAttempts)
+redis.call("HSETNX", key, "attempts", 0) -- 失败次数不因签发新令牌而重置
+if redis.call("PTTL", key) < expires then
+    redis.call("PEXPIRE", key, expires)
+end
+return 0 -- 成功
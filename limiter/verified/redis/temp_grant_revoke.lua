@@ -0,0 +1,11 @@
+local key = KEYS[1]   -- key
+local token = ARGV[1] -- 令牌
+
+local removed = redis.call("HDEL", key, "t:" .. token, "e:" .. token)
+if removed > 0 and redis.call("HLEN", key) <= 3 then -- 仅剩 seq, max_attempts, attempts, 无有效令牌
+    redis.call("DEL", key)
+end
+if removed > 0 then
+    return 0 -- 成功
+end
+return 1 -- 令牌不存在
@@ -22,6 +22,18 @@ func Test_Captcha_UnsupportedChallengeProvider(t *testing.T) {
 	)
 }
 
+func Test_Captcha_ChallengeRegistry(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.Nil(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_Captcha_ChallengeRegistry(
+		t,
+		mr,
+		NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
 func Test_Captcha_InMaxAttempts(t *testing.T) {
 	mr, err := miniredis.Run()
 	assert.NoError(t, err)
@@ -0,0 +1,108 @@
+package v9
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/thinkgos/proc-extra/limiter/verified"
+	redisScript "github.com/thinkgos/proc-extra/limiter/verified/redis"
+)
+
+var _ verified.TempGrantStorageBackend = (*RedisTempGrantStore)(nil)
+
+// RedisTempGrantStore the temp grant store, keep up to MaxActive tokens per key.
+// the storage format differs from RedisStore, the tokens issued with one can not be consumed with the other.
+type RedisTempGrantStore struct {
+	*RedisStore
+}
+
+// NewRedisTempGrantStore new redis temp grant store instance.
+func NewRedisTempGrantStore(client *redis.Client) *RedisTempGrantStore {
+	return &RedisTempGrantStore{RedisStore: NewRedisStore(client)}
+}
+
+// Issue the temp grant token.
+func (s *RedisTempGrantStore) Issue(ctx context.Context, p *verified.IssueArgs) error {
+	return s.client.Eval(
+		ctx,
+		redisScript.ScriptTempGrantIssue,
+		[]string{p.Key},
+		[]string{
+			p.Token,
+			strconv.Itoa(p.MaxAttempts),
+			strconv.FormatInt(p.KeyExpires.Milliseconds(), 10),
+			strconv.Itoa(p.MaxActive),
+		},
+	).Err()
+}
+
+// Consume the temp grant token.
+func (s *RedisTempGrantStore) Consume(ctx context.Context, p *verified.ConsumeArgs) (bool, error) {
+	code, err := s.client.Eval(
+		ctx,
+		redisScript.ScriptTempGrantConsume,
+		[]string{p.Key},
+		[]string{p.Token},
+	).Int64()
+	if err != nil {
+		return false, err
+	}
+	return code == 0, nil
+}
+
+// Revoke the temp grant token.
+func (s *RedisTempGrantStore) Revoke(ctx context.Context, p *verified.RevokeArgs) (bool, error) {
+	code, err := s.client.Eval(
+		ctx,
+		redisScript.ScriptTempGrantRevoke,
+		[]string{p.Key},
+		[]string{p.Token},
+	).Int64()
+	if err != nil {
+		return false, err
+	}
+	return code == 0, nil
+}
+
+// RevokeAll revoke all temp grant tokens of the key.
+func (s *RedisTempGrantStore) RevokeAll(ctx context.Context, p *verified.RevokeAllArgs) error {
+	return s.client.Del(ctx, p.Key).Err()
+}
+
+// Inspect the active temp grant tokens of the key.
+func (s *RedisTempGrantStore) Inspect(ctx context.Context, p *verified.InspectArgs) (*verified.TempGrantState, error) {
+	// reply: [now, attempts, max_attempts, [seq, expire_at]...]
+	vals, err := s.client.Eval(
+		ctx,
+		redisScript.ScriptTempGrantInspect,
+		[]string{p.Key},
+		[]string{},
+	).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) < 3 {
+		return nil, fmt.Errorf("verified: unexpected inspect reply length %d", len(vals))
+	}
+	state := &verified.TempGrantState{
+		Key:         p.Key,
+		Tokens:      make([]verified.TempGrantTokenState, 0, (len(vals)-3)/2),
+		Attempts:    int(vals[1]),
+		MaxAttempts: int(vals[2]),
+	}
+	for i := 3; i+1 < len(vals); i += 2 {
+		state.Tokens = append(state.Tokens, verified.TempGrantTokenState{
+			Seq:      vals[i],
+			ExpireAt: vals[i+1],
+		})
+	}
+	slices.SortFunc(state.Tokens, func(a, b verified.TempGrantTokenState) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+	return state, nil
+}
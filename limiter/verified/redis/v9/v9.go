@@ -7,11 +7,16 @@ import (
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/thinkgos/proc-extra/limiter/inspect"
 	"github.com/thinkgos/proc-extra/limiter/verified"
 	redisScript "github.com/thinkgos/proc-extra/limiter/verified/redis"
 )
 
-var _ verified.StorageBackend = (*RedisStore)(nil)
+var (
+	_ verified.StorageBackend = (*RedisStore)(nil)
+	_ verified.NonceBackend   = (*RedisStore)(nil)
+	_ inspect.Scanner         = (*RedisStore)(nil)
+)
 
 // RedisStore verified captcha limit
 type RedisStore struct {
@@ -50,3 +55,13 @@ func (s *RedisStore) Verify(ctx context.Context, p *verified.VerifyArgs) (bool,
 	}
 	return code == 0, nil
 }
+
+// ScanPrefix implements [inspect.Scanner].
+func (s *RedisStore) ScanPrefix(ctx context.Context, prefix string, cursor uint64, count int64) ([]string, uint64, error) {
+	return s.client.Scan(ctx, cursor, inspect.MatchPrefix(prefix), count).Result()
+}
+
+// UseNonce mark the nonce key used, return false if it has been used.
+func (s *RedisStore) UseNonce(ctx context.Context, key string, expires time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, key, "", max(expires, time.Second)).Result()
+}
@@ -24,3 +24,63 @@ type StorageBackend interface {
 	Save(context.Context, *SaveArgs) error
 	Verify(context.Context, *VerifyArgs) (bool, error)
 }
+
+// IssueArgs temp grant issue arguments
+type IssueArgs struct {
+	Key         string
+	KeyExpires  time.Duration
+	MaxAttempts int
+	MaxActive   int
+	Token       string
+}
+
+// ConsumeArgs temp grant consume arguments
+type ConsumeArgs struct {
+	Key   string
+	Token string
+}
+
+// RevokeArgs temp grant revoke arguments
+type RevokeArgs struct {
+	Key   string
+	Token string
+}
+
+// RevokeAllArgs temp grant revoke all arguments
+type RevokeAllArgs struct {
+	Key string
+}
+
+// InspectArgs temp grant inspect arguments
+type InspectArgs struct {
+	Key string
+}
+
+// TempGrantTokenState the state of an active token, it never contains the token value.
+type TempGrantTokenState struct {
+	Seq      int64 `json:"seq"`      // issue sequence
+	ExpireAt int64 `json:"expireAt"` // unix timestamp (milliseconds) at which the token expires.
+}
+
+// TempGrantState the current state of the temp grant tokens of a key.
+type TempGrantState struct {
+	Key         string                `json:"key"`         // key
+	Tokens      []TempGrantTokenState `json:"tokens"`      // active tokens, sorted by issue sequence.
+	Attempts    int                   `json:"attempts"`    // the failed consume attempts, it never revokes the other tokens
+	MaxAttempts int                   `json:"maxAttempts"` // the max failure attempts, not enforced since each token is single-use
+}
+
+// TempGrantStorageBackend temp grant store engine, keep up to MaxActive tokens per key.
+// it is optional, TempGrant uses it instead of StorageBackend if the backend implements it.
+type TempGrantStorageBackend interface {
+	// Issue save the token, revoke the oldest tokens beyond MaxActive.
+	Issue(context.Context, *IssueArgs) error
+	// Consume the token, the token is removed if success.
+	Consume(context.Context, *ConsumeArgs) (bool, error)
+	// Revoke the token, return false if the token not found.
+	Revoke(context.Context, *RevokeArgs) (bool, error)
+	// RevokeAll revoke all tokens of the key.
+	RevokeAll(context.Context, *RevokeAllArgs) error
+	// Inspect the active tokens of the key, it never modifies any data.
+	Inspect(context.Context, *InspectArgs) (*TempGrantState, error)
+}
@@ -2,9 +2,13 @@ package verified
 
 import (
 	"context"
+	"errors"
 	"slices"
 )
 
+// ErrTempGrantUnsupported the backend does not implement TempGrantStorageBackend.
+var ErrTempGrantUnsupported = errors.New("verified: temp grant backend not support multiple tokens")
+
 type TempGranter[S SceneValuer] interface {
 	Issue(ctx context.Context, scene S, id string, opts ...Option) (string, error)
 	Consume(ctx context.Context, scene S, id, token string) (bool, error)
@@ -17,6 +21,8 @@ type TempGrantGenerator interface {
 }
 
 // TempGrant temp grant verifier
+// if the backend implements TempGrantStorageBackend, it keeps up to MaxActive tokens per (scene, id)
+// and supports Revoke, RevokeAll and Inspect, otherwise a new token overwrites the old one.
 type TempGrant[S SceneValuer, P TempGrantGenerator, B StorageBackend] struct {
 	p         P               // temp grant provider
 	backend   B               // store backend
@@ -79,29 +85,96 @@ func (c *TempGrant[S, P, B]) useScene(scene S, opts ...Option) *Param {
 }
 
 // Issue a temp grant token. use option overwrite default param.
+// keep up to MaxActive tokens per (scene, id) if the backend implements TempGrantStorageBackend,
+// revoke the oldest tokens beyond it.
 func (t *TempGrant[S, P, B]) Issue(ctx context.Context, scene S, id string, opts ...Option) (string, error) {
 	p := t.useScene(scene, opts...)
-	answer := t.p.GenerateUniqueId()
-	err := t.backend.Save(ctx, &SaveArgs{
-		Key:         t.formatKey(scene.Value(), id),
-		KeyExpires:  p.KeyExpires,
-		MaxAttempts: p.MaxAttempts,
-		Answer:      answer,
-	})
+	token := t.p.GenerateUniqueId()
+	key := t.formatKey(scene.Value(), id)
+	var err error
+	if tb, ok := t.tempGrantBackend(); ok {
+		err = tb.Issue(ctx, &IssueArgs{
+			Key:         key,
+			KeyExpires:  p.KeyExpires,
+			MaxAttempts: p.MaxAttempts,
+			MaxActive:   max(p.MaxActive, 1),
+			Token:       token,
+		})
+	} else {
+		err = t.backend.Save(ctx, &SaveArgs{
+			Key:         key,
+			KeyExpires:  p.KeyExpires,
+			MaxAttempts: p.MaxAttempts,
+			Answer:      token,
+		})
+	}
 	if err != nil {
 		return "", err
 	}
-	return answer, nil
+	return token, nil
 }
 
 // Consume the temp grant token.
 func (t *TempGrant[S, P, B]) Consume(ctx context.Context, scene S, id, token string) (bool, error) {
+	key := t.formatKey(scene.Value(), id)
+	if tb, ok := t.tempGrantBackend(); ok {
+		return tb.Consume(ctx, &ConsumeArgs{
+			Key:   key,
+			Token: token,
+		})
+	}
 	return t.backend.Verify(ctx, &VerifyArgs{
-		Key:    t.formatKey(scene.Value(), id),
+		Key:    key,
 		Answer: token,
 	})
 }
 
+// Revoke the temp grant token, return false if the token not found.
+// return ErrTempGrantUnsupported if the backend does not implement TempGrantStorageBackend.
+func (t *TempGrant[S, P, B]) Revoke(ctx context.Context, scene S, id, token string) (bool, error) {
+	tb, ok := t.tempGrantBackend()
+	if !ok {
+		return false, ErrTempGrantUnsupported
+	}
+	return tb.Revoke(ctx, &RevokeArgs{
+		Key:   t.formatKey(scene.Value(), id),
+		Token: token,
+	})
+}
+
+// RevokeAll revoke all temp grant tokens of the (scene, id).
+// return ErrTempGrantUnsupported if the backend does not implement TempGrantStorageBackend.
+func (t *TempGrant[S, P, B]) RevokeAll(ctx context.Context, scene S, id string) error {
+	tb, ok := t.tempGrantBackend()
+	if !ok {
+		return ErrTempGrantUnsupported
+	}
+	return tb.RevokeAll(ctx, &RevokeAllArgs{
+		Key: t.formatKey(scene.Value(), id),
+	})
+}
+
+// Inspect the active temp grant tokens of the (scene, id) without revealing the token values,
+// it never modifies any data.
+// return ErrTempGrantUnsupported if the backend does not implement TempGrantStorageBackend.
+func (t *TempGrant[S, P, B]) Inspect(ctx context.Context, scene S, id string) (*TempGrantState, error) {
+	tb, ok := t.tempGrantBackend()
+	if !ok {
+		return nil, ErrTempGrantUnsupported
+	}
+	return tb.Inspect(ctx, &InspectArgs{
+		Key: t.formatKey(scene.Value(), id),
+	})
+}
+
+func (t *TempGrant[S, P, B]) tempGrantBackend() (TempGrantStorageBackend, bool) {
+	tb, ok := any(t.backend).(TempGrantStorageBackend)
+	return tb, ok
+}
+
+// KeyPrefix returns the key prefix.
+func (t *TempGrant[S, P, B]) KeyPrefix() string { return t.keyPrefix }
+
 func (c *TempGrant[S, P, B]) formatKey(scene, id string) string {
 	return c.keyPrefix + scene + ":" + id
 }
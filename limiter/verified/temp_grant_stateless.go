@@ -0,0 +1,197 @@
+package verified
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thinkgos/proc-extra/reflux"
+	"github.com/thinkgos/proc-extra/signature"
+)
+
+// TempGrantSigner the stateless temp grant token signer.
+type TempGrantSigner interface {
+	// Sign the plain text, return the signature.
+	Sign(plainText []byte) (string, error)
+	// Verify the signature of the plain text.
+	Verify(sign string, plainText []byte) error
+}
+
+// NonceBackend single-use nonce store.
+type NonceBackend interface {
+	// UseNonce mark the nonce key used, return false if it has been used.
+	UseNonce(ctx context.Context, key string, expires time.Duration) (bool, error)
+}
+
+var (
+	_ TempGrantSigner = (*HmacSigner)(nil)
+	_ TempGrantSigner = (*reflux.Reflux)(nil)
+)
+
+// HmacSigner the hmac signer, see signature.Hmac.
+type HmacSigner struct {
+	method string
+	key    []byte
+}
+
+// NewHmacSigner new hmac signer.
+// method expect: hmacmd5, hmacsha1, hmacsha224, hmacsha256, hmacsha384, hmacsha512.
+func NewHmacSigner(method string, key []byte) *HmacSigner {
+	return &HmacSigner{method: method, key: key}
+}
+
+// Sign implements TempGrantSigner.
+func (s *HmacSigner) Sign(plainText []byte) (string, error) {
+	return base64.RawURLEncoding.EncodeToString(signature.Hmac(s.method, s.key, plainText)), nil
+}
+
+// Verify implements TempGrantSigner.
+func (s *HmacSigner) Verify(sign string, plainText []byte) error {
+	got, err := base64.RawURLEncoding.DecodeString(sign)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(got, signature.Hmac(s.method, s.key, plainText)) {
+		return errors.New("verified: hmac signature mismatch")
+	}
+	return nil
+}
+
+// tempGrantClaims the stateless temp grant token claims.
+type tempGrantClaims struct {
+	Scene     string `json:"s"` // scene
+	Id        string `json:"i"` // id
+	ExpiresAt int64  `json:"e"` // unix timestamp (milliseconds)
+	Nonce     string `json:"n"` // single-use nonce
+}
+
+// StatelessTempGrant stateless temp grant verifier.
+// The token is signed and bound to scene and id, it can be consumed without backend round-trip.
+// NOTE: MaxAttempts of the param is not supported, only KeyExpires is used as the token lifetime.
+type StatelessTempGrant[S SceneValuer, G TempGrantSigner] struct {
+	signer    G               // token signer
+	nonce     NonceBackend    // single-use nonce backend, nil means replay is allowed within the lifetime.
+	keyPrefix string          // key prefix for nonce store
+	param     *Param          // general param
+	scenes    []SceneParam[S] // scene param.
+}
+
+// NewStatelessTempGrant new stateless temp grant verifier instance.
+func NewStatelessTempGrant[S SceneValuer, G TempGrantSigner](signer G) *StatelessTempGrant[S, G] {
+	return &StatelessTempGrant[S, G]{
+		signer:    signer,
+		nonce:     nil,
+		keyPrefix: "temp-grant:nonce:",
+		param:     NewParam(),
+		scenes:    make([]SceneParam[S], 0),
+	}
+}
+
+// SetNonceBackend sets the single-use nonce backend to prevent replay.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (t *StatelessTempGrant[S, G]) SetNonceBackend(b NonceBackend) *StatelessTempGrant[S, G] {
+	t.nonce = b
+	return t
+}
+
+// SetKeyPrefix sets the key prefix.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (t *StatelessTempGrant[S, G]) SetKeyPrefix(keyPrefix string) *StatelessTempGrant[S, G] {
+	t.keyPrefix = keyPrefix
+	return t
+}
+
+// SetGeneralParam sets the general param.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (t *StatelessTempGrant[S, G]) SetGeneralParam(p *Param) *StatelessTempGrant[S, G] {
+	t.param = p
+	return t
+}
+
+// SetSceneParam sets the param for a specific scene.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (t *StatelessTempGrant[S, G]) SetSceneParam(scene S, param *Param) *StatelessTempGrant[S, G] {
+	for i := range t.scenes {
+		if t.scenes[i].scene == scene {
+			t.scenes[i].param = param
+			return t
+		}
+	}
+	t.scenes = append(t.scenes, SceneParam[S]{scene: scene, param: param})
+	t.scenes = slices.Clone(t.scenes)
+	return t
+}
+
+func (t *StatelessTempGrant[S, G]) useScene(scene S, opts ...Option) *Param {
+	p := t.param
+	for i := range t.scenes {
+		if t.scenes[i].scene == scene {
+			p = t.scenes[i].param
+			break
+		}
+	}
+	return p.clone().apply(opts...)
+}
+
+// Issue a signed temp grant token. use option overwrite default param.
+func (t *StatelessTempGrant[S, G]) Issue(_ context.Context, scene S, id string, opts ...Option) (string, error) {
+	p := t.useScene(scene, opts...)
+	var nonce [16]byte
+	_, _ = rand.Read(nonce[:])
+	payload, err := json.Marshal(&tempGrantClaims{
+		Scene:     scene.Value(),
+		Id:        id,
+		ExpiresAt: time.Now().Add(p.KeyExpires).UnixMilli(),
+		Nonce:     hex.EncodeToString(nonce[:]),
+	})
+	if err != nil {
+		return "", err
+	}
+	sign, err := t.signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + sign, nil
+}
+
+// Consume the signed temp grant token.
+// if the nonce backend is set, the token can be consumed only once.
+func (t *StatelessTempGrant[S, G]) Consume(ctx context.Context, scene S, id, token string) (bool, error) {
+	encodedPayload, sign, ok := strings.Cut(token, ".")
+	if !ok {
+		return false, nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return false, nil
+	}
+	if err = t.signer.Verify(sign, payload); err != nil {
+		return false, nil
+	}
+	claims := tempGrantClaims{}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return false, nil
+	}
+	if claims.Scene != scene.Value() || claims.Id != id {
+		return false, nil
+	}
+	remain := time.Until(time.UnixMilli(claims.ExpiresAt))
+	if remain <= 0 {
+		return false, nil
+	}
+	if t.nonce == nil {
+		return true, nil
+	}
+	return t.nonce.UseNonce(ctx, t.formatKey(claims.Scene, claims.Id, claims.Nonce), remain)
+}
+
+func (t *StatelessTempGrant[S, G]) formatKey(scene, id, nonce string) string {
+	return t.keyPrefix + scene + ":" + id + ":" + nonce
+}
@@ -20,6 +20,11 @@ func Test_TempGrant_InMaxAttempts(t *testing.T) {
 		mr,
 		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
 	)
+	tests.GenericTest_TempGrant_InMaxAttempts(
+		t,
+		mr,
+		redisV9.NewRedisTempGrantStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
 }
 
 func Test_TempGrant_OverMaxAttempts(t *testing.T) {
@@ -33,6 +38,18 @@ func Test_TempGrant_OverMaxAttempts(t *testing.T) {
 		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
 	)
 }
+
+func Test_TempGrant_WrongToken(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_TempGrant_WrongToken(
+		t,
+		mr,
+		redisV9.NewRedisTempGrantStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
 func Test_TempGrant_OneShot(t *testing.T) {
 	mr, err := miniredis.Run()
 	assert.NoError(t, err)
@@ -43,6 +60,11 @@ func Test_TempGrant_OneShot(t *testing.T) {
 		mr,
 		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
 	)
+	tests.GenericTest_TempGrant_OneShot(
+		t,
+		mr,
+		redisV9.NewRedisTempGrantStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
 }
 func Test_TempGrant_OneShot_Timeout(t *testing.T) {
 	mr, err := miniredis.Run()
@@ -54,4 +76,117 @@ func Test_TempGrant_OneShot_Timeout(t *testing.T) {
 		mr,
 		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
 	)
+	tests.GenericTest_TempGrant_OneShot_Timeout(
+		t,
+		mr,
+		redisV9.NewRedisTempGrantStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_TempGrant_MultiActive(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_TempGrant_MultiActive(
+		t,
+		mr,
+		redisV9.NewRedisTempGrantStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_TempGrant_SingleActive(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_TempGrant_SingleActive(
+		t,
+		mr,
+		redisV9.NewRedisTempGrantStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_TempGrant_Revoke(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_TempGrant_Revoke(
+		t,
+		mr,
+		redisV9.NewRedisTempGrantStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_TempGrant_Unsupported(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_TempGrant_Unsupported(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_StatelessTempGrant_Hmac(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_StatelessTempGrant_Hmac(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_StatelessTempGrant_Reflux(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_StatelessTempGrant_Reflux(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_StatelessTempGrant_OneShot(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_StatelessTempGrant_OneShot(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_StatelessTempGrant_Timeout(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_StatelessTempGrant_Timeout(
+		t,
+		mr,
+		redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
+
+func Test_TempGrant_Inspect(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_TempGrant_Inspect(
+		t,
+		mr,
+		redisV9.NewRedisTempGrantStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
 }
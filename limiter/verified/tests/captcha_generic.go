@@ -173,3 +173,41 @@ func GenericTest_Captcha_OneShot_Timeout[B verified.StorageBackend](t *testing.T
 	require.NoError(t, err)
 	require.False(t, b)
 }
+
+func GenericTest_Captcha_ChallengeRegistry[B verified.StorageBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	registry := verified.NewChallengeRegistry()
+	registry.Register(testDriverName, new(TestChallenge))
+	registry.Register(unsupportedDriverName, new(verified.UnsupportedChallengeProvider))
+	registry.Unregister(unsupportedDriverName)
+
+	l := verified.NewCaptcha[testSceneType](registry, backend).
+		SetKeyPrefix(testKeyPrefix).
+		SetSceneParam(testScene, &verified.Param{
+			KeyExpires:  testKeyExpires,
+			MaxAttempts: testMaxAttempts_1,
+			Driver:      testDriverName,
+		})
+	require.Equal(t, []string{testDriverName}, l.Drivers())
+	// the driver does not list the driver names.
+	require.Nil(t, verified.NewCaptcha[testSceneType](new(TestCaptchaDriver), backend).Drivers())
+	require.Equal(t, testDriverName, l.Name(testDriverName))
+
+	// unregistered driver
+	_, _, err := l.Generate(context.Background(), unsupportedDriverName, testScene)
+	require.Error(t, err)
+
+	// use the default driver of the scene
+	id, _, err := l.Generate(context.Background(), "", testScene)
+	require.NoError(t, err)
+	b, err := l.Verify(context.Background(), testScene, id, rightAnswer)
+	require.NoError(t, err)
+	require.True(t, b)
+
+	// the general param has no default driver
+	_, _, err = l.Generate(context.Background(), "", testSceneType("other_scene"))
+	require.Error(t, err)
+
+	// overwrite the default driver by option
+	_, _, err = l.Generate(context.Background(), "", testScene, verified.WithDriver(unsupportedDriverName))
+	require.Error(t, err)
+}
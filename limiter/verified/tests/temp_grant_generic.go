@@ -9,6 +9,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/thinkgos/proc-extra/limiter/verified"
+	"github.com/thinkgos/proc-extra/reflux"
+	"github.com/thinkgos/proc-extra/testdata"
 )
 
 var _ verified.TempGrantGenerator = (*TestTempGrantProvider)(nil)
@@ -18,6 +20,12 @@ var testTempGrantSceneParam = &verified.Param{
 	MaxAttempts: testMaxAttempts_1,
 }
 
+// TempGrantBackend the backend keeps multiple temp grant tokens per key.
+type TempGrantBackend interface {
+	verified.StorageBackend
+	verified.TempGrantStorageBackend
+}
+
 type TestTempGrantProvider struct{}
 
 func (t TestTempGrantProvider) Name() string { return "test-temp-grant-provider" }
@@ -114,3 +122,291 @@ func GenericTest_TempGrant_OneShot_Timeout[B verified.StorageBackend](t *testing
 	require.NoError(t, err)
 	require.False(t, b)
 }
+
+func GenericTest_StatelessTempGrant_Hmac[B verified.NonceBackend](t *testing.T, _ *miniredis.Miniredis, _ B) {
+	l := verified.NewStatelessTempGrant[testSceneType](verified.NewHmacSigner("hmacsha256", []byte("test-secret"))).
+		SetSceneParam(testScene, testTempGrantSceneParam)
+
+	targetId := randString(6)
+	wantAnswer, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+
+	// bound to scene and id
+	b, err := l.Consume(context.Background(), testSceneType("other_scene"), targetId, wantAnswer)
+	require.NoError(t, err)
+	require.False(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId+"xxx", wantAnswer)
+	require.NoError(t, err)
+	require.False(t, b)
+	// bad token
+	for _, badAnswer := range []string{"", wantAnswer + "xxx", "xxx" + wantAnswer, "xxx.yyy"} {
+		b, err = l.Consume(context.Background(), testScene, targetId, badAnswer)
+		require.NoError(t, err)
+		require.False(t, b)
+	}
+	// signed by other key
+	other := verified.NewStatelessTempGrant[testSceneType](verified.NewHmacSigner("hmacsha256", []byte("other-secret")))
+	b, err = other.Consume(context.Background(), testScene, targetId, wantAnswer)
+	require.NoError(t, err)
+	require.False(t, b)
+
+	// without nonce backend, the token can be consumed within the lifetime.
+	b, err = l.Consume(context.Background(), testScene, targetId, wantAnswer)
+	require.NoError(t, err)
+	require.True(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId, wantAnswer)
+	require.NoError(t, err)
+	require.True(t, b)
+}
+
+func GenericTest_StatelessTempGrant_Reflux[B verified.NonceBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	r, err := reflux.New(testdata.PriveKey, testdata.PubKey)
+	require.NoError(t, err)
+	l := verified.NewStatelessTempGrant[testSceneType](r).
+		SetNonceBackend(backend).
+		SetKeyPrefix(testKeyPrefix).
+		SetSceneParam(testScene, testTempGrantSceneParam)
+
+	targetId := randString(6)
+	wantAnswer, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+
+	b, err := l.Consume(context.Background(), testScene, targetId, wantAnswer+"xxx")
+	require.NoError(t, err)
+	require.False(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId, wantAnswer)
+	require.NoError(t, err)
+	require.True(t, b)
+}
+
+func GenericTest_StatelessTempGrant_OneShot[B verified.NonceBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	l := verified.NewStatelessTempGrant[testSceneType](verified.NewHmacSigner("hmacsha256", []byte("test-secret"))).
+		SetNonceBackend(backend).
+		SetKeyPrefix(testKeyPrefix).
+		SetSceneParam(testScene, testTempGrantSceneParam)
+
+	targetId := randString(6)
+	wantAnswer, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	otherAnswer, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+
+	b, err := l.Consume(context.Background(), testScene, targetId, wantAnswer)
+	require.NoError(t, err)
+	require.True(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId, wantAnswer)
+	require.NoError(t, err)
+	require.False(t, b)
+
+	// the other token is still valid
+	b, err = l.Consume(context.Background(), testScene, targetId, otherAnswer)
+	require.NoError(t, err)
+	require.True(t, b)
+}
+
+func GenericTest_StatelessTempGrant_Timeout[B verified.NonceBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	l := verified.NewStatelessTempGrant[testSceneType](verified.NewHmacSigner("hmacsha256", []byte("test-secret"))).
+		SetNonceBackend(backend).
+		SetKeyPrefix(testKeyPrefix).
+		SetSceneParam(testScene, testTempGrantSceneParam)
+
+	targetId := randString(6)
+	wantAnswer, err := l.Issue(context.Background(), testScene, targetId, verified.WithKeyExpires(time.Millisecond*50))
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond * 100)
+
+	b, err := l.Consume(context.Background(), testScene, targetId, wantAnswer)
+	require.NoError(t, err)
+	require.False(t, b)
+}
+
+func GenericTest_TempGrant_MultiActive[B TempGrantBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	l := verified.NewTempGrant[testSceneType](new(TestTempGrantProvider), backend).
+		SetKeyPrefix(testKeyPrefix).
+		SetSceneParam(testScene, &verified.Param{
+			KeyExpires:  testKeyExpires,
+			MaxAttempts: testMaxAttempts_3,
+			MaxActive:   2,
+		})
+
+	targetId := randString(6)
+	token1, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	token2, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	token3, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+
+	// token1 is the oldest, revoked beyond the cap.
+	b, err := l.Consume(context.Background(), testScene, targetId, token1)
+	require.NoError(t, err)
+	require.False(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId, token3)
+	require.NoError(t, err)
+	require.True(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId, token2)
+	require.NoError(t, err)
+	require.True(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId, token2)
+	require.NoError(t, err)
+	require.False(t, b)
+}
+
+func GenericTest_TempGrant_WrongToken[B TempGrantBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	l := verified.NewTempGrant[testSceneType](new(TestTempGrantProvider), backend).
+		SetKeyPrefix(testKeyPrefix).
+		SetSceneParam(testScene, &verified.Param{
+			KeyExpires:  testKeyExpires,
+			MaxAttempts: testMaxAttempts_1,
+			MaxActive:   3,
+		})
+
+	targetId := randString(6)
+	token1, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	token2, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+
+	// a wrong token never revokes the sibling tokens.
+	b, err := l.Consume(context.Background(), testScene, targetId, "bad_token")
+	require.NoError(t, err)
+	require.False(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId, token1)
+	require.NoError(t, err)
+	require.True(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId, token1)
+	require.NoError(t, err)
+	require.False(t, b)
+
+	// the failed attempts are not reset by issuing a new token.
+	_, err = l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	st, err := l.Inspect(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	require.Equal(t, 2, st.Attempts)
+	require.Len(t, st.Tokens, 2)
+
+	b, err = l.Consume(context.Background(), testScene, targetId, token2)
+	require.NoError(t, err)
+	require.True(t, b)
+}
+
+func GenericTest_TempGrant_SingleActive[B TempGrantBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	l := verified.NewTempGrant[testSceneType](new(TestTempGrantProvider), backend).
+		SetKeyPrefix(testKeyPrefix).
+		SetSceneParam(testScene, &verified.Param{
+			KeyExpires:  testKeyExpires,
+			MaxAttempts: testMaxAttempts_3,
+		})
+
+	targetId := randString(6)
+	token1, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	token2, err := l.Issue(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+
+	// the second token invalidates the first one.
+	b, err := l.Consume(context.Background(), testScene, targetId, token1)
+	require.NoError(t, err)
+	require.False(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId, token2)
+	require.NoError(t, err)
+	require.True(t, b)
+}
+
+func GenericTest_TempGrant_Revoke[B TempGrantBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	l := verified.NewTempGrant[testSceneType](new(TestTempGrantProvider), backend).
+		SetKeyPrefix(testKeyPrefix).
+		SetSceneParam(testScene, testTempGrantSceneParam)
+
+	targetId := randString(6)
+	token1, err := l.Issue(context.Background(), testScene, targetId, verified.WithMaxActive(3))
+	require.NoError(t, err)
+	token2, err := l.Issue(context.Background(), testScene, targetId, verified.WithMaxActive(3))
+	require.NoError(t, err)
+	token3, err := l.Issue(context.Background(), testScene, targetId, verified.WithMaxActive(3))
+	require.NoError(t, err)
+
+	b, err := l.Revoke(context.Background(), testScene, targetId, token1)
+	require.NoError(t, err)
+	require.True(t, b)
+	b, err = l.Revoke(context.Background(), testScene, targetId, token1)
+	require.NoError(t, err)
+	require.False(t, b)
+	b, err = l.Consume(context.Background(), testScene, targetId, token1)
+	require.NoError(t, err)
+	require.False(t, b)
+
+	err = l.RevokeAll(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	for _, token := range []string{token2, token3} {
+		b, err = l.Consume(context.Background(), testScene, targetId, token)
+		require.NoError(t, err)
+		require.False(t, b)
+	}
+}
+
+func GenericTest_TempGrant_Inspect[B TempGrantBackend](t *testing.T, _ *miniredis.Miniredis, backend B) {
+	l := verified.NewTempGrant[testSceneType](new(TestTempGrantProvider), backend).
+		SetKeyPrefix(testKeyPrefix).
+		SetSceneParam(testScene, &verified.Param{
+			KeyExpires:  testKeyExpires,
+			MaxAttempts: 3,
+		})
+
+	targetId := randString(6)
+	st, err := l.Inspect(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	require.Equal(t, testKeyPrefix+testScene.Value()+":"+targetId, st.Key)
+	require.Empty(t, st.Tokens)
+
+	token1, err := l.Issue(context.Background(), testScene, targetId, verified.WithMaxActive(3))
+	require.NoError(t, err)
+	_, err = l.Issue(context.Background(), testScene, targetId, verified.WithMaxActive(3))
+	require.NoError(t, err)
+	b, err := l.Consume(context.Background(), testScene, targetId, "bad_token")
+	require.NoError(t, err)
+	require.False(t, b)
+
+	st, err = l.Inspect(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	require.Len(t, st.Tokens, 2)
+	require.Equal(t, int64(1), st.Tokens[0].Seq)
+	require.Equal(t, int64(2), st.Tokens[1].Seq)
+	require.NotZero(t, st.Tokens[0].ExpireAt)
+	require.Equal(t, 1, st.Attempts)
+	require.Equal(t, 3, st.MaxAttempts)
+
+	// inspect never reveals token values and never modifies data
+	b, err = l.Consume(context.Background(), testScene, targetId, token1)
+	require.NoError(t, err)
+	require.True(t, b)
+	st, err = l.Inspect(context.Background(), testScene, targetId)
+	require.NoError(t, err)
+	require.Len(t, st.Tokens, 1)
+	require.Equal(t, int64(2), st.Tokens[0].Seq)
+}
+
+func GenericTest_TempGrant_Unsupported[B verified.StorageBackend](t *testing.T, mr *miniredis.Miniredis, backend B) {
+	l := verified.NewTempGrant[testSceneType](new(TestTempGrantProvider), backend).
+		SetKeyPrefix(testKeyPrefix).
+		SetSceneParam(testScene, testTempGrantSceneParam)
+
+	targetId := randString(6)
+	token, err := l.Issue(context.Background(), testScene, targetId, verified.WithMaxActive(3))
+	require.NoError(t, err)
+	// keep the single token format
+	require.Equal(t, token, mr.HGet(testKeyPrefix+testScene.Value()+":"+targetId, "value"))
+
+	_, err = l.Revoke(context.Background(), testScene, targetId, token)
+	require.ErrorIs(t, err, verified.ErrTempGrantUnsupported)
+	err = l.RevokeAll(context.Background(), testScene, targetId)
+	require.ErrorIs(t, err, verified.ErrTempGrantUnsupported)
+	_, err = l.Inspect(context.Background(), testScene, targetId)
+	require.ErrorIs(t, err, verified.ErrTempGrantUnsupported)
+
+	b, err := l.Consume(context.Background(), testScene, targetId, token)
+	require.NoError(t, err)
+	require.True(t, b)
+}
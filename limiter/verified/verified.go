@@ -11,12 +11,15 @@ type SceneValuer interface {
 type Param struct {
 	KeyExpires  time.Duration // 验证码key的过期时间
 	MaxAttempts int           // 验证码最大允许尝试次数
+	Driver      string        // 默认验证码驱动名称, 仅 captcha 有效, 未指定驱动名称时使用
+	MaxActive   int           // 最大同时有效令牌数, 仅 temp grant 且存储实现 TempGrantStorageBackend 时有效, 超过时撤销最旧的令牌
 }
 
 func NewParam() *Param {
 	return &Param{
 		KeyExpires:  time.Minute * 5,
 		MaxAttempts: 1,
+		MaxActive:   1,
 	}
 }
 
@@ -24,6 +27,8 @@ func (p *Param) clone() *Param {
 	return &Param{
 		KeyExpires:  p.KeyExpires,
 		MaxAttempts: p.MaxAttempts,
+		Driver:      p.Driver,
+		MaxActive:   p.MaxActive,
 	}
 }
 func (p *Param) apply(opts ...Option) *Param {
@@ -56,3 +61,19 @@ func WithMaxAttempts(attempts int) Option {
 		}
 	}
 }
+
+// WithDriver 设置默认验证码驱动名称, 仅 captcha 有效
+func WithDriver(name string) Option {
+	return func(p *Param) {
+		p.Driver = name
+	}
+}
+
+// WithMaxActive 设置最大同时有效令牌数, 仅 temp grant 且存储实现 TempGrantStorageBackend 时有效
+func WithMaxActive(n int) Option {
+	return func(p *Param) {
+		if n > 0 {
+			p.MaxActive = n
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package window_limiter
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInspectUnsupported the backend does not implement InspectorBackend.
+var ErrInspectUnsupported = errors.New("window_limiter: backend not support inspect")
+
+// Inspector the optional interface of WindowLimiter and WindowFailureLimiter.
+type Inspector[S SceneValuer] interface {
+	// Inspect 查看 key 的当前状态(窗口内记录, 锁定状态), 不修改任何数据.
+	Inspect(ctx context.Context, scene S, id string) (*InspectResult, error)
+}
+
+// InspectorBackend the optional interface of SlidingWindowLimiterBackend and SlidingWindowFailureLimiterBackend.
+type InspectorBackend interface {
+	Inspect(ctx context.Context, v *InspectRequest) (*InspectResult, error)
+}
+
+// InspectRequest read-only inspect arguments.
+type InspectRequest struct {
+	Key       string // key
+	LockedKey string // locked key
+	Window    int    // sliding window size in seconds
+	MaxLimit  int    // max limit requests/failures in the sliding window
+}
+
+// InspectResult the current state of a key, it never modifies any data.
+type InspectResult struct {
+	Key          string  `json:"key"`          // key
+	Window       int     `json:"window"`       // sliding window size in seconds
+	MaxLimit     int     `json:"maxLimit"`     // max limit requests/failures in the sliding window
+	Count        int     `json:"count"`        // the current count of requests/failures in the sliding window
+	Records      []int64 `json:"records"`      // unix timestamp (seconds) of the records in the sliding window
+	ExpireAt     int64   `json:"expireAt"`     // unix timestamp (seconds) at which the current window fully resets, 0 if no record.
+	Locked       bool    `json:"locked"`       // whether the key is locked
+	LockExpireAt int64   `json:"lockExpireAt"` // unix timestamp (seconds) at which the lock expires, 0 if not locked.
+}
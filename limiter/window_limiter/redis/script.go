@@ -21,3 +21,8 @@ var (
 	//go:embed sliding_window_failure_limiter_check.lua
 	ScriptSlidingWindowFailureLimiterCheck string
 )
+
+var (
+	//go:embed sliding_window_inspect.lua
+	ScriptSlidingWindowInspect string
+)
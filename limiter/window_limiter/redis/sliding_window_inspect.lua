@@ -0,0 +1,18 @@
+local key = KEYS[1]                -- 限制的Key
+local locked_key = KEYS[2]         -- 锁定的Key, 用于标记当前窗口是否被锁定.
+local window = tonumber(ARGV[1])   -- 有效时间窗口, 单位: 秒
+
+local time_res = redis.call('TIME') -- 获取redis节点当前时间.
+local now = tonumber(time_res[1])   -- 当前时间戳, 单位秒
+
+-- 只读, 不清除过期记录
+local lock_ttl = redis.call('TTL', locked_key)
+local ttl = redis.call('TTL', key)
+local records = redis.call('ZRANGEBYSCORE', key, '(' .. (now - window), '+inf', 'WITHSCORES')
+
+-- 返回: 当前时间, 锁定剩余时间, key剩余时间, [窗口内记录时间戳...]
+local res = { now, lock_ttl, ttl }
+for i = 2, #records, 2 do
+    res[#res + 1] = tonumber(records[i])
+end
+return res
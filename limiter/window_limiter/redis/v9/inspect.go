@@ -0,0 +1,78 @@
+package v9
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/thinkgos/proc-extra/limiter/inspect"
+	"github.com/thinkgos/proc-extra/limiter/window_limiter"
+	redis_script "github.com/thinkgos/proc-extra/limiter/window_limiter/redis"
+)
+
+var (
+	_ window_limiter.InspectorBackend = (*LimitRedisStore)(nil)
+	_ window_limiter.InspectorBackend = (*LimitFailureRedisStore)(nil)
+	_ inspect.Scanner                 = (*LimitRedisStore)(nil)
+	_ inspect.Scanner                 = (*LimitFailureRedisStore)(nil)
+)
+
+// Inspect implements [window_limiter.InspectorBackend].
+func (p *LimitRedisStore) Inspect(ctx context.Context, v *window_limiter.InspectRequest) (*window_limiter.InspectResult, error) {
+	return inspectSlidingWindow(ctx, p.store, v)
+}
+
+// ScanPrefix implements [inspect.Scanner].
+func (p *LimitRedisStore) ScanPrefix(ctx context.Context, prefix string, cursor uint64, count int64) ([]string, uint64, error) {
+	return p.store.Scan(ctx, cursor, inspect.MatchPrefix(prefix), count).Result()
+}
+
+// Inspect implements [window_limiter.InspectorBackend].
+func (p *LimitFailureRedisStore) Inspect(ctx context.Context, v *window_limiter.InspectRequest) (*window_limiter.InspectResult, error) {
+	return inspectSlidingWindow(ctx, p.store, v)
+}
+
+// ScanPrefix implements [inspect.Scanner].
+func (p *LimitFailureRedisStore) ScanPrefix(ctx context.Context, prefix string, cursor uint64, count int64) ([]string, uint64, error) {
+	return p.store.Scan(ctx, cursor, inspect.MatchPrefix(prefix), count).Result()
+}
+
+func inspectSlidingWindow(ctx context.Context, store *redis.Client, v *window_limiter.InspectRequest) (*window_limiter.InspectResult, error) {
+	// reply: [now, lock_ttl, ttl, records...]
+	vals, err := store.Eval(ctx,
+		redis_script.ScriptSlidingWindowInspect,
+		[]string{
+			v.Key,
+			v.LockedKey,
+		},
+		[]string{
+			strconv.Itoa(v.Window),
+		},
+	).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) < 3 {
+		return nil, fmt.Errorf("window_limiter: unexpected inspect reply length %d", len(vals))
+	}
+	now, lockTtl, ttl, records := vals[0], vals[1], vals[2], vals[3:]
+	result := &window_limiter.InspectResult{
+		Key:      v.Key,
+		Window:   v.Window,
+		MaxLimit: v.MaxLimit,
+		Count:    len(records),
+		Records:  records,
+	}
+	if lockTtl > 0 {
+		result.Locked = true
+		result.LockExpireAt = now + lockTtl
+	}
+	if len(records) > 0 {
+		result.ExpireAt = records[len(records)-1] + int64(v.Window)
+		if ttl > 0 {
+			result.ExpireAt = min(result.ExpireAt, now+ttl)
+		}
+	}
+	return result, nil
+}
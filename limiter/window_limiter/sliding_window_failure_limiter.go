@@ -106,3 +106,22 @@ func (l *SlidingWindowFailureLimiter[S, B]) Reset(ctx context.Context, scene S,
 		LockedKey: l.sps.formatLockedKey(scene.Value(), id),
 	})
 }
+
+// Inspect 查看 key 的当前状态(窗口内失败记录, 锁定状态), 不修改任何数据.
+// 后端未实现 InspectorBackend 时返回 ErrInspectUnsupported.
+func (l *SlidingWindowFailureLimiter[S, B]) Inspect(ctx context.Context, scene S, id string) (*InspectResult, error) {
+	ib, ok := any(l.backend).(InspectorBackend)
+	if !ok {
+		return nil, ErrInspectUnsupported
+	}
+	p := l.sps.useScene(scene)
+	return ib.Inspect(ctx, &InspectRequest{
+		Key:       l.sps.formatKey(scene.Value(), id),
+		LockedKey: l.sps.formatLockedKey(scene.Value(), id),
+		Window:    p.Window,
+		MaxLimit:  p.MaxLimit,
+	})
+}
+
+// KeyPrefix returns the key prefix.
+func (l *SlidingWindowFailureLimiter[S, B]) KeyPrefix() string { return l.sps.keyPrefix }
@@ -33,3 +33,15 @@ func Test_SlidingWindowFailureLimiter_Lock(t *testing.T) {
 		redisv9.NewLimitFailureRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
 	)
 }
+
+func Test_SlidingWindowFailureLimiter_Inspect(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_SlidingWindowFailureLimiter_Inspect(
+		t,
+		mr,
+		redisv9.NewLimitFailureRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
@@ -98,3 +98,22 @@ func (l *SlidingWindowLimiter[S, B]) Reset(ctx context.Context, scene S, id stri
 		LockedKey: l.sps.formatLockedKey(scene.Value(), id),
 	})
 }
+
+// Inspect 查看 key 的当前状态(窗口内请求记录, 锁定状态), 不修改任何数据.
+// 后端未实现 InspectorBackend 时返回 ErrInspectUnsupported.
+func (l *SlidingWindowLimiter[S, B]) Inspect(ctx context.Context, scene S, id string) (*InspectResult, error) {
+	ib, ok := any(l.backend).(InspectorBackend)
+	if !ok {
+		return nil, ErrInspectUnsupported
+	}
+	p := l.sps.useScene(scene)
+	return ib.Inspect(ctx, &InspectRequest{
+		Key:       l.sps.formatKey(scene.Value(), id),
+		LockedKey: l.sps.formatLockedKey(scene.Value(), id),
+		Window:    p.Window,
+		MaxLimit:  p.MaxLimit,
+	})
+}
+
+// KeyPrefix returns the key prefix.
+func (l *SlidingWindowLimiter[S, B]) KeyPrefix() string { return l.sps.keyPrefix }
@@ -33,3 +33,15 @@ func Test_SlidingWindowLimiter_Lock(t *testing.T) {
 		redisv9.NewLimitRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
 	)
 }
+
+func Test_SlidingWindowLimiter_Inspect(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	defer mr.Close()
+
+	tests.GenericTest_SlidingWindowLimiter_Inspect(
+		t,
+		mr,
+		redisv9.NewLimitRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	)
+}
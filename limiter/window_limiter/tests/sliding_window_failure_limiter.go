@@ -149,3 +149,53 @@ func GenericTest_SlidingWindowFailureLimiter_Lock[B window_limiter.SlidingWindow
 	require.Equal(t, testSlidingWindowFailureLimiterMaxAttempt, v.MaxFailures)
 	require.NotZero(t, v.ExpireAt)
 }
+
+func GenericTest_SlidingWindowFailureLimiter_Inspect[B window_limiter.SlidingWindowFailureLimiterBackend](t *testing.T, mr *miniredis.Miniredis, backend B) {
+	l := window_limiter.NewSlidingWindowFailureLimiter[testSceneType](backend).
+		SetKeyPrefix(testSlidingWindowFailureLimiterKeyPrefix).
+		SetGeneralParam(testSlidingWindowFailureLimiterParam)
+
+	// inspect empty state
+	v, err := l.Inspect(context.Background(), testSlidingWindowFailureLimiterScene, testSlidingWindowFailureLimiterId1)
+	require.NoError(t, err)
+	require.Equal(t, testSlidingWindowFailureLimiterKeyPrefix+testSlidingWindowFailureLimiterScene.Value()+":"+testSlidingWindowFailureLimiterId1, v.Key)
+	require.Equal(t, 0, v.Count)
+	require.Empty(t, v.Records)
+	require.Zero(t, v.ExpireAt)
+	require.False(t, v.Locked)
+	require.Equal(t, testSlidingWindowFailureLimiterWindow, v.Window)
+	require.Equal(t, testSlidingWindowFailureLimiterMaxAttempt, v.MaxLimit)
+
+	for range 2 {
+		_, err = l.Evaluate(context.Background(), testSlidingWindowFailureLimiterScene, testSlidingWindowFailureLimiterId1, true)
+		require.NoError(t, err)
+	}
+	v, err = l.Inspect(context.Background(), testSlidingWindowFailureLimiterScene, testSlidingWindowFailureLimiterId1)
+	require.NoError(t, err)
+	require.Equal(t, 2, v.Count)
+	require.Len(t, v.Records, 2)
+	require.NotZero(t, v.ExpireAt)
+	require.False(t, v.Locked)
+
+	// inspect never modifies data
+	mr.FastForward(time.Second * (testSlidingWindowFailureLimiterWindow + 1))
+	v, err = l.Inspect(context.Background(), testSlidingWindowFailureLimiterScene, testSlidingWindowFailureLimiterId1)
+	require.NoError(t, err)
+	require.Equal(t, 0, v.Count)
+
+	// inspect locked state
+	_, err = l.Lock(context.Background(), testSlidingWindowFailureLimiterScene, testSlidingWindowFailureLimiterId1)
+	require.NoError(t, err)
+	v, err = l.Inspect(context.Background(), testSlidingWindowFailureLimiterScene, testSlidingWindowFailureLimiterId1)
+	require.NoError(t, err)
+	require.True(t, v.Locked)
+	require.NotZero(t, v.LockExpireAt)
+
+	// the backend does not implement InspectorBackend
+	var _ window_limiter.Inspector[testSceneType] = l
+	unsupported := window_limiter.NewSlidingWindowFailureLimiter[testSceneType](struct {
+		window_limiter.SlidingWindowFailureLimiterBackend
+	}{backend})
+	_, err = unsupported.Inspect(context.Background(), testSlidingWindowFailureLimiterScene, testSlidingWindowFailureLimiterId1)
+	require.ErrorIs(t, err, window_limiter.ErrInspectUnsupported)
+}
@@ -154,3 +154,53 @@ func GenericTest_SlidingWindowLimiter_Lock[B window_limiter.SlidingWindowLimiter
 	require.Equal(t, testSlidingWindowLimiterMaxLimit, v.MaxLimit)
 	require.NotZero(t, v.ExpireAt)
 }
+
+func GenericTest_SlidingWindowLimiter_Inspect[B window_limiter.SlidingWindowLimiterBackend](t *testing.T, mr *miniredis.Miniredis, backend B) {
+	l := window_limiter.NewSlidingWindowLimiter[testSceneType](backend).
+		SetKeyPrefix(testSlidingWindowLimiterKeyPrefix).
+		SetGeneralParam(testSlidingWindowLimiterParam)
+
+	// inspect empty state
+	v, err := l.Inspect(context.Background(), testSlidingWindowLimiterScene, testSlidingWindowLimiterId1)
+	require.NoError(t, err)
+	require.Equal(t, testSlidingWindowLimiterKeyPrefix+testSlidingWindowLimiterScene.Value()+":"+testSlidingWindowLimiterId1, v.Key)
+	require.Equal(t, 0, v.Count)
+	require.Empty(t, v.Records)
+	require.Zero(t, v.ExpireAt)
+	require.False(t, v.Locked)
+	require.Equal(t, testSlidingWindowLimiterWindow, v.Window)
+	require.Equal(t, testSlidingWindowLimiterMaxLimit, v.MaxLimit)
+
+	for range 2 {
+		_, err = l.Take(context.Background(), testSlidingWindowLimiterScene, testSlidingWindowLimiterId1)
+		require.NoError(t, err)
+	}
+	v, err = l.Inspect(context.Background(), testSlidingWindowLimiterScene, testSlidingWindowLimiterId1)
+	require.NoError(t, err)
+	require.Equal(t, 2, v.Count)
+	require.Len(t, v.Records, 2)
+	require.NotZero(t, v.ExpireAt)
+	require.False(t, v.Locked)
+
+	// inspect never modifies data
+	mr.FastForward(time.Second * (testSlidingWindowLimiterWindow + 1))
+	v, err = l.Inspect(context.Background(), testSlidingWindowLimiterScene, testSlidingWindowLimiterId1)
+	require.NoError(t, err)
+	require.Equal(t, 0, v.Count)
+
+	// inspect locked state
+	_, err = l.Lock(context.Background(), testSlidingWindowLimiterScene, testSlidingWindowLimiterId1)
+	require.NoError(t, err)
+	v, err = l.Inspect(context.Background(), testSlidingWindowLimiterScene, testSlidingWindowLimiterId1)
+	require.NoError(t, err)
+	require.True(t, v.Locked)
+	require.NotZero(t, v.LockExpireAt)
+
+	// the backend does not implement InspectorBackend
+	var _ window_limiter.Inspector[testSceneType] = l
+	unsupported := window_limiter.NewSlidingWindowLimiter[testSceneType](struct {
+		window_limiter.SlidingWindowLimiterBackend
+	}{backend})
+	_, err = unsupported.Inspect(context.Background(), testSlidingWindowLimiterScene, testSlidingWindowLimiterId1)
+	require.ErrorIs(t, err, window_limiter.ErrInspectUnsupported)
+}
@@ -0,0 +1,156 @@
+package sses
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// ErrAckUnsupported the store does not implement AckStore.
+var ErrAckUnsupported = errors.New("sses: the store does not support ack")
+
+// ErrMethodNotAllowed the request method is not allowed, the ack endpoint only accepts POST.
+var ErrMethodNotAllowed = errors.New("sses: method not allowed")
+
+// maxAckBodySize the max size of the ack request body.
+const maxAckBodySize = 64 << 10
+
+// AckStore the store supports the delivery acknowledgements, the events which require ack are kept pending
+// per user until the client acks them, see WithAck.
+type AckStore interface {
+	Store
+	// SavePending saves the event pending for the user.
+	SavePending(ctx context.Context, userId, channel string, e *Event) error
+	// ListPending lists the pending events of the user, oldest first, the Event.Channel is set.
+	ListPending(ctx context.Context, userId string) ([]*Event, error)
+	// Ack removes the pending events of the user, returns the number of the removed events.
+	Ack(ctx context.Context, userId string, eventIds ...string) (int, error)
+	// PendingCount returns the number of the pending events of the user.
+	PendingCount(ctx context.Context, userId string) (int, error)
+}
+
+// requireAck reports whether the event requires ack.
+func (h *Hub) requireAck(e *Event) bool {
+	if h.ackStore == nil {
+		return false
+	}
+	if len(h.ackEventTypes) == 0 {
+		return true
+	}
+	_, ok := h.ackEventTypes[e.Event]
+	return ok
+}
+
+// Ack acks the delivered events of the user, the acked events are no longer redelivered,
+// it returns the number of the acked events. the multi-channel cursor id acks the event ids in it.
+func (h *Hub) Ack(ctx context.Context, userId string, eventIds ...string) (int, error) {
+	if h.ackStore == nil {
+		return 0, ErrAckUnsupported
+	}
+	ids := make([]string, 0, len(eventIds))
+	for _, id := range eventIds {
+		ids = append(ids, cursorEventIds(id)...)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return h.ackStore.Ack(ctx, userId, ids...)
+}
+
+// Unacked returns the number of the unacked events of the user.
+func (h *Hub) Unacked(ctx context.Context, userId string) (int, error) {
+	if h.ackStore == nil {
+		return 0, ErrAckUnsupported
+	}
+	return h.ackStore.PendingCount(ctx, userId)
+}
+
+// redeliver the pending events which the session subscribes, regardless of the last event id,
+// the events have been resent are skipped, it reports whether redelivered.
+func (h *Hub) redeliver(ctx context.Context, sw *sessionWriter, sr *sessionRequest) bool {
+	if h.ackStore == nil {
+		return false
+	}
+	events, err := h.ackStore.ListPending(ctx, sr.session.UserId)
+	if err != nil {
+		slog.Warn("ListPending events error", slog.Any("error", err))
+		return false
+	}
+	redelivered := false
+	for _, e := range events {
+		if _, ok := sw.replayed[e.Id]; ok {
+			continue
+		}
+		if !sr.session.Subscribes(e.Channel) || !sr.session.Accept(e) {
+			continue
+		}
+		if err = sw.Render(e); err != nil {
+			slog.Warn("redeliver event failure!",
+				slog.Any("error", err),
+				slog.String("eventId", e.Id),
+				slog.String("eventType", e.Event),
+			)
+			return redelivered
+		}
+		h.onReplayed(ctx, e, ReplaySource_Pending)
+		redelivered = true
+	}
+	return redelivered
+}
+
+// AckRequest the request body of the ack endpoint.
+type AckRequest struct {
+	Ids []string `json:"ids"` // 确认的事件id
+}
+
+// AckResponse the response of the ack endpoint.
+type AckResponse struct {
+	Acked   int `json:"acked"`   // 本次确认的事件数量
+	Pending int `json:"pending"` // 剩余未确认的事件数量
+}
+
+// AckHandler returns the ack endpoint, the user id is extracted the same as Serve (WithServeExtractUserId),
+// the event ids are from the query `id` (repeated or comma separated) and the JSON body AckRequest,
+// it responds the AckResponse JSON, the errors are handled by WithErrorFallback.
+// only POST is accepted (ErrMethodNotAllowed), and the body is limited to maxAckBodySize.
+func (h *Hub) AckHandler(opts ...ServeOption) http.Handler {
+	opt := defaultServeOptions().apply(opts...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			opt.errFallback(w, r, ErrMethodNotAllowed)
+			return
+		}
+		userId := opt.extractUserId(r)
+		if userId == "" {
+			opt.errFallback(w, r, errors.New("userId is empty, not allow ack"))
+			return
+		}
+		var ids []string
+		for _, v := range r.URL.Query()["id"] {
+			ids = append(ids, splitChannels(v)...)
+		}
+		if r.Body != nil && r.ContentLength != 0 {
+			var req AckRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAckBodySize)).Decode(&req); err != nil {
+				opt.errFallback(w, r, err)
+				return
+			}
+			ids = append(ids, req.Ids...)
+		}
+		acked, err := h.Ack(r.Context(), userId, ids...)
+		if err != nil {
+			opt.errFallback(w, r, err)
+			return
+		}
+		pending, err := h.Unacked(r.Context(), userId)
+		if err != nil {
+			opt.errFallback(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&AckResponse{Acked: acked, Pending: pending})
+	})
+}
@@ -0,0 +1,143 @@
+package sses
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MemoryStore_Pending(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := NewMemoryStore(100).SetMaxAge(time.Minute).SetMaxPending(3)
+	s.now = func() time.Time { return now }
+
+	for _, id := range []string{"e1", "e2", "e3", "e4"} {
+		require.NoError(t, s.SavePending(ctx, "u1", "ch1", &Event{Id: id, Event: "message", Data: id}))
+	}
+	// the oldest is dropped beyond the max pending.
+	events, err := s.ListPending(ctx, "u1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"e2", "e3", "e4"}, eventIds(events))
+	require.Equal(t, "ch1", events[0].Channel)
+
+	n, err := s.Ack(ctx, "u1", "e3", "unknown")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	n, err = s.PendingCount(ctx, "u1")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	n, err = s.PendingCount(ctx, "u2")
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// expired with the max age.
+	now = now.Add(time.Minute * 2)
+	n, err = s.PendingCount(ctx, "u1")
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func Test_Hub_Ack(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	t.Run("unsupported", func(t *testing.T) {
+		h := NewHub(WithStore(newTestMemoryStore()), WithAck())
+		defer h.Close() // nolint: errcheck
+		_, err := h.Ack(ctx, "u1", "e1")
+		require.ErrorIs(t, err, ErrAckUnsupported)
+		_, err = h.Unacked(ctx, "u1")
+		require.ErrorIs(t, err, ErrAckUnsupported)
+	})
+
+	h := NewHub(WithStore(NewMemoryStore(100)), WithAck("order"))
+	defer h.Close() // nolint: errcheck
+	extractUserId := WithServeExtractUserId(func(r *http.Request) string { return r.URL.Query().Get("userId") })
+	mux := http.NewServeMux()
+	mux.Handle("/events", h.Serve(extractUserId))
+	mux.Handle("/ack", h.AckHandler(extractUserId))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	connect := func(ctx context.Context, query string) *Decoder {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events?userId=u1&"+query, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return NewDecoder(resp.Body)
+	}
+	next := func(dec *Decoder) string {
+		e, err := dec.Decode()
+		require.NoError(t, err)
+		return e.Data.(string)
+	}
+	ack := func(query, body string) AckResponse {
+		resp, err := http.Post(srv.URL+"/ack?userId=u1&"+query, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint: errcheck
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var v AckResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&v))
+		return v
+	}
+
+	// only the events of the ack types published to the user are tracked.
+	require.NoError(t, h.Publish(ctx, "ch1", "u1",
+		&Event{Id: "e1", Event: "order", Data: "1"},
+		&Event{Id: "e2", Event: "note", Data: "2"},
+	))
+	require.NoError(t, h.Publish(ctx, "ch2", "u1", &Event{Id: "e3", Event: "order", Data: "3"}))
+	require.NoError(t, h.Broadcast(ctx, "ch1", &Event{Id: "e4", Event: "order", Data: "4"}))
+	n, err := h.Unacked(ctx, "u1")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	// redelivered on connect without Last-Event-ID, only the subscribed channels.
+	connCtx, connCancel := context.WithCancel(ctx)
+	dec := connect(connCtx, "channel=ch1")
+	require.Equal(t, "1", next(dec))
+	require.Equal(t, AckResponse{Acked: 1, Pending: 1}, ack("id=e1,unknown", ""))
+	require.Equal(t, AckResponse{Acked: 0, Pending: 1}, ack("id=e1", ""))
+	require.NoError(t, h.Publish(ctx, "ch1", "u1", &Event{Id: "e5", Event: "order", Data: "5"}))
+	require.Equal(t, "5", next(dec))
+	connCancel()
+	require.Eventually(t, func() bool { return h.SessionTotal() == 0 }, time.Second, time.Millisecond*10)
+
+	// resent with Last-Event-ID, the resent events are not redelivered twice, the multi-channel session renders the cursor id.
+	require.NoError(t, h.Publish(ctx, "ch1", "u1", &Event{Id: "e6", Event: "order", Data: "6"}))
+	connCtx, connCancel = context.WithCancel(ctx)
+	defer connCancel()
+	dec = connect(connCtx, "channel=ch1,ch2&eventType=order&lastEventId="+url.QueryEscape("ch1=e5"))
+	require.Equal(t, "6", next(dec)) // resent
+	require.Equal(t, "3", next(dec)) // redelivered
+	require.Equal(t, "5", next(dec)) // redelivered
+	require.NoError(t, h.Publish(ctx, "ch2", "u1", &Event{Id: "e7", Event: "note", Data: "7"}))
+	require.Equal(t, "7", next(dec))
+
+	// ack by the cursor id and the JSON body.
+	cursor := url.Values{"ch1": {"e6"}, "ch2": {"e3"}}.Encode()
+	require.Equal(t, AckResponse{Acked: 3, Pending: 0}, ack("", `{"ids":["`+cursor+`","e5"]}`))
+
+	// only POST is accepted.
+	resp, err := http.Get(srv.URL + "/ack?userId=u1&id=e1")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	require.Equal(t, http.MethodPost, resp.Header.Get("Allow"))
+	// the body is limited.
+	body := `{"ids":["` + strings.Repeat("e", maxAckBodySize) + `"]}`
+	resp, err = http.Post(srv.URL+"/ack?userId=u1", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
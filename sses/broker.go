@@ -0,0 +1,157 @@
+package sses
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// BrokerTarget the target sessions of the broker message.
+type BrokerTarget int
+
+const (
+	// BrokerTarget_Broadcast all sessions which subscribe the channel.
+	BrokerTarget_Broadcast BrokerTarget = iota
+	// BrokerTarget_User the user's sessions which subscribe the channel.
+	BrokerTarget_User
+	// BrokerTarget_Session the user's specified session which subscribe the channel.
+	BrokerTarget_Session
+)
+
+// BrokerMessage the message fan-out to all hub nodes through the broker.
+type BrokerMessage struct {
+	Target    BrokerTarget // 推送目标
+	Channel   string       // 频道
+	UserId    string       // 用户id, BrokerTarget_User, BrokerTarget_Session 有效
+	SessionId string       // 会话id, BrokerTarget_Session 有效
+	Events    []*Event     // 事件
+	Origin    string       // 发布的节点id, 节点跳过自己发布的消息, 为空时所有节点都推送
+}
+
+// Broker the cross-instance fan-out broker, the hub publishes through it and subscribes to it.
+type Broker interface {
+	// Publish the message to all subscribers, include the origin hub node.
+	Publish(ctx context.Context, msg *BrokerMessage) error
+	// Subscribe registers the handler, it returns after the subscription is ready,
+	// the handler is called until the returned unsubscribe function is called.
+	Subscribe(ctx context.Context, handler func(*BrokerMessage)) (unsubscribe func(), err error)
+}
+
+type brokerWireEvent struct {
+	Event    string            `json:"event"`
+	Id       string            `json:"id"`
+	Retry    uint              `json:"retry,omitempty"`
+	Data     []byte            `json:"data"`
+	Meta     map[string]string `json:"meta,omitempty"`
+	Comments []string          `json:"comments,omitempty"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+type brokerWireMessage struct {
+	Target    BrokerTarget       `json:"target"`
+	Channel   string             `json:"channel"`
+	UserId    string             `json:"userId,omitempty"`
+	SessionId string             `json:"sessionId,omitempty"`
+	Events    []*brokerWireEvent `json:"events"`
+	Origin    string             `json:"origin,omitempty"`
+}
+
+// EncodeBrokerMessage encode the broker message for the wire,
+// the event data is pre-rendered so that the remote nodes render the same data as the origin node.
+func EncodeBrokerMessage(msg *BrokerMessage) ([]byte, error) {
+	wm := &brokerWireMessage{
+		Target:    msg.Target,
+		Channel:   msg.Channel,
+		UserId:    msg.UserId,
+		SessionId: msg.SessionId,
+		Events:    make([]*brokerWireEvent, 0, len(msg.Events)),
+		Origin:    msg.Origin,
+	}
+	for _, e := range msg.Events {
+		data, err := MarshalData(e.Data)
+		if err != nil {
+			return nil, err
+		}
+		wm.Events = append(wm.Events, &brokerWireEvent{
+			Event:    e.Event,
+			Id:       e.Id,
+			Retry:    e.Retry,
+			Data:     data,
+			Meta:     e.Meta,
+			Comments: e.Comments,
+			Fields:   e.Fields,
+		})
+	}
+	return json.Marshal(wm)
+}
+
+// DecodeBrokerMessage decode the broker message from the wire, the event data is []byte.
+func DecodeBrokerMessage(b []byte) (*BrokerMessage, error) {
+	var wm brokerWireMessage
+
+	if err := json.Unmarshal(b, &wm); err != nil {
+		return nil, err
+	}
+	msg := &BrokerMessage{
+		Target:    wm.Target,
+		Channel:   wm.Channel,
+		UserId:    wm.UserId,
+		SessionId: wm.SessionId,
+		Events:    make([]*Event, 0, len(wm.Events)),
+		Origin:    wm.Origin,
+	}
+	for _, e := range wm.Events {
+		msg.Events = append(msg.Events, &Event{
+			Event:    e.Event,
+			Id:       e.Id,
+			Retry:    e.Retry,
+			Data:     e.Data,
+			Meta:     e.Meta,
+			Comments: e.Comments,
+			Fields:   e.Fields,
+		})
+	}
+	return msg, nil
+}
+
+// MemoryBroker in-process broker, fan-out to all hubs in the same process.
+type MemoryBroker struct {
+	mu       sync.RWMutex
+	seq      uint64
+	handlers map[uint64]func(*BrokerMessage)
+}
+
+// NewMemoryBroker new an in-process broker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		handlers: make(map[uint64]func(*BrokerMessage)),
+	}
+}
+
+// Publish implements Broker.
+func (b *MemoryBroker) Publish(_ context.Context, msg *BrokerMessage) error {
+	b.mu.RLock()
+	handlers := make([]func(*BrokerMessage), 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+// Subscribe implements Broker.
+func (b *MemoryBroker) Subscribe(_ context.Context, handler func(*BrokerMessage)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	id := b.seq
+	b.handlers[id] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}, nil
+}
@@ -0,0 +1,108 @@
+package sses
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_BrokerMessage_Codec(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+	msg := &BrokerMessage{
+		Target:    BrokerTarget_Session,
+		Channel:   "ch1",
+		UserId:    "u1",
+		SessionId: "s1",
+		Origin:    "n1",
+		Events: []*Event{
+			{Id: "e1", Event: "test", Data: "hello"},
+			{Id: "e2", Event: "test", Retry: 1000, Data: payload{Name: "world"}},
+			{Id: "e3", Event: "test", Data: []byte("bytes")},
+			{Id: "e4", Event: "test", Data: 100},
+			{Id: "e5", Event: "test", Data: "x", Comments: []string{"c"}, Fields: map[string]string{"f": "v"}},
+		},
+	}
+	b, err := EncodeBrokerMessage(msg)
+	require.NoError(t, err)
+	got, err := DecodeBrokerMessage(b)
+	require.NoError(t, err)
+	require.Equal(t, &BrokerMessage{
+		Target:    BrokerTarget_Session,
+		Channel:   "ch1",
+		UserId:    "u1",
+		SessionId: "s1",
+		Origin:    "n1",
+		Events: []*Event{
+			{Id: "e1", Event: "test", Data: []byte("hello")},
+			{Id: "e2", Event: "test", Retry: 1000, Data: []byte(`{"name":"world"}`)},
+			{Id: "e3", Event: "test", Data: []byte("bytes")},
+			{Id: "e4", Event: "test", Data: []byte("100")},
+			{Id: "e5", Event: "test", Data: []byte("x"), Comments: []string{"c"}, Fields: map[string]string{"f": "v"}},
+		},
+	}, got)
+
+	_, err = DecodeBrokerMessage([]byte("invalid"))
+	require.Error(t, err)
+}
+
+func Test_MemoryBroker(t *testing.T) {
+	broker := NewMemoryBroker()
+	h1 := NewHub(WithBroker(broker))
+	h2 := NewHub(WithBroker(broker))
+	h3 := NewHub(WithBroker(broker))
+
+	s1 := &Session{Channel: "ch1", UserId: "u1", SessionId: NewSessionId(), Message: make(chan *Event, 10)}
+	s2 := &Session{Channel: "ch1", UserId: "u1", SessionId: NewSessionId(), Message: make(chan *Event, 10)}
+	s3 := &Session{Channel: "ch1", UserId: "u2", SessionId: NewSessionId(), Message: make(chan *Event, 10)}
+	h1.sessions.Add(s1)
+	h2.sessions.Add(s2)
+	h3.sessions.Add(s3)
+
+	err := h1.Broadcast(context.Background(), "ch1", &Event{Id: "e1", Event: "test", Data: "data"})
+	require.NoError(t, err)
+	err = h2.Publish(context.Background(), "ch1", "u1", &Event{Id: "e2", Event: "test", Data: "data"})
+	require.NoError(t, err)
+	err = h3.PublishSession(context.Background(), "ch1", "u1", s1.SessionId, &Event{Id: "e3", Event: "test", Data: "data"})
+	require.NoError(t, err)
+	// the same event published to the other targets is delivered by all nodes.
+	e4 := &Event{Event: "test", Data: "data"}
+	err = h1.Publish(context.Background(), "ch1", "u1", e4)
+	require.NoError(t, err)
+	err = h1.Publish(context.Background(), "ch1", "u2", e4)
+	require.NoError(t, err)
+	err = h1.Broadcast(context.Background(), "ch2", e4)
+	require.NoError(t, err)
+	err = h1.Broadcast(context.Background(), "ch1", e4)
+	require.NoError(t, err)
+
+	collect := func(ses *Session) []string {
+		ids := make([]string, 0)
+		for {
+			select {
+			case e, ok := <-ses.Message:
+				if !ok {
+					return ids
+				}
+				ids = append(ids, e.Id)
+			case <-time.After(time.Millisecond * 100):
+				return ids
+			}
+		}
+	}
+	require.Equal(t, []string{"e1", "e2", "e3", e4.Id, e4.Id}, collect(s1))
+	require.Equal(t, []string{"e1", "e2", e4.Id, e4.Id}, collect(s2))
+	require.Equal(t, []string{"e1", e4.Id, e4.Id}, collect(s3))
+
+	// unsubscribe after close
+	require.NoError(t, h3.Close())
+	err = h1.Broadcast(context.Background(), "ch1", &Event{Id: "e5", Event: "test", Data: "data"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"e5"}, collect(s2))
+	require.Equal(t, []string{""}, collect(s3)) // only the final shutdown event, then closed
+	require.NoError(t, h1.Close())
+	require.NoError(t, h2.Close())
+}
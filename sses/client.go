@@ -0,0 +1,228 @@
+package sses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// ErrNotEventStream the response content type is not `text/event-stream`.
+var ErrNotEventStream = errors.New("sses: response is not the event stream")
+
+// StatusError the unexpected response status of the event stream.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("sses: unexpected status code %d", e.StatusCode)
+}
+
+// Temporary reports whether the client should reconnect, the 5xx and 429 are temporary.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+// Client the event stream client, like the EventSource,
+// it reconnects with the `Last-Event-ID` and backoff when disconnected.
+type Client struct {
+	url         string
+	httpClient  *http.Client
+	header      http.Header
+	lastEventId string
+	retry       time.Duration
+	maxRetry    time.Duration
+	maxAttempts int
+}
+
+// NewClient new the event stream client, the default reconnection time is 3 seconds, up to 1 minute with backoff.
+func NewClient(url string) *Client {
+	return &Client{
+		url:         url,
+		httpClient:  http.DefaultClient,
+		header:      make(http.Header),
+		lastEventId: "",
+		retry:       time.Second * 3,
+		maxRetry:    time.Minute,
+		maxAttempts: 0,
+	}
+}
+
+// SetHttpClient sets the http client, the client should not set the timeout for the long-lived stream.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (c *Client) SetHttpClient(httpClient *http.Client) *Client {
+	if httpClient != nil {
+		c.httpClient = httpClient
+	}
+	return c
+}
+
+// SetHeader sets the request header, such as the authorization.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (c *Client) SetHeader(key, value string) *Client {
+	c.header.Set(key, value)
+	return c
+}
+
+// SetLastEventId sets the initial last event id.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (c *Client) SetLastEventId(id string) *Client {
+	c.lastEventId = id
+	return c
+}
+
+// SetRetry sets the reconnection time, it is overridden by the `retry:` field from the server.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (c *Client) SetRetry(retry time.Duration) *Client {
+	if retry > 0 {
+		c.retry = retry
+	}
+	return c
+}
+
+// SetMaxRetry sets the max reconnection time of the backoff.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (c *Client) SetMaxRetry(maxRetry time.Duration) *Client {
+	if maxRetry > 0 {
+		c.maxRetry = maxRetry
+	}
+	return c
+}
+
+// SetMaxAttempts sets the max consecutive failed connection attempts, 0 means unlimited.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (c *Client) SetMaxAttempts(n int) *Client {
+	c.maxAttempts = max(n, 0)
+	return c
+}
+
+// Events returns the events of the stream, it reconnects until the context done, or
+//   - the server responds 204 No Content, the iteration ends.
+//   - the server responds the non-temporary status (see StatusError) or not the event stream (ErrNotEventStream),
+//     the error is yielded then ends.
+//   - the consecutive failed attempts reach the max attempts, the error is yielded then ends.
+//
+// the connection errors are yielded with the nil event, and then it reconnects, the caller can break the iteration.
+func (c *Client) Events(ctx context.Context) iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		lastEventId := c.lastEventId
+		retry := c.retry
+		failures := 0
+		for attempt := 0; ; attempt++ {
+			if attempt > 0 {
+				delay := min(retry<<min(failures, 16), c.maxRetry)
+				t := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return
+				case <-t.C:
+				}
+			}
+			body, err := c.connect(ctx, lastEventId)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if se := (*StatusError)(nil); errors.Is(err, ErrNotEventStream) || (errors.As(err, &se) && !se.Temporary()) {
+					yield(nil, err)
+					return
+				}
+				failures++
+				if c.maxAttempts > 0 && failures >= c.maxAttempts {
+					yield(nil, fmt.Errorf("sses: reach max attempts %d, %w", c.maxAttempts, err))
+					return
+				}
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			if body == nil { // 204 No Content
+				return
+			}
+			failures = 0
+			dec := NewDecoder(body)
+			dec.lastEventId = lastEventId
+			for {
+				e, err := dec.Decode()
+				lastEventId = dec.LastEventId()
+				if dec.Retry() > 0 {
+					retry = dec.Retry()
+				}
+				if err != nil {
+					_ = body.Close()
+					if ctx.Err() != nil {
+						return
+					}
+					if !errors.Is(err, io.EOF) && !yield(nil, err) {
+						return
+					}
+					break
+				}
+				if !yield(e, nil) {
+					_ = body.Close()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Subscribe returns the events on a channel, the channel is closed when the stream ends or the context done.
+// the errors are dropped, use Events if the errors matter.
+func (c *Client) Subscribe(ctx context.Context) <-chan *Event {
+	ch := make(chan *Event)
+	go func() {
+		defer close(ch)
+		for e, err := range c.Events(ctx) {
+			if err != nil {
+				continue
+			}
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// connect to the server, it returns nil body if the server responds 204 No Content.
+func (c *Client) connect(ctx context.Context, lastEventId string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		_ = resp.Body.Close()
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		_ = resp.Body.Close()
+		return nil, ErrNotEventStream
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,124 @@
+package sses
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Client(t *testing.T) {
+	var attempts atomic.Int32
+	lastEventIds := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIds <- r.Header.Get("Last-Event-ID")
+		switch attempts.Add(1) {
+		case 1:
+			w.Header().Set("Content-Type", ContentType)
+			_, _ = w.Write([]byte(heartbeat + "retry:10\nid:e1\ndata:one\n\nid:e2\ndata:two\n\n"))
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable) // temporary, reconnect
+		case 3:
+			w.Header().Set("Content-Type", ContentType)
+			_, _ = w.Write([]byte("id:e3\nevent:other\ndata:three\n\n"))
+		default:
+			w.WriteHeader(http.StatusNoContent) // stop
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	got := []*Event{}
+	errs := 0
+	for e, err := range NewClient(srv.URL).SetLastEventId("e0").Events(ctx) {
+		if err != nil {
+			var se *StatusError
+			require.ErrorAs(t, err, &se)
+			require.Equal(t, http.StatusServiceUnavailable, se.StatusCode)
+			errs++
+			continue
+		}
+		got = append(got, e)
+	}
+	require.Equal(t, []*Event{
+		{Id: "e1", Event: "message", Data: "one", Retry: 10},
+		{Id: "e2", Event: "message", Data: "two"},
+		{Id: "e3", Event: "other", Data: "three"},
+	}, got)
+	require.Equal(t, 1, errs)
+	require.Equal(t, int32(4), attempts.Load())
+	close(lastEventIds)
+	ids := []string{}
+	for id := range lastEventIds {
+		ids = append(ids, id)
+	}
+	require.Equal(t, []string{"e0", "e2", "e2", "e3"}, ids)
+}
+
+func Test_Client_Fatal(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	collectErrs := func(c *Client) []error {
+		errs := []error{}
+		for _, err := range c.Events(ctx) {
+			errs = append(errs, err)
+		}
+		return errs
+	}
+
+	t.Run("non-temporary status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer srv.Close()
+		errs := collectErrs(NewClient(srv.URL))
+		require.Len(t, errs, 1)
+		require.Equal(t, &StatusError{StatusCode: http.StatusUnauthorized}, errs[0])
+	})
+	t.Run("not event stream", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+		}))
+		defer srv.Close()
+		errs := collectErrs(NewClient(srv.URL))
+		require.Len(t, errs, 1)
+		require.ErrorIs(t, errs[0], ErrNotEventStream)
+	})
+	t.Run("max attempts", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+		errs := collectErrs(NewClient(srv.URL).SetRetry(time.Millisecond).SetMaxAttempts(3))
+		require.Len(t, errs, 3)
+		require.ErrorContains(t, errs[2], "reach max attempts 3")
+	})
+}
+
+func Test_Client_Subscribe(t *testing.T) {
+	h := NewHub()
+	defer h.Close() // nolint: errcheck
+	srv := httptest.NewServer(h.Serve(WithServeExtractUserId(func(*http.Request) string { return "u1" })))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	ch := NewClient(srv.URL + "?channel=ch1").Subscribe(ctx)
+	require.Eventually(t, func() bool { return h.SessionTotal() == 1 }, time.Second, time.Millisecond*10)
+	require.NoError(t, h.Broadcast(ctx, "ch1",
+		&Event{Id: "e1", Event: "message", Data: map[string]string{"name": "hello"}},
+		&Event{Id: "e2", Event: "message", Data: "multi\nline"},
+	))
+	require.Equal(t, &Event{Id: "e1", Event: "message", Data: `{"name":"hello"}`}, <-ch)
+	require.Equal(t, &Event{Id: "e2", Event: "message", Data: "multi\nline"}, <-ch)
+	cancel()
+	for range ch {
+	}
+	_, ok := <-ch
+	require.False(t, ok)
+}
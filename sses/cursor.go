@@ -0,0 +1,176 @@
+package sses
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// LastIdStore the store reports the last event id of the channel, it is optional,
+// the multi-channel session seeds the cursor with it, see sessionCursor.
+type LastIdStore interface {
+	Store
+	// LastId returns the last event id of the channel, empty if the channel has no events.
+	LastId(ctx context.Context, channel string) (string, error)
+}
+
+// maxSessionChannels the max channels (include wildcard) a session subscribes.
+const maxSessionChannels = 32
+
+// maxWildcardCursor the max matched channels kept in the cursor per wildcard subscription,
+// the least recently updated are dropped beyond it.
+const maxWildcardCursor = 8
+
+// cursorEntry the last event id of the channel.
+type cursorEntry struct {
+	channel string
+	id      string
+}
+
+// sessionCursor the last event ids of the multi-channel or wildcard session, keyed by the subscription,
+// it is encoded as the url encoded `subscription=lastEventId` pairs, the value of the wildcard subscription
+// is the url encoded `channel=lastEventId` pairs of the matched channels, up to maxWildcardCursor.
+// the concrete subscription is seeded with the last event id of the channel when the session registered,
+// the empty id means the channel had no events, its events are resent from the oldest.
+type sessionCursor struct {
+	subscriptions []string
+	last          map[string]string        // subscription -> last event id, 非通配符订阅
+	matched       map[string][]cursorEntry // wildcard subscription -> 匹配的channel的最后事件id, 最近更新的在最后
+}
+
+func newSessionCursor(subscriptions []string) *sessionCursor {
+	return &sessionCursor{
+		subscriptions: subscriptions,
+		last:          make(map[string]string),
+		matched:       make(map[string][]cursorEntry),
+	}
+}
+
+// parseSessionCursor parse the cursor of the subscriptions, the not subscribed channels are ignored.
+// the channel matched the wildcard subscription at the top level (the previous cursor format) is accepted too.
+func parseSessionCursor(subscriptions []string, s string) *sessionCursor {
+	c := newSessionCursor(subscriptions)
+	values, _ := url.ParseQuery(s)
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if !IsWildcardChannel(key) {
+			c.update(key, values.Get(key))
+			c.seed(key, "")
+			continue
+		}
+		matched, _ := url.ParseQuery(values.Get(key))
+		for _, ch := range slices.Sorted(maps.Keys(matched)) {
+			c.update(ch, matched.Get(ch))
+		}
+	}
+	return c
+}
+
+// update the last event id of the channel for the subscriptions match it.
+func (c *sessionCursor) update(channel, id string) {
+	if id == "" {
+		return
+	}
+	for _, sub := range c.subscriptions {
+		switch {
+		case sub == channel:
+			c.last[sub] = id
+		case IsWildcardChannel(sub) && MatchChannel(sub, channel):
+			entries := slices.DeleteFunc(c.matched[sub], func(e cursorEntry) bool { return e.channel == channel })
+			entries = append(entries, cursorEntry{channel: channel, id: id})
+			if len(entries) > maxWildcardCursor {
+				entries = slices.Delete(entries, 0, len(entries)-maxWildcardCursor)
+			}
+			c.matched[sub] = entries
+		}
+	}
+}
+
+// seed the last event id of the concrete subscription not in the cursor.
+func (c *sessionCursor) seed(sub, id string) {
+	if _, ok := c.last[sub]; ok || IsWildcardChannel(sub) || !slices.Contains(c.subscriptions, sub) {
+		return
+	}
+	c.last[sub] = id
+}
+
+// lastIds returns the last event ids of the subscription, the channel -> last event id,
+// the concrete subscription not in the cursor returns nil, the same as a single channel session
+// reconnects without the last event id.
+func (c *sessionCursor) lastIds(sub string) []cursorEntry {
+	if IsWildcardChannel(sub) {
+		return slices.Clone(c.matched[sub])
+	}
+	id, ok := c.last[sub]
+	if !ok {
+		return nil
+	}
+	return []cursorEntry{{channel: sub, id: id}}
+}
+
+// Encode the cursor.
+func (c *sessionCursor) Encode() string {
+	values := url.Values{}
+	for sub, id := range c.last {
+		values.Set(sub, id)
+	}
+	for sub, entries := range c.matched {
+		matched := url.Values{}
+		for _, e := range entries {
+			matched.Set(e.channel, e.id)
+		}
+		values.Set(sub, matched.Encode())
+	}
+	return values.Encode()
+}
+
+// cursorEventIds returns the event ids in the cursor id, or the id itself if it is not a cursor.
+func cursorEventIds(id string) []string {
+	if !strings.Contains(id, "=") {
+		return []string{id}
+	}
+	values, err := url.ParseQuery(id)
+	if err != nil {
+		return []string{id}
+	}
+	ids := make([]string, 0, len(values))
+	for key, vs := range values {
+		if !IsWildcardChannel(key) {
+			for _, v := range vs {
+				if v != "" {
+					ids = append(ids, v)
+				}
+			}
+			continue
+		}
+		for _, v := range vs {
+			matched, _ := url.ParseQuery(v)
+			for _, mv := range matched {
+				ids = append(ids, mv...)
+			}
+		}
+	}
+	return ids
+}
+
+// seedCursor seeds the cursor of the multi-channel session with the last event ids of the concrete subscriptions
+// not in the cursor, so that the events published before the session registered are never resent.
+func (h *Hub) seedCursor(ctx context.Context, sw *sessionWriter) {
+	ls, ok := h.store.(LastIdStore)
+	if !ok || sw.cursor == nil {
+		return
+	}
+	for _, sub := range sw.cursor.subscriptions {
+		if _, ok := sw.cursor.last[sub]; ok || IsWildcardChannel(sub) {
+			continue
+		}
+		id, err := ls.LastId(ctx, sub)
+		if err != nil {
+			slog.WarnContext(ctx, "sses: get last event id failure", slog.String("channel", sub), slog.Any("error", err))
+			continue
+		}
+		sw.cursor.seed(sub, id)
+	}
+}
@@ -0,0 +1,47 @@
+package sses
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SessionCursor(t *testing.T) {
+	subs := []string{"ch1", "orders/+/status", "ch2"}
+	c := newSessionCursor(subs)
+	c.update("ch1", "e1")
+	c.update("other", "x") // not subscribed
+	for i := range maxWildcardCursor + 2 {
+		c.update("orders/"+strconv.Itoa(i)+"/status", "o"+strconv.Itoa(i))
+	}
+	c.update("orders/2/status", "o2-new") // the most recent
+	// capped by the subscriptions and the matched channels of the wildcard subscription.
+	values, err := url.ParseQuery(c.Encode())
+	require.NoError(t, err)
+	require.Len(t, values, 2)
+	entries := c.lastIds("orders/+/status")
+	require.Len(t, entries, maxWildcardCursor)
+	require.Equal(t, cursorEntry{channel: "orders/3/status", id: "o3"}, entries[0])
+	require.Equal(t, cursorEntry{channel: "orders/2/status", id: "o2-new"}, entries[maxWildcardCursor-1])
+	require.Empty(t, c.lastIds("ch2"))
+
+	// round trip
+	parsed := parseSessionCursor(subs, c.Encode())
+	require.Equal(t, c.last, parsed.last)
+	require.ElementsMatch(t, c.lastIds("orders/+/status"), parsed.lastIds("orders/+/status"))
+	require.ElementsMatch(t, []string{"e1", "o2-new", "o3", "o4", "o5", "o6", "o7", "o8", "o9"}, cursorEventIds(c.Encode()))
+
+	// the previous format keyed by the matched channel.
+	parsed = parseSessionCursor(subs, "ch1=e1&orders%2F1%2Fstatus=e3&unknown=e4")
+	require.Equal(t, []cursorEntry{{channel: "ch1", id: "e1"}}, parsed.lastIds("ch1"))
+	require.Equal(t, []cursorEntry{{channel: "orders/1/status", id: "e3"}}, parsed.lastIds("orders/+/status"))
+
+	// the empty id is kept, the channel is resent from the oldest.
+	parsed = parseSessionCursor(subs, "ch1=e1&ch2=")
+	require.Equal(t, []cursorEntry{{channel: "ch2", id: ""}}, parsed.lastIds("ch2"))
+	require.Equal(t, []string{"e1"}, cursorEventIds("ch1=e1&ch2="))
+
+	require.Equal(t, []string{"e1"}, cursorEventIds("e1"))
+}
@@ -0,0 +1,133 @@
+package sses
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decoder decodes the `text/event-stream` format, it is the reverse of Encode.
+// see https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+type Decoder struct {
+	r           *bufio.Reader
+	lastEventId string        // 最后的事件id, 跨事件保持
+	retry       time.Duration // 服务端指定的重连间隔, 0 表示未指定
+	comments    bool          // 是否保留注释行
+}
+
+// NewDecoder new a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// LastEventId returns the last event id, it persists across the events.
+func (d *Decoder) LastEventId() string { return d.lastEventId }
+
+// SetComments sets whether to keep the comment lines in Event.Comments, the block with only comments
+// is decoded as the event with only comments (no event type and nil data). default false, the comments are skipped.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (d *Decoder) SetComments(keep bool) *Decoder {
+	d.comments = keep
+	return d
+}
+
+// Retry returns the reconnection time set by the `retry:` field, 0 if not set.
+func (d *Decoder) Retry() time.Duration { return d.retry }
+
+// Decode the next event, the comments (such as the heartbeats) are skipped unless SetComments,
+// the event without data is discarded. the event data is string, multi-line `data:` are joined with "\n",
+// the event type defaults to DefaultEventType, the unknown fields are kept in Event.Fields.
+// it returns io.EOF if the stream ends, the incomplete event at the end of stream is discarded.
+func (d *Decoder) Decode() (*Event, error) {
+	var (
+		eventType string
+		data      strings.Builder
+		hasData   bool
+		retry     uint
+		comments  []string
+		fields    map[string]string
+	)
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 { // dispatch the event
+			if !hasData {
+				if len(comments) > 0 {
+					return &Event{Comments: comments}, nil
+				}
+				eventType, retry, fields = "", 0, nil
+				continue
+			}
+			if eventType == "" {
+				eventType = DefaultEventType
+			}
+			return &Event{
+				Event:    eventType,
+				Id:       d.lastEventId,
+				Retry:    retry,
+				Data:     data.String(),
+				Comments: comments,
+				Fields:   fields,
+			}, nil
+		}
+		if line[0] == ':' { // comment
+			if d.comments {
+				comments = append(comments, string(bytes.TrimPrefix(line[1:], []byte{' '})))
+			}
+			continue
+		}
+		field, value, _ := bytes.Cut(line, []byte{':'})
+		value = bytes.TrimPrefix(value, []byte{' '})
+		switch string(field) {
+		case "event":
+			eventType = string(value)
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.Write(value)
+			hasData = true
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				d.lastEventId = string(value)
+			}
+		case "retry":
+			if v, err := strconv.ParseUint(string(value), 10, 64); err == nil {
+				retry = uint(v)
+				d.retry = time.Duration(v) * time.Millisecond
+			}
+		default:
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[string(field)] = string(value)
+		}
+	}
+}
+
+// readLine read a line without the line ending, which is "\r\n", "\n" or "\r".
+func (d *Decoder) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case '\n':
+			return line, nil
+		case '\r':
+			if next, err := d.r.Peek(1); err == nil && next[0] == '\n' {
+				_, _ = d.r.ReadByte()
+			}
+			return line, nil
+		default:
+			line = append(line, b)
+		}
+	}
+}
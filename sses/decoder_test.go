@@ -0,0 +1,65 @@
+package sses
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Decoder(t *testing.T) {
+	stream := ": heartbeat\n\n" +
+		"id:e1\nevent:price\ndata:line1\ndata:line2\n\n" +
+		"retry:5000\ndata: leading space trimmed once\r\n\r\n" +
+		"event:ignored\n\n" + // no data, discarded
+		"id\rdata\r\r" + // empty id resets, empty data
+		"data:incomplete"
+	dec := NewDecoder(strings.NewReader(stream))
+
+	e, err := dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, &Event{Id: "e1", Event: "price", Data: "line1\nline2"}, e)
+
+	e, err = dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, &Event{Id: "e1", Event: DefaultEventType, Retry: 5000, Data: "leading space trimmed once"}, e)
+	require.Equal(t, time.Second*5, dec.Retry())
+
+	e, err = dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, &Event{Id: "", Event: DefaultEventType, Data: ""}, e)
+	require.Equal(t, "", dec.LastEventId())
+
+	_, err = dec.Decode()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func Test_Decoder_RoundTrip(t *testing.T) {
+	events := []Event{
+		{Id: "e1", Event: "message", Data: "hello"},
+		{Id: "e2", Event: "message", Data: "multi\nline\ndata"},
+		{Id: "e3", Event: "json", Retry: 3000, Data: map[string]any{"name": "world"}},
+		{Id: "e4", Event: "bytes", Data: []byte("raw")},
+	}
+	buf := &bytes.Buffer{}
+	for _, e := range events {
+		require.NoError(t, Encode(buf, e))
+		buf.WriteString(heartbeat)
+	}
+	dec := NewDecoder(buf)
+	for _, want := range events {
+		got, err := dec.Decode()
+		require.NoError(t, err)
+		data, err := MarshalData(want.Data)
+		require.NoError(t, err)
+		require.Equal(t, want.Id, got.Id)
+		require.Equal(t, want.Event, got.Event)
+		require.Equal(t, want.Retry, got.Retry)
+		require.Equal(t, string(data), got.Data)
+	}
+	_, err := dec.Decode()
+	require.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,102 @@
+package sses
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Encoder encodes the event data into the `data:` field.
+// the hub encodes the data once when publishing, the encoded data is saved to the store and
+// fan-out through the broker as is, see WithEncoder and WithEventEncoder.
+type Encoder interface {
+	Encode(data any) ([]byte, error)
+}
+
+// EncoderFunc the function adapter of Encoder.
+type EncoderFunc func(data any) ([]byte, error)
+
+// Encode implements Encoder.
+func (f EncoderFunc) Encode(data any) ([]byte, error) { return f(data) }
+
+var (
+	_ Encoder = EncoderFunc(nil)
+	_ Encoder = (*ProtoJSONEncoder)(nil)
+	_ Encoder = (*Base64Encoder)(nil)
+)
+
+// DefaultEncoder the default encoder, the []byte as is, the struct, slice and map as JSON,
+// others as fmt.Sprint, the same as MarshalData.
+var DefaultEncoder Encoder = EncoderFunc(MarshalData)
+
+// ProtoJSONEncoder encodes the proto.Message with protojson, others with DefaultEncoder.
+type ProtoJSONEncoder struct {
+	protojson.MarshalOptions
+}
+
+// NewProtoJSONEncoder new the protojson encoder with the default marshal options.
+func NewProtoJSONEncoder() *ProtoJSONEncoder {
+	return &ProtoJSONEncoder{}
+}
+
+// Encode implements Encoder.
+func (e *ProtoJSONEncoder) Encode(data any) ([]byte, error) {
+	if m, ok := data.(proto.Message); ok {
+		return e.Marshal(m)
+	}
+	return DefaultEncoder.Encode(data)
+}
+
+// Base64Encoder marshals the data into the binary, such as msgpack, then encodes it with base64,
+// the client decodes the base64 then unmarshals it.
+type Base64Encoder struct {
+	marshal  func(any) ([]byte, error)
+	encoding *base64.Encoding
+}
+
+// NewBase64Encoder new the base64 encoder with the binary marshal function, such as msgpack.Marshal,
+// the []byte data is encoded as is without marshal, the nil marshal function only accepts the []byte data.
+// the encoding is the base64.StdEncoding.
+func NewBase64Encoder(marshal func(any) ([]byte, error)) *Base64Encoder {
+	return &Base64Encoder{
+		marshal:  marshal,
+		encoding: base64.StdEncoding,
+	}
+}
+
+// SetEncoding sets the base64 encoding, such as base64.RawURLEncoding.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (e *Base64Encoder) SetEncoding(encoding *base64.Encoding) *Base64Encoder {
+	if encoding != nil {
+		e.encoding = encoding
+	}
+	return e
+}
+
+// Encode implements Encoder.
+func (e *Base64Encoder) Encode(data any) ([]byte, error) {
+	b, ok := data.([]byte)
+	if !ok {
+		if e.marshal == nil {
+			return nil, &UnsupportedDataError{Data: data}
+		}
+		var err error
+		if b, err = e.marshal(data); err != nil {
+			return nil, err
+		}
+	}
+	dst := make([]byte, e.encoding.EncodedLen(len(b)))
+	e.encoding.Encode(dst, b)
+	return dst, nil
+}
+
+// UnsupportedDataError the data type is not supported by the encoder.
+type UnsupportedDataError struct {
+	Data any
+}
+
+func (e *UnsupportedDataError) Error() string {
+	return fmt.Sprintf("sses: unsupported data type %T", e.Data)
+}
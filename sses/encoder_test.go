@@ -0,0 +1,173 @@
+package sses
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func Test_Encode_CommentsAndFields(t *testing.T) {
+	events := []Event{
+		{Comments: []string{"only comment", "multi\nline"}},
+		{
+			Id:       "e1",
+			Event:    "message",
+			Data:     "hello",
+			Comments: []string{"with comment"},
+			Fields:   map[string]string{"z-trace": "t1", "a-seq": "1\n2", "id": "skipped", "in:valid": "skipped"},
+		},
+	}
+	buf := &bytes.Buffer{}
+	for _, e := range events {
+		require.NoError(t, Encode(buf, e))
+	}
+	require.Equal(t,
+		": only comment\n: multi\n: line\n\n"+
+			": with comment\nid:e1\nevent:message\na-seq:1\\n2\nz-trace:t1\ndata:hello\n\n",
+		buf.String(),
+	)
+
+	dec := NewDecoder(buf).SetComments(true)
+	e, err := dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, &Event{Comments: []string{"only comment", "multi", "line"}}, e)
+	e, err = dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, &Event{
+		Id:       "e1",
+		Event:    "message",
+		Data:     "hello",
+		Comments: []string{"with comment"},
+		Fields:   map[string]string{"z-trace": "t1", "a-seq": "1\\n2"},
+	}, e)
+	_, err = dec.Decode()
+	require.ErrorIs(t, err, io.EOF)
+
+	// the comments are skipped by default.
+	dec = NewDecoder(strings.NewReader(": c\n\n: c\ndata:hello\n\n"))
+	e, err = dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, &Event{Event: DefaultEventType, Data: "hello"}, e)
+}
+
+func Test_Encoder(t *testing.T) {
+	msg, err := structpb.NewStruct(map[string]any{"name": "world", "count": 2})
+	require.NoError(t, err)
+
+	t.Run("protojson", func(t *testing.T) {
+		enc := NewProtoJSONEncoder()
+		b, err := enc.Encode(msg)
+		require.NoError(t, err)
+		got := &structpb.Struct{}
+		require.NoError(t, protojson.Unmarshal(b, got))
+		require.True(t, proto.Equal(msg, got))
+		// not proto.Message, the default encoder.
+		b, err = enc.Encode(map[string]int{"a": 1})
+		require.NoError(t, err)
+		require.Equal(t, `{"a":1}`, string(b))
+	})
+
+	t.Run("base64", func(t *testing.T) {
+		b, err := NewBase64Encoder(nil).Encode([]byte{0xff, 0x00})
+		require.NoError(t, err)
+		require.Equal(t, "/wA=", string(b))
+		b, err = NewBase64Encoder(nil).SetEncoding(base64.RawURLEncoding).Encode([]byte{0xff, 0x00})
+		require.NoError(t, err)
+		require.Equal(t, "_wA", string(b))
+		_, err = NewBase64Encoder(nil).Encode("text")
+		var unsupported *UnsupportedDataError
+		require.ErrorAs(t, err, &unsupported)
+	})
+}
+
+type testGobData struct {
+	Name  string
+	Count int
+}
+
+func gobMarshal(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := gob.NewEncoder(buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func Test_Hub_Encoder_RoundTrip(t *testing.T) {
+	msg, err := structpb.NewStruct(map[string]any{"name": "world", "multi": "line1\nline2"})
+	require.NoError(t, err)
+	store := newTestMemoryStore()
+	h := NewHub(
+		WithStore(store),
+		WithEncoder(NewProtoJSONEncoder()),
+		// binary marshal (such as msgpack) then base64, gob is used in the test.
+		WithEventEncoder("binary", NewBase64Encoder(gobMarshal)),
+		WithEventEncoder("upper", EncoderFunc(func(data any) ([]byte, error) {
+			s, ok := data.(string)
+			if !ok {
+				return nil, errors.New("expect string")
+			}
+			return []byte(strings.ToUpper(s)), nil
+		})),
+	)
+	defer h.Close() // nolint: errcheck
+	srv := httptest.NewServer(h.Serve(WithServeExtractUserId(func(*http.Request) string { return "u1" })))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?channel=ch1", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint: errcheck
+	dec := NewDecoder(resp.Body).SetComments(true)
+	e, err := dec.Decode() // the heartbeat
+	require.NoError(t, err)
+	require.Equal(t, []string{"heartbeat"}, e.Comments)
+
+	require.NoError(t, h.Broadcast(ctx, "ch1",
+		&Event{Id: "e1", Event: "proto", Data: msg, Comments: []string{"proto"}, Fields: map[string]string{"schema": "Struct"}},
+		&Event{Id: "e2", Event: "binary", Data: &testGobData{Name: "world", Count: 2}},
+		&Event{Id: "e3", Event: "upper", Data: "hello"},
+	))
+	require.ErrorContains(t, h.Broadcast(ctx, "ch1", &Event{Event: "upper", Data: 1}), "expect string")
+
+	// proto json
+	e, err = dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, []string{"proto"}, e.Comments)
+	require.Equal(t, map[string]string{"schema": "Struct"}, e.Fields)
+	gotMsg := &structpb.Struct{}
+	require.NoError(t, protojson.Unmarshal([]byte(e.Data.(string)), gotMsg))
+	require.True(t, proto.Equal(msg, gotMsg))
+	// base64 binary
+	e, err = dec.Decode()
+	require.NoError(t, err)
+	b, err := base64.StdEncoding.DecodeString(e.Data.(string))
+	require.NoError(t, err)
+	var gotData testGobData
+	require.NoError(t, gob.NewDecoder(bytes.NewReader(b)).Decode(&gotData))
+	require.Equal(t, testGobData{Name: "world", Count: 2}, gotData)
+	// custom
+	e, err = dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, "HELLO", e.Data)
+
+	// the encoded data is saved to the store.
+	events, err := store.ListByLastId(ctx, "ch1", "upper", "", 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, []byte("HELLO"), events[0].Data)
+}
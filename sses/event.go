@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -29,6 +31,16 @@ type Event struct {
 	Id    string `json:"id"`
 	Retry uint   `json:"retry"`
 	Data  any    `json:"data"`
+	// Meta the event metadata, used by the session filter, not rendered.
+	Meta map[string]string `json:"meta,omitempty"`
+	// Channel the event published to, it is set by the hub before pushing to the sessions, not rendered.
+	Channel string `json:"-"`
+	// Comments the comment lines rendered as `: comment` before the fields, ignored by the EventSource.
+	// the event with only comments (no event type and nil data) renders the comment lines only.
+	Comments []string `json:"comments,omitempty"`
+	// Fields the extra fields rendered as `name:value` after the standard fields, ignored by the EventSource,
+	// but available to the custom parsers, the standard field names and the invalid names are skipped.
+	Fields map[string]string `json:"fields,omitempty"`
 }
 
 func (r Event) Render(w http.ResponseWriter) error {
@@ -47,12 +59,56 @@ func (r Event) WriteContentType(w http.ResponseWriter) {
 
 func Encode(writer io.Writer, event Event) error {
 	w := checkWriter(writer)
+	writeComments(w, event.Comments)
+	if event.isCommentOnly() {
+		_, _ = w.WriteString("\n")
+		return nil
+	}
 	writeId(w, event.Id)
 	writeEvent(w, event.Event)
 	writeRetry(w, event.Retry)
+	writeFields(w, event.Fields)
 	return writeData(w, event.Data)
 }
 
+func (r *Event) isCommentOnly() bool {
+	return len(r.Comments) > 0 && r.Id == "" && r.Event == "" && r.Retry == 0 && len(r.Fields) == 0 && r.Data == nil
+}
+
+// writeComments write the comment lines, the multi-line comment is split into lines.
+func writeComments(w stringWriter, comments []string) {
+	for _, comment := range comments {
+		for line := range strings.Lines(strings.ReplaceAll(comment, "\r", "\n")) {
+			_, _ = w.WriteString(": ")
+			_, _ = w.WriteString(strings.TrimSuffix(line, "\n"))
+			_, _ = w.WriteString("\n")
+		}
+	}
+}
+
+// writeFields write the extra fields in the name order.
+func writeFields(w stringWriter, fields map[string]string) {
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if !isValidFieldName(name) {
+			continue
+		}
+		_, _ = w.WriteString(name)
+		_, _ = w.WriteString(":")
+		_, _ = fieldReplacer.WriteString(w, fields[name])
+		_, _ = w.WriteString("\n")
+	}
+}
+
+// isValidFieldName reports whether the name can be used as the extra field name.
+func isValidFieldName(name string) bool {
+	switch name {
+	case "", "id", "event", "retry", "data":
+		return false
+	default:
+		return !strings.ContainsAny(name, ":\r\n")
+	}
+}
+
 func writeId(w stringWriter, id string) {
 	if len(id) > 0 {
 		_, _ = w.WriteString("id:")
@@ -101,6 +157,19 @@ func writeData(w stringWriter, data any) error {
 	return nil
 }
 
+// MarshalData marshal the data as the same as it rendered in the data field.
+func MarshalData(data any) ([]byte, error) {
+	if bData, ok := data.([]byte); ok {
+		return bData, nil
+	}
+	switch kindOfData(data) { //nolint:exhaustive
+	case reflect.Struct, reflect.Slice, reflect.Map:
+		return json.Marshal(data)
+	default:
+		return []byte(fmt.Sprint(data)), nil
+	}
+}
+
 func kindOfData(data any) reflect.Kind {
 	value := reflect.ValueOf(data)
 	valueType := value.Kind()
@@ -0,0 +1,98 @@
+package sses
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// EventFilter reports whether the event is accepted by the session.
+type EventFilter func(*Event) bool
+
+// AllowEventTypes returns the filter which accepts the events of the types.
+func AllowEventTypes(types ...string) EventFilter {
+	return func(e *Event) bool {
+		return slices.Contains(types, e.Event)
+	}
+}
+
+// And returns the filter which accepts the events accepted by all the filters, the nil filter is ignored.
+func And(filters ...EventFilter) EventFilter {
+	filters = slices.DeleteFunc(slices.Clone(filters), func(f EventFilter) bool { return f == nil })
+	switch len(filters) {
+	case 0:
+		return nil
+	case 1:
+		return filters[0]
+	}
+	return func(e *Event) bool {
+		for _, f := range filters {
+			if !f(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// ParseEventFilter parse the filter expression on the event metadata.
+// the expression is the comma separated clauses, all the clauses must be matched:
+//   - `key=v1|v2`: the value is one of v1, v2.
+//   - `key!=v1|v2`: the value is none of v1, v2.
+//
+// the key `event`, `id`, `channel` refer to the event fields, the others refer to Event.Meta.
+// such as: `event=price|status,region=eu,level!=debug`
+func ParseEventFilter(expr string) (EventFilter, error) {
+	type clause struct {
+		key    string
+		values []string
+		not    bool
+	}
+	clauses := make([]clause, 0, strings.Count(expr, ",")+1)
+	for s := range strings.SplitSeq(expr, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		c := clause{}
+		key, value, ok := strings.Cut(s, "!=")
+		if ok {
+			c.not = true
+		} else if key, value, ok = strings.Cut(s, "="); !ok {
+			return nil, fmt.Errorf("sses: invalid filter clause %q", s)
+		}
+		c.key = strings.TrimSpace(key)
+		if c.key == "" {
+			return nil, fmt.Errorf("sses: invalid filter clause %q, key is empty", s)
+		}
+		for v := range strings.SplitSeq(value, "|") {
+			c.values = append(c.values, strings.TrimSpace(v))
+		}
+		clauses = append(clauses, c)
+	}
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+	return func(e *Event) bool {
+		for _, c := range clauses {
+			if slices.Contains(c.values, e.field(c.key)) == c.not {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// field returns the value of the event field or metadata.
+func (r Event) field(key string) string {
+	switch key {
+	case "event":
+		return r.Event
+	case "id":
+		return r.Id
+	case "channel":
+		return r.Channel
+	default:
+		return r.Meta[key]
+	}
+}
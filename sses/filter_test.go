@@ -0,0 +1,75 @@
+package sses
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseEventFilter(t *testing.T) {
+	e := &Event{Id: "e1", Event: "price", Channel: "ch1", Meta: map[string]string{"region": "eu", "level": "info"}}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"event=price", true},
+		{"event=status", false},
+		{"event=price|status", true},
+		{"event!=price|status", false},
+		{"event=price, region=eu", true},
+		{"event=price,region=us", false},
+		{"region=us|eu,level!=debug", true},
+		{"channel=ch1,id=e1", true},
+		{"missing=", true},
+		{"missing!=", false},
+	}
+	for _, tt := range tests {
+		f, err := ParseEventFilter(tt.expr)
+		require.NoError(t, err, tt.expr)
+		require.Equal(t, tt.want, f(e), tt.expr)
+	}
+
+	f, err := ParseEventFilter(" , ")
+	require.NoError(t, err)
+	require.Nil(t, f)
+	_, err = ParseEventFilter("event")
+	require.Error(t, err)
+	_, err = ParseEventFilter("=price")
+	require.Error(t, err)
+}
+
+func Test_EventFilter_Publish(t *testing.T) {
+	h := NewHub()
+	defer h.Close() // nolint: errcheck
+
+	allowed, err := ParseEventFilter("region=eu")
+	require.NoError(t, err)
+	s1 := &Session{UserId: "u1", SessionId: NewSessionId(), Channel: "ch1", Filter: AllowEventTypes("price"), Message: make(chan *Event, 10)}
+	s2 := &Session{UserId: "u1", SessionId: NewSessionId(), Channel: "ch1", Filter: And(AllowEventTypes("price", "status"), allowed), Message: make(chan *Event, 10)}
+	s3 := &Session{UserId: "u1", SessionId: NewSessionId(), Channel: "ch1", Message: make(chan *Event, 10)}
+	h.sessions.Add(s1)
+	h.sessions.Add(s2)
+	h.sessions.Add(s3)
+
+	events := []*Event{
+		{Id: "e1", Event: "price", Data: 1, Meta: map[string]string{"region": "eu"}},
+		{Id: "e2", Event: "status", Data: 2, Meta: map[string]string{"region": "eu"}},
+		{Id: "e3", Event: "price", Data: 3, Meta: map[string]string{"region": "us"}},
+		{Id: "e4", Event: "other", Data: 4},
+	}
+	require.NoError(t, h.Publish(context.Background(), "ch1", "u1", events...))
+
+	drain := func(ses *Session) []string {
+		ids := make([]string, 0)
+		for len(ses.Message) > 0 {
+			ids = append(ids, (<-ses.Message).Id)
+		}
+		return ids
+	}
+	require.Equal(t, []string{"e1", "e3"}, drain(s1))
+	require.Equal(t, []string{"e1", "e2"}, drain(s2))
+	require.Equal(t, []string{"e1", "e2", "e3", "e4"}, drain(s3))
+	require.Equal(t, int64(4), h.Stats().Filtered.Load())
+	require.Equal(t, int64(8), h.Stats().ReqSuccess.Load())
+}
@@ -1,12 +1,16 @@
 package sses
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/thinkgos/proc/lookup"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const CtxUserIdKey = "http/sse/user-id"
@@ -21,9 +25,12 @@ type serveOptions struct {
 	extractChannel     *lookup.Lookup
 	extractEventType   *lookup.Lookup
 	extractLastEventId *lookup.Lookup
+	extractEventTypes  *lookup.Lookup
+	extractFilter      *lookup.Lookup
 	errFallback        func(http.ResponseWriter, *http.Request, error)
-	onRegister         func(*Session) // 注册时
-	onDeregister       func(*Session) // 注销时
+	checkOrigin        func(*http.Request) bool // WebSocket 握手时检查 Origin
+	onRegister         func(*Session)           // 注册时
+	onDeregister       func(*Session)           // 注销时
 }
 
 func defaultServeOptions() *serveOptions {
@@ -33,10 +40,26 @@ func defaultServeOptions() *serveOptions {
 		extractChannel:     lookup.NewLookup("query:channel"),
 		extractEventType:   lookup.NewLookup("query:eventType,header:Event-Type"),
 		extractLastEventId: lookup.NewLookup("query:lastEventId,header:Last-Event-ID"),
+		extractEventTypes:  lookup.NewLookup("query:eventTypes"),
+		extractFilter:      lookup.NewLookup("query:filter"),
 		errFallback: func(w http.ResponseWriter, r *http.Request, err error) {
-			w.WriteHeader(http.StatusBadRequest)
+			switch {
+			case errors.Is(err, ErrHubClosed):
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case errors.Is(err, ErrTooManySessions):
+				w.WriteHeader(http.StatusTooManyRequests)
+			case errors.Is(err, ErrOriginNotAllowed):
+				w.WriteHeader(http.StatusForbidden)
+			case errors.Is(err, ErrMethodNotAllowed):
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			case errors.As(err, new(*http.MaxBytesError)):
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
 			_, _ = w.Write([]byte(err.Error()))
 		},
+		checkOrigin:  SameOrigin,
 		onRegister:   func(s *Session) {},
 		onDeregister: func(s *Session) {},
 	}
@@ -91,8 +114,25 @@ func WithServeExtractLastEventId(l *lookup.Lookup) ServeOption {
 	}
 }
 
+// WithServeExtractEventTypes sets the function to extract the allowed event types (comma separated) from the request,
+// only the events of the types are pushed to the session.
+func WithServeExtractEventTypes(l *lookup.Lookup) ServeOption {
+	return func(o *serveOptions) {
+		o.extractEventTypes = l
+	}
+}
+
+// WithServeExtractFilter sets the function to extract the filter expression from the request,
+// only the events accepted by the filter are pushed to the session, see ParseEventFilter.
+func WithServeExtractFilter(l *lookup.Lookup) ServeOption {
+	return func(o *serveOptions) {
+		o.extractFilter = l
+	}
+}
+
 // WithErrorFallback set the fallback handler when request are error happened.
-// default: the 400 bad request error to the client
+// default: the 400 bad request error to the client, 503 service unavailable if the hub closed(ErrHubClosed),
+// 429 too many requests if the session limit exceeded(ErrTooManySessions).
 func WithErrorFallback(fn func(http.ResponseWriter, *http.Request, error)) ServeOption {
 	return func(o *serveOptions) {
 		if fn != nil {
@@ -101,6 +141,16 @@ func WithErrorFallback(fn func(http.ResponseWriter, *http.Request, error)) Serve
 	}
 }
 
+// WithServeCheckOrigin sets the function to check the Origin of the WebSocket handshake,
+// default SameOrigin, the rejected handshake is responded by the errFallback with ErrOriginNotAllowed.
+func WithServeCheckOrigin(fn func(*http.Request) bool) ServeOption {
+	return func(o *serveOptions) {
+		if fn != nil {
+			o.checkOrigin = fn
+		}
+	}
+}
+
 // WithServeOnRegister sets the function to be called when a user session is registered.
 func WithServeOnRegister(fn func(*Session)) ServeOption {
 	return func(o *serveOptions) {
@@ -129,41 +179,31 @@ func (h *Hub) Serve(opts ...ServeOption) http.Handler {
 			w.Header().Set(k, v)
 		}
 
+		if h.closed.Load() {
+			opt.errFallback(w, r, ErrHubClosed)
+			return
+		}
+		h.serving.Add()
+		defer h.serving.Done()
+
 		_, ok := w.(http.Flusher)
 		if !ok {
 			opt.errFallback(w, r, errors.New("streaming unsupported"))
 			return
 		}
-		//* 获取用户id
-		userId := opt.extractUserId(r)
-		if userId == "" {
-			opt.errFallback(w, r, errors.New("userId is empty, not allow connection"))
-			return
-		}
-		//* 获取订阅的channel
-		channel, err := opt.extractChannel.ExtractValue(r)
+		sr, err := h.extractSession(r, opt)
 		if err != nil {
-			opt.errFallback(w, r, errors.New("channel is empty, not allow connection"))
+			opt.errFallback(w, r, err)
 			return
 		}
-		//* 获取会话id, 如果没有, 则创建一个
-		sessionId := opt.extractSessionId.ExtractValueOr(r, "")
-		if sessionId == "" {
-			sessionId = NewSessionId()
-		}
-		//* 获取请求事件类型和最后的事件id
-		eventType := opt.extractEventType.ExtractValueOr(r, DefaultEventType)
-		lastEventId := opt.extractLastEventId.ExtractValueOr(r, "")
-		//* 创建用户会话
-		session := &Session{
-			UserId:    userId,
-			SessionId: sessionId,
-			Channel:   channel,
-			Message:   make(chan *Event, h.bufferSize),
-		}
+		session := sr.session
+		sw := newSessionWriter(session, func(e *Event) error { return e.Render(w) })
 
-		//* 注册用户会话
-		h.sessions.Add(session)
+		//* 注册用户会话, 超出会话数量限制时拒绝
+		if err = h.sessions.TryAdd(session); err != nil {
+			opt.errFallback(w, r, err)
+			return
+		}
 		// h.logger.OnInfoContext(c.Request.Context()).
 		// 	String("channel", session.Channel).
 		// 	String("userId", session.UserId).
@@ -182,8 +222,7 @@ func (h *Hub) Serve(opts ...ServeOption) http.Handler {
 		}()
 
 		//* 重发旧的消息
-		if h.store != nil && lastEventId != "" {
-			h.resendEvents(ctx, w, channel, eventType, lastEventId)
+		if h.resend(ctx, sw, sr) {
 			w.(http.Flusher).Flush()
 		}
 		// 会话连接成功, 发送一次心跳, 客户端连接成功
@@ -200,14 +239,12 @@ func (h *Hub) Serve(opts ...ServeOption) http.Handler {
 				if !ok { // 关闭
 					return false
 				}
-				err := e.Render(w)
-				if err != nil {
-					h.stats.SendSuccess.Add(1)
-					return false
-				} else {
+				if err := sw.Render(e); err != nil {
 					h.stats.SendFailure.Add(1)
-					return true
+					return false
 				}
+				h.stats.SendSuccess.Add(1)
+				return true
 			case <-t.C:
 				// 发送心跳
 				_, err := w.Write([]byte(heartbeat))
@@ -226,3 +263,148 @@ func (h *Hub) Serve(opts ...ServeOption) http.Handler {
 		}
 	})
 }
+
+// sessionRequest the session and the resend parameters extracted from the request.
+type sessionRequest struct {
+	session     *Session
+	eventType   string // 重发的事件类型, 为空时重发所有类型
+	lastEventId string // 最后的事件id, 多频道会话时为游标
+}
+
+// extractSession extract the session from the request.
+func (h *Hub) extractSession(r *http.Request, opt *serveOptions) (*sessionRequest, error) {
+	//* 获取用户id
+	userId := opt.extractUserId(r)
+	if userId == "" {
+		return nil, errors.New("userId is empty, not allow connection")
+	}
+	//* 获取订阅的channel, 多个channel以逗号分隔, 支持通配符
+	channel, err := opt.extractChannel.ExtractValue(r)
+	channels := splitChannels(channel)
+	if err != nil || len(channels) == 0 {
+		return nil, errors.New("channel is empty, not allow connection")
+	}
+	if len(channels) > maxSessionChannels {
+		return nil, errors.New("too many channels, not allow connection")
+	}
+	//* 获取会话id, 如果没有, 则创建一个
+	sessionId := opt.extractSessionId.ExtractValueOr(r, "")
+	if sessionId == "" {
+		sessionId = NewSessionId()
+	}
+	//* 获取事件过滤器
+	var filter EventFilter
+	if eventTypes := splitChannels(opt.extractEventTypes.ExtractValueOr(r, "")); len(eventTypes) > 0 {
+		filter = AllowEventTypes(eventTypes...)
+	}
+	if expr := opt.extractFilter.ExtractValueOr(r, ""); expr != "" {
+		f, err := ParseEventFilter(expr)
+		if err != nil {
+			return nil, err
+		}
+		filter = And(filter, f)
+	}
+	//* 获取请求事件类型和最后的事件id, 设置了过滤器时, 默认重发所有类型的事件并过滤
+	eventType := opt.extractEventType.ExtractValueOr(r, "")
+	if eventType == "" && filter == nil {
+		eventType = DefaultEventType
+	}
+	lastEventId := opt.extractLastEventId.ExtractValueOr(r, "")
+	//* 创建用户会话
+	session := &Session{
+		UserId:    userId,
+		SessionId: sessionId,
+		Channel:   channels[0],
+		Channels:  channels,
+		Filter:    filter,
+		Message:   make(chan *Event, h.bufferSize),
+	}
+	return &sessionRequest{
+		session:     session,
+		eventType:   eventType,
+		lastEventId: lastEventId,
+	}, nil
+}
+
+// resend the persisted events after the last event id, then redeliver the unacked events,
+// it reports whether resent.
+func (h *Hub) resend(ctx context.Context, sw *sessionWriter, sr *sessionRequest) bool {
+	ctx, span := h.tracer.Start(ctx, "sses.replay",
+		trace.WithAttributes(
+			AttrChannel.StringSlice(sr.session.Subscriptions()),
+			AttrLastEventId.String(sr.lastEventId),
+		),
+	)
+	sw.replayed = make(map[string]struct{})
+	defer func() {
+		span.SetAttributes(AttrEventCount.Int(len(sw.replayed)))
+		span.End()
+		sw.replayed = nil
+	}()
+	resent := false
+	if h.store != nil && sr.lastEventId != "" {
+		resent = true
+		if sw.cursor == nil {
+			h.resendEvents(ctx, sw, sr.session.Channel, sr.eventType, sr.lastEventId)
+		} else {
+			// 多频道会话, 按游标重发所有订阅的频道, 游标中没有的频道不重发(与单频道会话没有最后事件id时一致),
+			// 游标中为空的频道(注册时尚无事件)从最旧的事件开始重发, 通配符订阅重发游标中匹配的频道
+			sw.cursor = parseSessionCursor(sr.session.Subscriptions(), sr.lastEventId)
+			for _, sub := range sr.session.Subscriptions() {
+				for _, e := range sw.cursor.lastIds(sub) {
+					h.resendEvents(ctx, sw, e.channel, sr.eventType, e.id)
+				}
+			}
+		}
+	}
+	//* 游标中没有的频道以当前最后的事件id为起点
+	h.seedCursor(ctx, sw)
+	//* 重发未确认的事件
+	if h.redeliver(ctx, sw, sr) {
+		resent = true
+	}
+	return resent
+}
+
+// sessionWriter render the events of the session.
+// for multi-channel or wildcard sessions, the `id:` is rendered as the cursor of all subscriptions,
+// see sessionCursor, so that the client reconnects with it to resend all subscriptions.
+type sessionWriter struct {
+	session  *Session
+	cursor   *sessionCursor      // 多频道会话的游标, nil for single channel session
+	write    func(*Event) error  // write the event to the transport
+	replayed map[string]struct{} // 重连时已重发的事件id, 非重连时为nil
+}
+
+func newSessionWriter(ses *Session, write func(*Event) error) *sessionWriter {
+	sw := &sessionWriter{session: ses, write: write}
+	if subs := ses.Subscriptions(); len(subs) > 1 || IsWildcardChannel(subs[0]) {
+		sw.cursor = newSessionCursor(subs)
+	}
+	return sw
+}
+
+// Render the event.
+func (sw *sessionWriter) Render(e *Event) error {
+	if sw.replayed != nil && e.Id != "" {
+		sw.replayed[e.Id] = struct{}{}
+	}
+	if sw.cursor == nil || e.Id == "" {
+		return sw.write(e)
+	}
+	sw.cursor.update(e.Channel, e.Id)
+	rendered := *e
+	rendered.Id = sw.cursor.Encode()
+	return sw.write(&rendered)
+}
+
+// splitChannels split the comma separated channels, also used for the event types.
+func splitChannels(s string) []string {
+	channels := make([]string, 0, strings.Count(s, ",")+1)
+	for ch := range strings.SplitSeq(s, ",") {
+		if ch = strings.TrimSpace(ch); ch != "" && !slices.Contains(channels, ch) {
+			channels = append(channels, ch)
+		}
+	}
+	return channels
+}
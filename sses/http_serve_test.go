@@ -0,0 +1,231 @@
+package sses
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Serve_MultiChannel(t *testing.T) {
+	h := NewHub(WithStore(NewMemoryStore(100)))
+	defer h.Close() // nolint: errcheck
+	srv := httptest.NewServer(h.Serve(WithServeExtractUserId(func(*http.Request) string { return "u1" })))
+	defer srv.Close()
+
+	connect := func(lastEventId string) (*bufio.Reader, func()) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?channel="+url.QueryEscape("ch1, orders/+/status, ch2"), nil)
+		require.NoError(t, err)
+		if lastEventId != "" {
+			req.Header.Set("Last-Event-ID", lastEventId)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return bufio.NewReader(resp.Body), func() {
+			cancel()
+			_ = resp.Body.Close()
+		}
+	}
+	read := func(r *bufio.Reader, want string) {
+		buf := make([]byte, len(want))
+		_, err := io.ReadFull(r, buf)
+		require.NoError(t, err)
+		require.Equal(t, want, string(buf))
+	}
+
+	// too many channels
+	channels := make([]string, 0, maxSessionChannels+1)
+	for i := range maxSessionChannels + 1 {
+		channels = append(channels, "ch"+strconv.Itoa(i))
+	}
+	resp, err := http.Get(srv.URL + "?channel=" + url.QueryEscape(strings.Join(channels, ",")))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	r, disconnect := connect("")
+	read(r, heartbeat)
+	require.Equal(t, 1, h.SessionTotal())
+
+	ctx := context.Background()
+	require.NoError(t, h.Broadcast(ctx, "ch1", &Event{Id: "e1", Event: "message", Data: "1"}))
+	require.NoError(t, h.Broadcast(ctx, "orders/1/other", &Event{Id: "e2", Event: "message", Data: "2"}))
+	require.NoError(t, h.Broadcast(ctx, "orders/1/status", &Event{Id: "e3", Event: "message", Data: "3"}))
+	// the id is the cursor of all subscriptions, the wildcard subscription keeps the matched channels.
+	// ch2 is seeded empty since it had no events when registered.
+	read(r, "id:ch1=e1&ch2=\nevent:message\ndata:1\n\n")
+	read(r, "id:ch1=e1&ch2=&orders%2F%2B%2Fstatus=orders%252F1%252Fstatus%3De3\nevent:message\ndata:3\n\n")
+	disconnect()
+
+	// published when offline
+	require.Eventually(t, func() bool { return h.SessionTotal() == 0 }, time.Second, time.Millisecond*10)
+	require.NoError(t, h.Broadcast(ctx, "ch1", &Event{Id: "e4", Event: "message", Data: "4"}))
+	require.NoError(t, h.Broadcast(ctx, "orders/1/status", &Event{Id: "e5", Event: "message", Data: "5"}))
+	require.NoError(t, h.Broadcast(ctx, "orders/2/status", &Event{Id: "e6", Event: "message", Data: "6"}))
+	require.NoError(t, h.Broadcast(ctx, "ch2", &Event{Id: "e7", Event: "message", Data: "7"}))
+
+	// resend all the subscriptions, ch2 empty in the cursor is resent from the oldest,
+	// the wildcard subscription resends the matched channels in the cursor.
+	r, disconnect = connect("ch1=e1&ch2=&orders%2F%2B%2Fstatus=orders%252F1%252Fstatus%3De3")
+	defer disconnect()
+	read(r, "id:ch1=e4&ch2=&orders%2F%2B%2Fstatus=orders%252F1%252Fstatus%3De3\nevent:message\ndata:4\n\n")
+	read(r, "id:ch1=e4&ch2=&orders%2F%2B%2Fstatus=orders%252F1%252Fstatus%3De5\nevent:message\ndata:5\n\n")
+	read(r, "id:ch1=e4&ch2=e7&orders%2F%2B%2Fstatus=orders%252F1%252Fstatus%3De5\nevent:message\ndata:7\n\n")
+	read(r, heartbeat)
+}
+
+func Test_Serve_MultiChannel_History(t *testing.T) {
+	h := NewHub(WithStore(NewMemoryStore(100)))
+	defer h.Close() // nolint: errcheck
+	srv := httptest.NewServer(h.Serve(WithServeExtractUserId(func(*http.Request) string { return "u1" })))
+	defer srv.Close()
+
+	connect := func(lastEventId string) (*bufio.Reader, func()) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?channel=ch1,ch2", nil)
+		require.NoError(t, err)
+		if lastEventId != "" {
+			req.Header.Set("Last-Event-ID", lastEventId)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return bufio.NewReader(resp.Body), func() {
+			cancel()
+			_ = resp.Body.Close()
+			require.Eventually(t, func() bool { return h.SessionTotal() == 0 }, time.Second, time.Millisecond*10)
+		}
+	}
+	read := func(r *bufio.Reader, want string) {
+		buf := make([]byte, len(want))
+		_, err := io.ReadFull(r, buf)
+		require.NoError(t, err)
+		require.Equal(t, want, string(buf))
+	}
+
+	// the history of ch2 published before the first connect.
+	ctx := context.Background()
+	for _, id := range []string{"h1", "h2", "h3"} {
+		require.NoError(t, h.Broadcast(ctx, "ch2", &Event{Id: id, Event: "message", Data: id}))
+	}
+	r, disconnect := connect("")
+	read(r, heartbeat)
+	require.NoError(t, h.Broadcast(ctx, "ch1", &Event{Id: "live1", Event: "message", Data: "1"}))
+	// ch2 is seeded with its last event id.
+	read(r, "id:ch1=live1&ch2=h3\nevent:message\ndata:1\n\n")
+	disconnect()
+
+	// the history is never resent, with the cursor or the channel missing from the cursor.
+	for _, cursor := range []string{"ch1=live1&ch2=h3", "ch1=live1"} {
+		r, disconnect = connect(cursor)
+		read(r, heartbeat)
+		require.NoError(t, h.Broadcast(ctx, "ch2", &Event{Id: "live-" + cursor, Event: "message", Data: "2"}))
+		read(r, "id:"+url.Values{"ch1": {"live1"}, "ch2": {"live-" + cursor}}.Encode()+"\nevent:message\ndata:2\n\n")
+		disconnect()
+	}
+}
+
+func Test_Serve_EventFilter(t *testing.T) {
+	store := NewMemoryStore(100)
+	h := NewHub(WithStore(store))
+	defer h.Close() // nolint: errcheck
+	srv := httptest.NewServer(h.Serve(WithServeExtractUserId(func(*http.Request) string { return "u1" })))
+	defer srv.Close()
+
+	ctx := context.Background()
+	require.NoError(t, h.Broadcast(ctx, "ch1",
+		&Event{Id: "e1", Event: "message", Data: "1"},
+		&Event{Id: "e2", Event: "price", Data: "2", Meta: map[string]string{"region": "us"}},
+		&Event{Id: "e3", Event: "price", Data: "3", Meta: map[string]string{"region": "eu"}},
+		&Event{Id: "e4", Event: "status", Data: "4"},
+	))
+
+	// invalid filter
+	resp, err := http.Get(srv.URL + "?channel=ch1&filter=region")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	reqCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet,
+		srv.URL+"?channel=ch1&eventTypes=price,status&filter="+url.QueryEscape("region!=us")+"&lastEventId=e1", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint: errcheck
+	r := bufio.NewReader(resp.Body)
+	read := func(want string) {
+		buf := make([]byte, len(want))
+		_, err := io.ReadFull(r, buf)
+		require.NoError(t, err)
+		require.Equal(t, want, string(buf))
+	}
+	// resend all types then filtered.
+	read("id:e3\nevent:price\ndata:3\n\n")
+	read("id:e4\nevent:status\ndata:4\n\n")
+	read(heartbeat)
+
+	require.NoError(t, h.Broadcast(ctx, "ch1",
+		&Event{Id: "e5", Event: "message", Data: "5"},
+		&Event{Id: "e6", Event: "price", Data: "6", Meta: map[string]string{"region": "us"}},
+		&Event{Id: "e7", Event: "status", Data: "7"},
+	))
+	read("id:e7\nevent:status\ndata:7\n\n")
+}
+
+// notifyWriter the response writer notifies after written.
+type notifyWriter struct {
+	*httptest.ResponseRecorder
+	written chan struct{}
+}
+
+func (w *notifyWriter) Write(b []byte) (int, error) {
+	select {
+	case w.written <- struct{}{}:
+	default:
+	}
+	return len(b), nil
+}
+
+func (w *notifyWriter) WriteString(s string) (int, error) { return w.Write([]byte(s)) }
+
+func Test_Serve_SendStats(t *testing.T) {
+	h := NewHub()
+	defer h.Close() // nolint: errcheck
+	handler := h.Serve(WithServeExtractUserId(func(*http.Request) string { return "u1" }))
+	ctx := context.Background()
+
+	w := &notifyWriter{ResponseRecorder: httptest.NewRecorder(), written: make(chan struct{}, 1)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?channel=ch1", nil))
+	}()
+	<-w.written // the heartbeat
+	for i := range 3 {
+		require.NoError(t, h.Broadcast(ctx, "ch1", &Event{Event: "message", Data: i}))
+		<-w.written
+	}
+	require.Eventually(t, func() bool { return h.Stats().SendSuccess.Load() == 3 }, time.Second, time.Millisecond*10)
+	require.Zero(t, h.Stats().SendFailure.Load())
+
+	// the render failure closes the connection.
+	require.NoError(t, h.Broadcast(ctx, "ch1", &Event{Event: "message", Data: map[string]any{"invalid": func() {}}}))
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("the connection is not closed")
+	}
+	require.Equal(t, int64(3), h.Stats().SendSuccess.Load())
+	require.Equal(t, int64(1), h.Stats().SendFailure.Load())
+	require.Zero(t, h.SessionTotal())
+}
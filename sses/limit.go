@@ -0,0 +1,139 @@
+package sses
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ErrTooManySessions the new session exceeds the session limit, and the limit policy is LimitPolicy_Reject.
+var ErrTooManySessions = errors.New("sses: too many sessions")
+
+// EvictedEventType is the final event type sent to the session evicted by the session limit,
+// the data is EvictedEvent, it tells the client why it was closed.
+const EvictedEventType = "evicted"
+
+// LimitScope the scope of the session limit.
+type LimitScope string
+
+const (
+	LimitScope_User    LimitScope = "user"    // the sessions of the user
+	LimitScope_Channel LimitScope = "channel" // the sessions which subscribe the channel
+	LimitScope_Global  LimitScope = "global"  // all sessions
+)
+
+// LimitPolicy the policy when the new session exceeds the session limit.
+type LimitPolicy int
+
+const (
+	// LimitPolicy_Reject reject the new session with ErrTooManySessions, default.
+	LimitPolicy_Reject LimitPolicy = iota
+	// LimitPolicy_EvictOldest evict the oldest sessions with the EvictedEventType final event, then add the new session.
+	LimitPolicy_EvictOldest
+)
+
+// EvictedEvent the data of the evicted event.
+type EvictedEvent struct {
+	Scope LimitScope `json:"scope"` // 超出限制的范围
+	Limit int        `json:"limit"` // 会话数量限制
+}
+
+// sessionLimit the session limit of the scope, 0 means unlimited.
+type sessionLimit struct {
+	max    int
+	policy LimitPolicy
+}
+
+type sessionLimits struct {
+	perUser    sessionLimit
+	perChannel sessionLimit
+	global     sessionLimit
+}
+
+// admit checks the session limits for the new session by the scope of user, channel, global in order,
+// the replaced session (same session id) and the sessions to be evicted by the previous scope are excluded.
+// it rejects the session if the limit with LimitPolicy_Reject exceeded, nothing evicted,
+// otherwise evicts the oldest sessions of the scopes with LimitPolicy_EvictOldest.
+// NOTE: it should be called with the lock held.
+func (sm *SessionManager) admit(ses, replaced *Session) error {
+	victims := make(map[*Session]*Event)
+	check := func(scope LimitScope, limit sessionLimit, sessions []*Session) error {
+		if limit.max <= 0 {
+			return nil
+		}
+		sessions = slices.DeleteFunc(sessions, func(v *Session) bool {
+			_, ok := victims[v]
+			return ok || v == replaced
+		})
+		n := len(sessions) - limit.max + 1
+		if n <= 0 {
+			return nil
+		}
+		if limit.policy == LimitPolicy_Reject {
+			return fmt.Errorf("%w, %s limit %d", ErrTooManySessions, scope, limit.max)
+		}
+		slices.SortFunc(sessions, func(a, b *Session) int { return a.ConnectedAt.Compare(b.ConnectedAt) })
+		final := &Event{Event: EvictedEventType, Data: &EvictedEvent{Scope: scope, Limit: limit.max}}
+		for _, v := range sessions[:n] {
+			victims[v] = final
+		}
+		return nil
+	}
+	if err := check(LimitScope_User, sm.limits.perUser, slices.Clone(sm.byUserId[ses.UserId])); err != nil {
+		return err
+	}
+	for _, channel := range ses.Subscriptions() {
+		if err := check(LimitScope_Channel, sm.limits.perChannel, slices.Collect(maps.Keys(sm.byChannel[channel]))); err != nil {
+			return err
+		}
+	}
+	if sm.limits.global.max > 0 {
+		sessions := make([]*Session, 0, sm.total)
+		for _, v := range sm.byUserId {
+			sessions = append(sessions, v...)
+		}
+		if err := check(LimitScope_Global, sm.limits.global, sessions); err != nil {
+			return err
+		}
+	}
+	for v, final := range victims {
+		sm.evict(v, final)
+	}
+	return nil
+}
+
+// evict removes the session, sends the final event then closes it.
+// NOTE: it should be called with the lock held.
+func (sm *SessionManager) evict(ses *Session, final *Event) {
+	sessions := sm.byUserId[ses.UserId]
+	idx := slices.Index(sessions, ses)
+	if idx < 0 {
+		return
+	}
+	sm.total--
+	sessions = slices.Delete(sessions, idx, idx+1)
+	if len(sessions) == 0 {
+		delete(sm.byUserId, ses.UserId)
+	} else {
+		sm.byUserId[ses.UserId] = sessions
+	}
+	sm.unindex(ses)
+	pushEvicted(ses, final)
+	close(ses.Message) // 关闭会话
+}
+
+// pushEvicted push the final event without blocking, drop the oldest buffered event if the buffer is full,
+// so that the client always knows why it was closed.
+func pushEvicted(ses *Session, e *Event) {
+	ses.mu.Lock()
+	defer ses.mu.Unlock()
+	for range 2 {
+		select {
+		case ses.Message <- e:
+			return
+		default:
+		}
+		_, _ = tryReceive(ses.Message)
+	}
+}
@@ -0,0 +1,134 @@
+package sses
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SessionManager_Limit(t *testing.T) {
+	t0 := time.Now()
+	newSession := func(userId, channel string, seq int) *Session {
+		return &Session{
+			UserId:      userId,
+			SessionId:   NewSessionId(),
+			Channel:     channel,
+			ConnectedAt: t0.Add(time.Duration(seq) * time.Second),
+			Message:     make(chan *Event, 1),
+		}
+	}
+	evicted := func(ses *Session) *Event {
+		e, ok := <-ses.Message
+		require.True(t, ok)
+		_, ok = <-ses.Message
+		require.False(t, ok, "the evicted session should be closed")
+		return e
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		sm := NewSessionManager()
+		sm.limits = sessionLimits{
+			perUser:    sessionLimit{max: 2},
+			perChannel: sessionLimit{max: 3},
+			global:     sessionLimit{max: 4},
+		}
+		u1s1 := newSession("u1", "ch1", 1)
+		require.NoError(t, sm.TryAdd(u1s1))
+		require.NoError(t, sm.TryAdd(newSession("u1", "ch1", 2)))
+		require.ErrorIs(t, sm.TryAdd(newSession("u1", "ch2", 3)), ErrTooManySessions)
+		// replace the session is not limited.
+		require.NoError(t, sm.TryAdd(&Session{UserId: "u1", SessionId: u1s1.SessionId, Channel: "ch1", Message: make(chan *Event, 1)}))
+
+		require.NoError(t, sm.TryAdd(newSession("u2", "ch1", 4)))
+		err := sm.TryAdd(newSession("u3", "ch1", 5))
+		require.ErrorIs(t, err, ErrTooManySessions)
+		require.ErrorContains(t, err, "channel limit 3")
+		require.NoError(t, sm.TryAdd(newSession("u3", "ch2", 6)))
+		err = sm.TryAdd(newSession("u4", "ch3", 7))
+		require.ErrorIs(t, err, ErrTooManySessions)
+		require.ErrorContains(t, err, "global limit 4")
+		require.Equal(t, 4, sm.SessionTotal())
+		// Add closes the rejected session.
+		rejected := newSession("u4", "ch3", 8)
+		sm.Add(rejected)
+		_, ok := <-rejected.Message
+		require.False(t, ok)
+		require.Equal(t, 4, sm.SessionTotal())
+	})
+
+	t.Run("evict oldest", func(t *testing.T) {
+		sm := NewSessionManager()
+		sm.limits = sessionLimits{
+			perUser:    sessionLimit{max: 2, policy: LimitPolicy_EvictOldest},
+			perChannel: sessionLimit{max: 2, policy: LimitPolicy_EvictOldest},
+		}
+		u1s1 := newSession("u1", "ch1", 1)
+		u1s2 := newSession("u1", "ch2", 2)
+		require.NoError(t, sm.TryAdd(u1s2))
+		require.NoError(t, sm.TryAdd(u1s1))
+		// evict the oldest session of the user, not the last added.
+		require.NoError(t, sm.TryAdd(newSession("u1", "ch3", 3)))
+		require.Equal(t, &Event{Event: EvictedEventType, Data: &EvictedEvent{Scope: LimitScope_User, Limit: 2}}, evicted(u1s1))
+		require.Equal(t, 2, sm.SessionTotal())
+
+		u2s1 := newSession("u2", "ch2", 4)
+		require.NoError(t, sm.TryAdd(u2s1))
+		require.NoError(t, sm.TryAdd(newSession("u3", "ch2", 5)))
+		require.Equal(t, &Event{Event: EvictedEventType, Data: &EvictedEvent{Scope: LimitScope_Channel, Limit: 2}}, evicted(u1s2))
+		require.Equal(t, 2, sm.SessionTotalByChannel("ch2"))
+		require.Equal(t, 1, sm.SessionTotalByChannel("ch3"))
+	})
+
+	t.Run("evict with the full buffer", func(t *testing.T) {
+		sm := NewSessionManager()
+		sm.limits = sessionLimits{global: sessionLimit{max: 1, policy: LimitPolicy_EvictOldest}}
+		s1 := newSession("u1", "ch1", 1)
+		require.NoError(t, sm.TryAdd(s1))
+		s1.Message <- &Event{Event: "message", Data: "buffered"}
+		require.NoError(t, sm.TryAdd(newSession("u2", "ch1", 2)))
+		require.Equal(t, EvictedEventType, evicted(s1).Event)
+		require.Equal(t, 1, sm.SessionTotal())
+	})
+}
+
+func Test_Serve_SessionLimit(t *testing.T) {
+	h := NewHub(WithMaxSessionsPerUser(1, LimitPolicy_EvictOldest), WithMaxSessions(1, LimitPolicy_Reject))
+	defer h.Close() // nolint: errcheck
+	srv := httptest.NewServer(h.Serve(WithServeExtractUserId(func(r *http.Request) string { return r.URL.Query().Get("userId") })))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	connect := func(userId string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?channel=ch1&userId="+userId, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp1 := connect("u1")
+	defer resp1.Body.Close() // nolint: errcheck
+	buf := make([]byte, len(heartbeat))
+	_, err := io.ReadFull(resp1.Body, buf)
+	require.NoError(t, err)
+
+	// the global limit rejects the other user.
+	resp2 := connect("u2")
+	defer resp2.Body.Close() // nolint: errcheck
+	require.Equal(t, http.StatusTooManyRequests, resp2.StatusCode)
+
+	// the same user evicts the oldest session with the final event.
+	resp3 := connect("u1")
+	defer resp3.Body.Close() // nolint: errcheck
+	require.Equal(t, http.StatusOK, resp3.StatusCode)
+	b, err := io.ReadAll(resp1.Body)
+	require.NoError(t, err)
+	require.Equal(t, "event:evicted\ndata:{\"scope\":\"user\",\"limit\":1}\n\n", string(b))
+	require.Equal(t, 1, h.SessionTotal())
+}
@@ -0,0 +1,368 @@
+package sses
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrGapDetected the last event id has been evicted (or unknown, such as after restart),
+// the events between it and the oldest retained event are lost.
+var ErrGapDetected = errors.New("sses: gap detected, the last event id has been evicted")
+
+var (
+	_ Store       = (*MemoryStore)(nil)
+	_ AckStore    = (*MemoryStore)(nil)
+	_ LastIdStore = (*MemoryStore)(nil)
+)
+
+// MemoryStore bounded in-memory store, keep the last N events or T duration per channel in a ring buffer,
+// up to the max channels and the max pending users, the least recently saved are evicted beyond it.
+type MemoryStore struct {
+	mu          sync.Mutex
+	capacity    int
+	maxAge      time.Duration
+	now         func() time.Time
+	maxChannels int                    // 最多保留的频道数量
+	channels    map[string]*memoryRing // channel -> 事件环形缓冲区
+	channelLRU  lruKeys                // 频道, 从最久未写入到最近写入
+	// 投递确认
+	maxPending      int                        // 每个用户最多未确认的事件数量
+	maxPendingUsers int                        // 最多保留未确认事件的用户数量
+	pending         map[string][]memoryPending // userId -> 未确认的事件, 从旧到新
+	pendingLRU      lruKeys                    // 用户, 从最久未写入到最近写入
+}
+
+// NewMemoryStore new in-memory store, keep up to capacity events per channel, default 1000.
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryStore{
+		capacity:    capacity,
+		maxAge:      0,
+		now:         time.Now,
+		maxChannels: 10000,
+		channels:    make(map[string]*memoryRing),
+		channelLRU:  newLRUKeys(),
+
+		maxPending:      1000,
+		maxPendingUsers: 10000,
+		pending:         make(map[string][]memoryPending),
+		pendingLRU:      newLRUKeys(),
+	}
+}
+
+// SetMaxAge sets the max age of the events (including the pending events), 0 means unlimited.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (m *MemoryStore) SetMaxAge(d time.Duration) *MemoryStore {
+	m.maxAge = max(d, 0)
+	return m
+}
+
+// SetMaxChannels sets the max channels, the least recently saved channel is evicted beyond it, default 10000.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (m *MemoryStore) SetMaxChannels(n int) *MemoryStore {
+	if n > 0 {
+		m.maxChannels = n
+	}
+	return m
+}
+
+// Save implements Store.
+func (m *MemoryStore) Save(_ context.Context, channel string, e *Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.channels[channel]
+	if !ok {
+		if len(m.channels) >= m.maxChannels {
+			m.deleteChannel(m.channelLRU.oldest())
+		}
+		r = newMemoryRing(m.capacity)
+		m.channels[channel] = r
+	}
+	m.channelLRU.touch(channel)
+	now := m.now()
+	r.evictBefore(m.deadline(now))
+	r.push(e, now)
+	return nil
+}
+
+// ListByLastId implements Store.
+// if lastId is empty, list from the oldest retained event, if lastId has been evicted, return ErrGapDetected.
+// if eventType is not empty, only the events of the type are listed.
+func (m *MemoryStore) ListByLastId(_ context.Context, channel, eventType, lastId string, pageSize int) ([]*Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.channels[channel]
+	if ok {
+		r.evictBefore(m.deadline(m.now()))
+		if r.size == 0 {
+			m.deleteChannel(channel)
+			ok = false
+		}
+	}
+	if !ok {
+		if lastId != "" {
+			return nil, ErrGapDetected
+		}
+		return nil, nil
+	}
+	start := 0
+	if lastId != "" {
+		seq, ok := r.index[lastId]
+		if !ok {
+			return nil, ErrGapDetected
+		}
+		start = int(seq-r.firstSeq()) + 1
+	}
+	events := make([]*Event, 0, min(pageSize, r.size-start))
+	for i := start; i < r.size && len(events) < pageSize; i++ {
+		e := r.at(i).event
+		if eventType != "" && e.Event != eventType {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// LastId implements LastIdStore.
+func (m *MemoryStore) LastId(_ context.Context, channel string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.channels[channel]
+	if !ok {
+		return "", nil
+	}
+	r.evictBefore(m.deadline(m.now()))
+	if r.size == 0 {
+		m.deleteChannel(channel)
+		return "", nil
+	}
+	return r.at(r.size - 1).event.Id, nil
+}
+
+func (m *MemoryStore) deleteChannel(channel string) {
+	delete(m.channels, channel)
+	m.channelLRU.remove(channel)
+}
+
+func (m *MemoryStore) deadline(now time.Time) time.Time {
+	if m.maxAge <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-m.maxAge)
+}
+
+type memoryEntry struct {
+	event *Event
+	at    time.Time
+	seq   uint64
+}
+
+// memoryRing ring buffer of the channel events.
+type memoryRing struct {
+	capacity int
+	buf      []memoryEntry
+	head     int               // 最旧事件在buf中的位置
+	size     int               // 事件数量
+	nextSeq  uint64            // 下一个事件的序号
+	index    map[string]uint64 // 事件id -> 序号
+}
+
+func newMemoryRing(capacity int) *memoryRing {
+	return &memoryRing{
+		capacity: capacity,
+		index:    make(map[string]uint64),
+	}
+}
+
+// firstSeq the sequence of the oldest retained event.
+func (r *memoryRing) firstSeq() uint64 { return r.nextSeq - uint64(r.size) }
+
+// at the i-th retained event, from the oldest.
+func (r *memoryRing) at(i int) *memoryEntry { return &r.buf[(r.head+i)%len(r.buf)] }
+
+func (r *memoryRing) push(e *Event, at time.Time) {
+	entry := memoryEntry{event: e, at: at, seq: r.nextSeq}
+	switch {
+	case r.size < len(r.buf):
+		*r.at(r.size) = entry
+		r.size++
+	case len(r.buf) < r.capacity: // grow lazily
+		if r.head != 0 {
+			r.buf = append(slices.Clone(r.buf[r.head:]), r.buf[:r.head]...)
+			r.head = 0
+		}
+		r.buf = append(r.buf, entry)
+		r.size++
+	default: // full, overwrite the oldest
+		r.forget(r.at(0))
+		r.buf[r.head] = entry
+		r.head = (r.head + 1) % len(r.buf)
+	}
+	r.index[e.Id] = entry.seq
+	r.nextSeq++
+}
+
+// evictBefore evict the events saved before the deadline.
+func (r *memoryRing) evictBefore(deadline time.Time) {
+	for r.size > 0 && r.at(0).at.Before(deadline) {
+		entry := r.at(0)
+		r.forget(entry)
+		*entry = memoryEntry{}
+		r.head = (r.head + 1) % len(r.buf)
+		r.size--
+	}
+}
+
+func (r *memoryRing) forget(entry *memoryEntry) {
+	if seq, ok := r.index[entry.event.Id]; ok && seq == entry.seq {
+		delete(r.index, entry.event.Id)
+	}
+}
+
+// memoryPending the pending event of the user.
+type memoryPending struct {
+	channel string
+	event   *Event
+	at      time.Time
+}
+
+// SetMaxPending sets the max pending events per user, the oldest are dropped beyond it, default 1000.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (m *MemoryStore) SetMaxPending(n int) *MemoryStore {
+	if n > 0 {
+		m.maxPending = n
+	}
+	return m
+}
+
+// SetMaxPendingUsers sets the max users who have the pending events,
+// the pending events of the least recently saved user are evicted beyond it, default 10000.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (m *MemoryStore) SetMaxPendingUsers(n int) *MemoryStore {
+	if n > 0 {
+		m.maxPendingUsers = n
+	}
+	return m
+}
+
+// SavePending implements AckStore.
+func (m *MemoryStore) SavePending(_ context.Context, userId, channel string, e *Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pending := m.evictPending(userId, m.now())
+	if len(pending) == 0 && len(m.pending) >= m.maxPendingUsers {
+		m.deletePending(m.pendingLRU.oldest())
+	}
+	if len(pending) >= m.maxPending {
+		pending = slices.Delete(pending, 0, len(pending)-m.maxPending+1)
+	}
+	m.pending[userId] = append(pending, memoryPending{channel: channel, event: e, at: m.now()})
+	m.pendingLRU.touch(userId)
+	return nil
+}
+
+// ListPending implements AckStore.
+func (m *MemoryStore) ListPending(_ context.Context, userId string) ([]*Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pending := m.evictPending(userId, m.now())
+	events := make([]*Event, 0, len(pending))
+	for _, p := range pending {
+		e := *p.event
+		e.Channel = p.channel
+		events = append(events, &e)
+	}
+	return events, nil
+}
+
+// Ack implements AckStore.
+func (m *MemoryStore) Ack(_ context.Context, userId string, eventIds ...string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pending := m.evictPending(userId, m.now())
+	n := len(pending)
+	pending = slices.DeleteFunc(pending, func(p memoryPending) bool {
+		return slices.Contains(eventIds, p.event.Id)
+	})
+	if len(pending) == 0 {
+		m.deletePending(userId)
+	} else {
+		m.pending[userId] = pending
+	}
+	return n - len(pending), nil
+}
+
+// PendingCount implements AckStore.
+func (m *MemoryStore) PendingCount(_ context.Context, userId string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.evictPending(userId, m.now())), nil
+}
+
+// evictPending evict the pending events of the user older than the max age, returns the remaining.
+func (m *MemoryStore) evictPending(userId string, now time.Time) []memoryPending {
+	pending := m.pending[userId]
+	deadline := m.deadline(now)
+	i := 0
+	for i < len(pending) && pending[i].at.Before(deadline) {
+		i++
+	}
+	if i > 0 {
+		pending = slices.Delete(pending, 0, i)
+		if len(pending) == 0 {
+			m.deletePending(userId)
+		} else {
+			m.pending[userId] = pending
+		}
+	}
+	return pending
+}
+
+func (m *MemoryStore) deletePending(userId string) {
+	delete(m.pending, userId)
+	m.pendingLRU.remove(userId)
+}
+
+// lruKeys the keys ordered by the recent use, from the least recently used.
+type lruKeys struct {
+	order *list.List
+	elems map[string]*list.Element
+}
+
+func newLRUKeys() lruKeys {
+	return lruKeys{
+		order: list.New(),
+		elems: make(map[string]*list.Element),
+	}
+}
+
+// touch add the key or mark it as the most recently used.
+func (l *lruKeys) touch(key string) {
+	if elem, ok := l.elems[key]; ok {
+		l.order.MoveToBack(elem)
+		return
+	}
+	l.elems[key] = l.order.PushBack(key)
+}
+
+func (l *lruKeys) remove(key string) {
+	if elem, ok := l.elems[key]; ok {
+		l.order.Remove(elem)
+		delete(l.elems, key)
+	}
+}
+
+// oldest the least recently used key, empty if no key.
+func (l *lruKeys) oldest() string {
+	if elem := l.order.Front(); elem != nil {
+		return elem.Value.(string)
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package sses
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func eventIds(events []*Event) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
+
+func Test_MemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("list by last id", func(t *testing.T) {
+		s := NewMemoryStore(100)
+		for i := range 10 {
+			eventType := "message"
+			if i%2 == 1 {
+				eventType = "other"
+			}
+			require.NoError(t, s.Save(ctx, "ch1", &Event{Id: "e" + strconv.Itoa(i), Event: eventType, Data: i}))
+		}
+		events, err := s.ListByLastId(ctx, "ch1", "", "e3", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e4", "e5", "e6", "e7", "e8", "e9"}, eventIds(events))
+
+		// filter by event type, and paging
+		events, err = s.ListByLastId(ctx, "ch1", "message", "e1", 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e2", "e4"}, eventIds(events))
+		events, err = s.ListByLastId(ctx, "ch1", "message", "e4", 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e6", "e8"}, eventIds(events))
+		events, err = s.ListByLastId(ctx, "ch1", "message", "e9", 2)
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		// empty last id, list from the oldest
+		events, err = s.ListByLastId(ctx, "ch1", "other", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e1", "e3", "e5", "e7", "e9"}, eventIds(events))
+
+		// unknown last id or channel
+		_, err = s.ListByLastId(ctx, "ch1", "", "unknown", 100)
+		require.ErrorIs(t, err, ErrGapDetected)
+		_, err = s.ListByLastId(ctx, "ch2", "", "e1", 100)
+		require.ErrorIs(t, err, ErrGapDetected)
+		events, err = s.ListByLastId(ctx, "ch2", "", "", 100)
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		// the last event id
+		id, err := s.LastId(ctx, "ch1")
+		require.NoError(t, err)
+		require.Equal(t, "e9", id)
+		id, err = s.LastId(ctx, "ch2")
+		require.NoError(t, err)
+		require.Empty(t, id)
+	})
+
+	t.Run("evict by capacity", func(t *testing.T) {
+		s := NewMemoryStore(3)
+		for i := range 8 {
+			require.NoError(t, s.Save(ctx, "ch1", &Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: i}))
+		}
+		events, err := s.ListByLastId(ctx, "ch1", "", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e5", "e6", "e7"}, eventIds(events))
+		events, err = s.ListByLastId(ctx, "ch1", "", "e5", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e6", "e7"}, eventIds(events))
+		_, err = s.ListByLastId(ctx, "ch1", "", "e4", 100)
+		require.ErrorIs(t, err, ErrGapDetected)
+	})
+
+	t.Run("evict by max age", func(t *testing.T) {
+		now := time.Now()
+		s := NewMemoryStore(3).SetMaxAge(time.Minute)
+		s.now = func() time.Time { return now }
+		for i := range 5 {
+			now = now.Add(time.Second * 20)
+			require.NoError(t, s.Save(ctx, "ch1", &Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: i}))
+		}
+		// capacity 3, e0, e1 evicted.
+		events, err := s.ListByLastId(ctx, "ch1", "", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e2", "e3", "e4"}, eventIds(events))
+
+		// 30s later, e2 is older than 1 minute.
+		now = now.Add(time.Second * 30)
+		events, err = s.ListByLastId(ctx, "ch1", "", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e3", "e4"}, eventIds(events))
+		_, err = s.ListByLastId(ctx, "ch1", "", "e2", 100)
+		require.ErrorIs(t, err, ErrGapDetected)
+
+		// the ring grows again after all events evicted.
+		now = now.Add(time.Minute * 2)
+		_, err = s.ListByLastId(ctx, "ch1", "", "e4", 100)
+		require.ErrorIs(t, err, ErrGapDetected)
+		for i := 5; i < 10; i++ {
+			require.NoError(t, s.Save(ctx, "ch1", &Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: i}))
+		}
+		events, err = s.ListByLastId(ctx, "ch1", "", "e7", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e8", "e9"}, eventIds(events))
+	})
+
+	t.Run("evict by max channels", func(t *testing.T) {
+		s := NewMemoryStore(3).SetMaxChannels(2)
+		require.NoError(t, s.Save(ctx, "ch1", &Event{Id: "e1", Event: "message"}))
+		require.NoError(t, s.Save(ctx, "ch2", &Event{Id: "e2", Event: "message"}))
+		require.NoError(t, s.Save(ctx, "ch1", &Event{Id: "e3", Event: "message"}))
+		// ch2 is the least recently saved.
+		require.NoError(t, s.Save(ctx, "ch3", &Event{Id: "e4", Event: "message"}))
+		require.Len(t, s.channels, 2)
+		_, err := s.ListByLastId(ctx, "ch2", "", "e2", 100)
+		require.ErrorIs(t, err, ErrGapDetected)
+		events, err := s.ListByLastId(ctx, "ch1", "", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e1", "e3"}, eventIds(events))
+	})
+
+	t.Run("evict by max pending users", func(t *testing.T) {
+		s := NewMemoryStore(3).SetMaxPendingUsers(2)
+		require.NoError(t, s.SavePending(ctx, "u1", "ch1", &Event{Id: "p1", Event: "message"}))
+		require.NoError(t, s.SavePending(ctx, "u2", "ch1", &Event{Id: "p2", Event: "message"}))
+		require.NoError(t, s.SavePending(ctx, "u1", "ch1", &Event{Id: "p3", Event: "message"}))
+		// u2 is the least recently saved.
+		require.NoError(t, s.SavePending(ctx, "u3", "ch1", &Event{Id: "p4", Event: "message"}))
+		require.Len(t, s.pending, 2)
+		n, err := s.PendingCount(ctx, "u2")
+		require.NoError(t, err)
+		require.Zero(t, n)
+		n, err = s.PendingCount(ctx, "u1")
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+		// the acked user is removed.
+		n, err = s.Ack(ctx, "u3", "p4")
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		require.Len(t, s.pending, 1)
+		require.Equal(t, 1, s.pendingLRU.order.Len())
+	})
+}
+
+func Test_ResendEvents_GapDetected(t *testing.T) {
+	s := NewMemoryStore(2)
+	h := NewHub(WithStore(s))
+	defer h.Close() // nolint: errcheck
+	err := h.Broadcast(context.Background(), "ch1",
+		&Event{Id: "e1", Event: "message", Data: "one"},
+		&Event{Id: "e2", Event: "message", Data: "two"},
+		&Event{Id: "e3", Event: "message", Data: "three"},
+	)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	h.resendEvents(context.Background(), newSessionWriter(&Session{Channel: "ch1"}, func(e *Event) error { return e.Render(w) }), "ch1", "message", "e1")
+	require.Equal(t,
+		"event:gap\ndata:e1\n\n"+
+			"id:e2\nevent:message\ndata:two\n\n"+
+			"id:e3\nevent:message\ndata:three\n\n",
+		w.Body.String(),
+	)
+}
@@ -0,0 +1,257 @@
+package sses
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName the instrumentation scope name of the meter and tracer.
+const instrumentationName = "github.com/thinkgos/proc-extra/sses"
+
+// the attribute keys of the metrics and spans.
+const (
+	AttrChannel      = attribute.Key("sses.channel")       // 频道, 会话数量及队列深度为订阅的频道(可以是通配符), 指标中见 WithMetricsChannelLabel
+	AttrDropReason   = attribute.Key("sses.drop.reason")   // 丢弃原因, 见 DropReason_*
+	AttrReplaySource = attribute.Key("sses.replay.source") // 重发来源, 见 ReplaySource_*
+	AttrTarget       = attribute.Key("sses.target")        // 推送目标, broadcast, user, session
+	AttrEventCount   = attribute.Key("sses.event.count")   // 事件数量
+	AttrLastEventId  = attribute.Key("sses.last_event_id") // 重连时的最后事件id
+)
+
+// MetricsChannelOther the channel attribute of the metrics beyond the max distinct values, see WithMetricsMaxChannels.
+const MetricsChannelOther = "other"
+
+// the drop reasons of the sses.dropped metric.
+const (
+	DropReason_DropNewest = "drop_newest" // 缓冲区满, 丢弃最新的事件
+	DropReason_DropOldest = "drop_oldest" // 缓冲区满, 丢弃最旧的缓冲事件
+	DropReason_Coalesce   = "coalesce"    // 缓冲区满, 被同类型的最新事件替换
+	DropReason_Disconnect = "disconnect"  // 缓冲区满, 断开慢会话
+	DropReason_Closed     = "closed"      // 已关闭, 放弃推送
+)
+
+// the replay sources of the sses.replayed metric.
+const (
+	ReplaySource_Store   = "store"   // 按最后事件id从存储重发
+	ReplaySource_Pending = "pending" // 重发未确认的事件
+)
+
+// WithMeterProvider set the meter provider of the metrics, default the global meter provider.
+// the metrics:
+//   - sses.sessions: the sessions per subscribed channel.
+//   - sses.queue.depth: the buffered events of the sessions per subscribed channel.
+//   - sses.enqueue.duration: the latency of enqueueing the event into the session buffer, including retries.
+//   - sses.dropped: the events dropped, by channel and reason.
+//   - sses.timeouts: the events timed out pushing with retry.
+//   - sses.replayed: the events replayed on reconnect, by channel and source.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(h *options) {
+		h.meterProvider = mp
+	}
+}
+
+// WithMetricsChannelLabel set the mapping of the channel to the attribute sses.channel of the metrics,
+// such as the subscription pattern (`orders/123/status` -> `orders/+/status`) for the per-user or
+// per-order channels, default the channel itself.
+func WithMetricsChannelLabel(f func(channel string) string) Option {
+	return func(h *options) {
+		h.metricsLabel = f
+	}
+}
+
+// WithMetricsMaxChannels set the max distinct values of the attribute sses.channel of the metrics,
+// the channels beyond it are attributed MetricsChannelOther, default 1000.
+func WithMetricsMaxChannels(n int) Option {
+	return func(h *options) {
+		if n > 0 {
+			h.metricsMaxLabel = n
+		}
+	}
+}
+
+// WithTracerProvider set the tracer provider of the spans, default the global tracer provider.
+// the spans:
+//   - sses.publish: publish the events (Broadcast, Publish, PublishSession).
+//   - sses.replay: replay the events on reconnect.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(h *options) {
+		h.tracerProvider = tp
+	}
+}
+
+// hubMetrics the metrics of the hub.
+type hubMetrics struct {
+	enqueueDuration metric.Float64Histogram
+	dropped         metric.Int64Counter
+	timeouts        metric.Int64Counter
+	replayed        metric.Int64Counter
+	registration    metric.Registration // 会话数量及队列深度的回调注册
+	labelMu         sync.Mutex
+	labels          map[string]struct{} // 已使用的频道属性值, 最多 metricsMaxLabel 个
+}
+
+// channelLabel returns the bounded value of the attribute sses.channel of the channel.
+func (h *Hub) channelLabel(channel string) string {
+	label := channel
+	if h.metricsLabel != nil {
+		label = h.metricsLabel(channel)
+	}
+	h.metrics.labelMu.Lock()
+	defer h.metrics.labelMu.Unlock()
+	if _, ok := h.metrics.labels[label]; !ok {
+		if len(h.metrics.labels) >= h.metricsMaxLabel {
+			label = MetricsChannelOther
+		} else {
+			h.metrics.labels[label] = struct{}{}
+		}
+	}
+	return label
+}
+
+// initTelemetry init the metrics and tracer of the hub.
+func (h *Hub) initTelemetry() error {
+	mp, tp := h.meterProvider, h.tracerProvider
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	h.tracer = tp.Tracer(instrumentationName)
+	h.metrics.labels = make(map[string]struct{})
+
+	meter := mp.Meter(instrumentationName)
+	var err, e error
+	h.metrics.enqueueDuration, e = meter.Float64Histogram("sses.enqueue.duration",
+		metric.WithDescription("The latency of enqueueing the event into the session buffer."),
+		metric.WithUnit("s"),
+	)
+	err = errors.Join(err, e)
+	h.metrics.dropped, e = meter.Int64Counter("sses.dropped",
+		metric.WithDescription("The number of the events dropped."),
+		metric.WithUnit("{event}"),
+	)
+	err = errors.Join(err, e)
+	h.metrics.timeouts, e = meter.Int64Counter("sses.timeouts",
+		metric.WithDescription("The number of the events timed out pushing."),
+		metric.WithUnit("{event}"),
+	)
+	err = errors.Join(err, e)
+	h.metrics.replayed, e = meter.Int64Counter("sses.replayed",
+		metric.WithDescription("The number of the events replayed on reconnect."),
+		metric.WithUnit("{event}"),
+	)
+	err = errors.Join(err, e)
+	sessions, e := meter.Int64ObservableGauge("sses.sessions",
+		metric.WithDescription("The number of the sessions subscribed the channel."),
+		metric.WithUnit("{session}"),
+	)
+	err = errors.Join(err, e)
+	depth, e := meter.Int64ObservableGauge("sses.queue.depth",
+		metric.WithDescription("The number of the buffered events of the sessions subscribed the channel."),
+		metric.WithUnit("{event}"),
+	)
+	err = errors.Join(err, e)
+	h.metrics.registration, e = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		// the channels of the same label are summed up.
+		stats := make(map[string]channelStat)
+		for channel, st := range h.sessions.channelStats() {
+			label := h.channelLabel(channel)
+			sum := stats[label]
+			sum.sessions += st.sessions
+			sum.depth += st.depth
+			stats[label] = sum
+		}
+		for label, st := range stats {
+			attrs := metric.WithAttributes(AttrChannel.String(label))
+			o.ObserveInt64(sessions, int64(st.sessions), attrs)
+			o.ObserveInt64(depth, int64(st.depth), attrs)
+		}
+		return nil
+	}, sessions, depth)
+	return errors.Join(err, e)
+}
+
+// closeTelemetry unregister the metrics callback.
+func (h *Hub) closeTelemetry() {
+	if h.metrics.registration != nil {
+		if err := h.metrics.registration.Unregister(); err != nil {
+			slog.Error("sses: unregister metrics callback failure", slog.Any("error", err))
+		}
+	}
+}
+
+// onEnqueued the event enqueued into the session buffer since start.
+func (h *Hub) onEnqueued(ctx context.Context, e *Event, start time.Time) {
+	h.stats.ReqSuccess.Add(1)
+	h.metrics.enqueueDuration.Record(ctx, time.Since(start).Seconds(),
+		metric.WithAttributes(AttrChannel.String(h.channelLabel(e.Channel))))
+}
+
+// onDropped the n events dropped for the reason.
+func (h *Hub) onDropped(ctx context.Context, e *Event, reason string, n int) {
+	switch reason {
+	case DropReason_DropNewest:
+		h.stats.OverflowDropNewest.Add(int64(n))
+	case DropReason_DropOldest:
+		h.stats.OverflowDropOldest.Add(int64(n))
+	case DropReason_Coalesce:
+		h.stats.OverflowCoalesce.Add(int64(n))
+	case DropReason_Disconnect:
+		h.stats.OverflowDisconnect.Add(int64(n))
+	default:
+		h.stats.ReqFailure.Add(int64(n))
+	}
+	h.metrics.dropped.Add(ctx, int64(n),
+		metric.WithAttributes(AttrChannel.String(h.channelLabel(e.Channel)), AttrDropReason.String(reason)))
+}
+
+// onTimeout the event timed out pushing with retry.
+func (h *Hub) onTimeout(ctx context.Context, e *Event) {
+	h.stats.ReqFailure.Add(1)
+	h.stats.ReqTimeout.Add(1)
+	h.metrics.timeouts.Add(ctx, 1, metric.WithAttributes(AttrChannel.String(h.channelLabel(e.Channel))))
+}
+
+// onReplayed the event replayed on reconnect from the source.
+func (h *Hub) onReplayed(ctx context.Context, e *Event, source string) {
+	h.metrics.replayed.Add(ctx, 1,
+		metric.WithAttributes(AttrChannel.String(h.channelLabel(e.Channel)), AttrReplaySource.String(source)))
+}
+
+// startPublishSpan start the span of publishing the message.
+func (h *Hub) startPublishSpan(ctx context.Context, msg *BrokerMessage) (context.Context, trace.Span) {
+	target := "broadcast"
+	switch msg.Target {
+	case BrokerTarget_User:
+		target = "user"
+	case BrokerTarget_Session:
+		target = "session"
+	}
+	return h.tracer.Start(ctx, "sses.publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			AttrChannel.String(msg.Channel),
+			AttrTarget.String(target),
+			AttrEventCount.Int(len(msg.Events)),
+		),
+	)
+}
+
+// endSpan end the span, record the error if any.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
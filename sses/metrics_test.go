@@ -0,0 +1,191 @@
+package sses
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testMetrics the collected metrics, metric name -> attributes -> value,
+// the value of the histogram is the count.
+type testMetrics map[string]map[attribute.Distinct]int64
+
+func collectMetrics(t *testing.T, reader sdkmetric.Reader) testMetrics {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	got := make(testMetrics)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			values := make(map[attribute.Distinct]int64)
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					values[dp.Attributes.Equivalent()] = dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					values[dp.Attributes.Equivalent()] = dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					values[dp.Attributes.Equivalent()] = int64(dp.Count)
+				}
+			}
+			got[m.Name] = values
+		}
+	}
+	return got
+}
+
+func (m testMetrics) value(name string, attrs ...attribute.KeyValue) int64 {
+	set := attribute.NewSet(attrs...)
+	return m[name][set.Equivalent()]
+}
+
+func Test_Hub_Metrics(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	h := NewHub(
+		WithStore(NewMemoryStore(100)),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		WithOverflowPolicy(OverflowPolicy_DropNewest),
+		WithChannelOverflowPolicy("ch2", OverflowPolicy_Retry),
+		WithRetryLimit(1),
+		WithRetryTimeout(time.Millisecond*10),
+	)
+	defer h.Close() // nolint: errcheck
+
+	newSession := func(channels ...string) *Session {
+		ses := &Session{UserId: "u1", SessionId: NewSessionId(), Channel: channels[0], Channels: channels, Message: make(chan *Event, 2)}
+		h.sessions.Add(ses)
+		return ses
+	}
+	s1 := newSession("ch1")
+	newSession("ch1", "ch2")
+	newSession("ch2")
+
+	for i := range 3 {
+		require.NoError(t, h.Broadcast(ctx, "ch1", &Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: i}))
+	}
+	// ch2 retry with timeout, s2 is full.
+	require.NoError(t, h.PublishSync(ctx, "ch2", "u1", &Event{Id: "e3", Event: "message", Data: 3}))
+
+	channel1 := AttrChannel.String("ch1")
+	channel2 := AttrChannel.String("ch2")
+	got := collectMetrics(t, reader)
+	require.Equal(t, int64(2), got.value("sses.sessions", channel1))
+	require.Equal(t, int64(2), got.value("sses.sessions", channel2))
+	require.Equal(t, int64(4), got.value("sses.queue.depth", channel1)) // s1(2) + s2(2)
+	require.Equal(t, int64(3), got.value("sses.queue.depth", channel2)) // s2(2) + s3(1)
+	require.Equal(t, int64(4), got.value("sses.enqueue.duration", channel1))
+	require.Equal(t, int64(1), got.value("sses.enqueue.duration", channel2))
+	require.Equal(t, int64(2), got.value("sses.dropped", channel1, AttrDropReason.String(DropReason_DropNewest)))
+	require.Equal(t, int64(1), got.value("sses.timeouts", channel2))
+	require.Equal(t, int64(5), h.Stats().ReqSuccess.Load())
+	require.Equal(t, int64(2), h.Stats().OverflowDropNewest.Load())
+	require.Equal(t, int64(1), h.Stats().ReqTimeout.Load())
+
+	// replayed from the store.
+	var rendered []string
+	sw := newSessionWriter(s1, func(e *Event) error {
+		rendered = append(rendered, e.Id)
+		return nil
+	})
+	require.True(t, h.resend(ctx, sw, &sessionRequest{session: s1, lastEventId: "e0"}))
+	require.Equal(t, []string{"e1", "e2"}, rendered)
+
+	// the sessions gauge follows the deregistration.
+	h.sessions.Delete(s1)
+	got = collectMetrics(t, reader)
+	require.Equal(t, int64(2), got.value("sses.replayed", channel1, AttrReplaySource.String(ReplaySource_Store)))
+	require.Equal(t, int64(1), got.value("sses.sessions", channel1))
+	require.Equal(t, int64(2), got.value("sses.queue.depth", channel1))
+}
+
+func Test_Hub_Metrics_ChannelLabel(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	h := NewHub(
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		WithMetricsChannelLabel(func(channel string) string {
+			if strings.HasPrefix(channel, "orders/") {
+				return "orders/+"
+			}
+			return channel
+		}),
+		WithMetricsMaxChannels(2),
+	)
+	defer h.Close() // nolint: errcheck
+
+	for _, channel := range []string{"orders/1", "orders/2", "ch1", "ch2", "ch3"} {
+		h.sessions.Add(&Session{UserId: "u1", SessionId: NewSessionId(), Channel: channel, Message: make(chan *Event, 2)})
+		require.NoError(t, h.Broadcast(ctx, channel, &Event{Id: channel, Event: "message", Data: channel}))
+	}
+
+	// mapped to the label, and the labels beyond the max are attributed other.
+	got := collectMetrics(t, reader)
+	require.Len(t, got["sses.enqueue.duration"], 3)
+	require.Equal(t, int64(2), got.value("sses.enqueue.duration", AttrChannel.String("orders/+")))
+	require.Equal(t, int64(1), got.value("sses.enqueue.duration", AttrChannel.String("ch1")))
+	require.Equal(t, int64(2), got.value("sses.enqueue.duration", AttrChannel.String(MetricsChannelOther)))
+	require.Len(t, got["sses.sessions"], 3)
+	require.Equal(t, int64(2), got.value("sses.sessions", AttrChannel.String("orders/+")))
+	require.Equal(t, int64(1), got.value("sses.sessions", AttrChannel.String("ch1")))
+	require.Equal(t, int64(2), got.value("sses.sessions", AttrChannel.String(MetricsChannelOther)))
+}
+
+func Test_Hub_Spans(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	h := NewHub(
+		WithStore(NewMemoryStore(100)),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+		WithEventEncoder("invalid", EncoderFunc(func(any) ([]byte, error) { return nil, errors.New("invalid data") })),
+	)
+	defer h.Close() // nolint: errcheck
+
+	require.NoError(t, h.Publish(ctx, "ch1", "u1",
+		&Event{Id: "e1", Event: "message", Data: "1"},
+		&Event{Id: "e2", Event: "message", Data: "2"},
+	))
+	require.Error(t, h.Broadcast(ctx, "ch1", &Event{Id: "e3", Event: "invalid", Data: "3"}))
+	ses := &Session{UserId: "u1", SessionId: NewSessionId(), Channel: "ch1", Message: make(chan *Event, 2)}
+	sw := newSessionWriter(ses, func(*Event) error { return nil })
+	h.resend(ctx, sw, &sessionRequest{session: ses, eventType: "message", lastEventId: "e1"})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	attrs := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes() {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	require.Equal(t, "sses.publish", spans[0].Name())
+	require.Equal(t, "user", attrs(spans[0])[AttrTarget].AsString())
+	require.Equal(t, "ch1", attrs(spans[0])[AttrChannel].AsString())
+	require.Equal(t, int64(2), attrs(spans[0])[AttrEventCount].AsInt64())
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	require.Equal(t, "sses.publish", spans[1].Name())
+	require.Equal(t, "broadcast", attrs(spans[1])[AttrTarget].AsString())
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+
+	require.Equal(t, "sses.replay", spans[2].Name())
+	require.Equal(t, []string{"ch1"}, attrs(spans[2])[AttrChannel].AsStringSlice())
+	require.Equal(t, "e1", attrs(spans[2])[AttrLastEventId].AsString())
+	require.Equal(t, int64(1), attrs(spans[2])[AttrEventCount].AsInt64())
+}
@@ -0,0 +1,170 @@
+package sses
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+)
+
+// maxRetryPerSession the max in-flight retry pushes per session of OverflowPolicy_Retry.
+const maxRetryPerSession = 8
+
+// OverflowPolicy the policy when the session message buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowPolicy_Retry retry push with timeout, the async push retries in the goroutine pool,
+	// at most 8 in-flight retries per session, the more events are dropped as OverflowPolicy_DropNewest.
+	OverflowPolicy_Retry OverflowPolicy = iota
+	// OverflowPolicy_DropNewest drop the newest event.
+	OverflowPolicy_DropNewest
+	// OverflowPolicy_DropOldest drop the oldest buffered event, then enqueue the newest event.
+	OverflowPolicy_DropOldest
+	// OverflowPolicy_Coalesce replace the buffered events of the same event type with the newest event,
+	// if there is none, drop the oldest buffered event.
+	OverflowPolicy_Coalesce
+	// OverflowPolicy_Disconnect disconnect the slow session, the client reconnects with the last event id,
+	// and the events are resent if the store set.
+	OverflowPolicy_Disconnect
+)
+
+// rewritesBuffer reports whether the policy rewrites the buffered events under the session lock.
+func (p OverflowPolicy) rewritesBuffer() bool {
+	return p == OverflowPolicy_DropOldest || p == OverflowPolicy_Coalesce
+}
+
+// trySend send the event to the session buffer without blocking, it reports whether sent.
+// it holds the session lock if any policy rewrites the buffer, so that the send never interleaves
+// with dropOldest or coalesce.
+func (h *Hub) trySend(ses *Session, e *Event) bool {
+	if h.lockOnSend {
+		ses.mu.Lock()
+		defer ses.mu.Unlock()
+	}
+	select {
+	case ses.Message <- e:
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *Hub) overflowPolicyOf(channel string) OverflowPolicy {
+	if p, ok := h.channelOverflow[channel]; ok {
+		return p
+	}
+	return h.overflowPolicy
+}
+
+// overflow handle the event when the session message buffer is full.
+func (h *Hub) overflow(ctx context.Context, ses *Session, e *Event, start time.Time, async bool) {
+	switch h.overflowPolicyOf(e.Channel) {
+	case OverflowPolicy_DropNewest:
+		h.onDropped(ctx, e, DropReason_DropNewest, 1)
+	case OverflowPolicy_DropOldest:
+		h.dropOldest(ctx, ses, e, start)
+	case OverflowPolicy_Coalesce:
+		h.coalesce(ctx, ses, e, start)
+	case OverflowPolicy_Disconnect:
+		h.onDropped(ctx, e, DropReason_Disconnect, 1)
+		slog.WarnContext(ctx, "disconnect slow session",
+			slog.String("userId", ses.UserId),
+			slog.String("sessionId", ses.SessionId),
+			slog.String("channel", ses.Channel),
+		)
+		h.sessions.Delete(ses)
+	default:
+		if !async {
+			h.tryPushWithTimeout(ctx, ses, e, start)
+			return
+		}
+		if ses.retrying.Add(1) > maxRetryPerSession {
+			ses.retrying.Add(-1)
+			h.onDropped(ctx, e, DropReason_DropNewest, 1)
+			return
+		}
+		h.pending.Add()
+		err := ants.Submit(func() {
+			defer h.pending.Done()
+			defer ses.retrying.Add(-1)
+			h.tryPushWithTimeout(context.Background(), ses, e, start)
+		})
+		if err != nil {
+			h.pending.Done()
+			ses.retrying.Add(-1)
+			slog.ErrorContext(ctx, "tryPublish submit task failure", slog.Any("error", err))
+		}
+	}
+}
+
+// dropOldest drop the oldest buffered events until the event enqueued.
+func (h *Hub) dropOldest(ctx context.Context, ses *Session, e *Event, start time.Time) {
+	ses.mu.Lock()
+	defer ses.mu.Unlock()
+	for range cap(ses.Message) + 1 {
+		select {
+		case ses.Message <- e:
+			h.onEnqueued(ctx, e, start)
+			return
+		default:
+		}
+		select {
+		case v := <-ses.Message:
+			h.onDropped(ctx, v, DropReason_DropOldest, 1)
+		default:
+		}
+	}
+	h.onDropped(ctx, e, DropReason_DropNewest, 1)
+}
+
+// coalesce replace the buffered events of the same event type with the event.
+func (h *Hub) coalesce(ctx context.Context, ses *Session, e *Event, start time.Time) {
+	ses.mu.Lock()
+	defer ses.mu.Unlock()
+
+	buffered := make([]*Event, 0, cap(ses.Message))
+	for len(buffered) < cap(ses.Message) {
+		v, ok := tryReceive(ses.Message)
+		if !ok {
+			break
+		}
+		buffered = append(buffered, v)
+	}
+	coalesced := false
+	buffered = slices.DeleteFunc(buffered, func(v *Event) bool {
+		if v.Event != e.Event {
+			return false
+		}
+		coalesced = true
+		h.onDropped(ctx, v, DropReason_Coalesce, 1)
+		return true
+	})
+	if !coalesced && len(buffered) > 0 && len(buffered) >= cap(ses.Message) {
+		h.onDropped(ctx, buffered[0], DropReason_DropOldest, 1)
+		buffered = buffered[1:]
+	}
+	buffered = append(buffered, e)
+	for _, v := range buffered {
+		select {
+		case ses.Message <- v:
+			if v == e {
+				h.onEnqueued(ctx, e, start)
+			}
+		default:
+			h.onDropped(ctx, v, DropReason_DropNewest, 1)
+		}
+	}
+}
+
+// tryReceive receive the buffered event without blocking, return false if no event or the chan closed.
+func tryReceive(ch chan *Event) (*Event, bool) {
+	select {
+	case v, ok := <-ch:
+		return v, ok && v != nil
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,130 @@
+package sses
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OverflowPolicy(t *testing.T) {
+	newSession := func(channel string) *Session {
+		return &Session{UserId: "u1", SessionId: NewSessionId(), Channel: channel, Message: make(chan *Event, 2)}
+	}
+	broadcast := func(t *testing.T, h *Hub, channel string, events ...*Event) {
+		require.NoError(t, h.Broadcast(context.Background(), channel, events...))
+	}
+	drain := func(ses *Session) []string {
+		ids := make([]string, 0)
+		for {
+			e, ok := tryReceive(ses.Message)
+			if !ok {
+				return ids
+			}
+			ids = append(ids, e.Id)
+		}
+	}
+
+	t.Run("drop newest", func(t *testing.T) {
+		h := NewHub(WithOverflowPolicy(OverflowPolicy_DropNewest))
+		defer h.Close() // nolint: errcheck
+		ses := newSession("ch1")
+		h.sessions.Add(ses)
+		for i := range 5 {
+			broadcast(t, h, "ch1", &Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: i})
+		}
+		require.Equal(t, []string{"e0", "e1"}, drain(ses))
+		require.Equal(t, int64(2), h.Stats().ReqSuccess.Load())
+		require.Equal(t, int64(3), h.Stats().OverflowDropNewest.Load())
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		h := NewHub(WithOverflowPolicy(OverflowPolicy_DropOldest))
+		defer h.Close() // nolint: errcheck
+		ses := newSession("ch1")
+		h.sessions.Add(ses)
+		for i := range 5 {
+			broadcast(t, h, "ch1", &Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: i})
+		}
+		require.Equal(t, []string{"e3", "e4"}, drain(ses))
+		require.Equal(t, int64(5), h.Stats().ReqSuccess.Load())
+		require.Equal(t, int64(3), h.Stats().OverflowDropOldest.Load())
+	})
+
+	t.Run("coalesce by event type", func(t *testing.T) {
+		h := NewHub(WithOverflowPolicy(OverflowPolicy_Coalesce))
+		defer h.Close() // nolint: errcheck
+		ses := newSession("ch1")
+		h.sessions.Add(ses)
+		broadcast(t, h, "ch1",
+			&Event{Id: "e0", Event: "price", Data: 0},
+			&Event{Id: "e1", Event: "status", Data: 1},
+			&Event{Id: "e2", Event: "price", Data: 2},  // replace e0
+			&Event{Id: "e3", Event: "status", Data: 3}, // replace e1
+			&Event{Id: "e4", Event: "other", Data: 4},  // no same type, drop the oldest e2
+		)
+		require.Equal(t, []string{"e3", "e4"}, drain(ses))
+		require.Equal(t, int64(5), h.Stats().ReqSuccess.Load())
+		require.Equal(t, int64(2), h.Stats().OverflowCoalesce.Load())
+		require.Equal(t, int64(1), h.Stats().OverflowDropOldest.Load())
+	})
+
+	t.Run("concurrent publish keep the accounting", func(t *testing.T) {
+		for _, policy := range []OverflowPolicy{OverflowPolicy_DropOldest, OverflowPolicy_Coalesce} {
+			h := NewHub(WithOverflowPolicy(policy))
+			ses := newSession("ch1")
+			h.sessions.Add(ses)
+			var wg sync.WaitGroup
+			for i := range 8 {
+				wg.Go(func() {
+					for j := range 50 {
+						broadcast(t, h, "ch1", &Event{Id: strconv.Itoa(i*50 + j), Event: "e" + strconv.Itoa(j%3), Data: j})
+					}
+				})
+			}
+			wg.Wait()
+			st := h.Stats()
+			enqueued := st.ReqSuccess.Load()
+			dropped := st.OverflowDropOldest.Load() + st.OverflowCoalesce.Load() + st.OverflowDropNewest.Load()
+			require.Equal(t, int64(400), enqueued)
+			require.Equal(t, int64(len(drain(ses))), enqueued-dropped)
+			require.NoError(t, h.Close())
+		}
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		h := NewHub(WithOverflowPolicy(OverflowPolicy_DropNewest), WithChannelOverflowPolicy("ch2", OverflowPolicy_Disconnect))
+		defer h.Close() // nolint: errcheck
+		ses1 := newSession("ch1")
+		ses2 := newSession("ch2")
+		h.sessions.Add(ses1)
+		h.sessions.Add(ses2)
+		for i := range 3 {
+			broadcast(t, h, "ch1", &Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: i})
+			broadcast(t, h, "ch2", &Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: i})
+		}
+		require.Equal(t, 1, h.SessionTotal())
+		require.Equal(t, []string{"e0", "e1"}, drain(ses2))
+		_, ok := <-ses2.Message
+		require.False(t, ok)
+		require.Equal(t, int64(1), h.Stats().OverflowDisconnect.Load())
+		require.Equal(t, int64(1), h.Stats().OverflowDropNewest.Load())
+	})
+
+	t.Run("retry bounded per session", func(t *testing.T) {
+		h := NewHub(WithRetryTimeout(time.Second * 10))
+		ses := newSession("ch1")
+		h.sessions.Add(ses)
+		for i := range 20 {
+			broadcast(t, h, "ch1", &Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: i})
+		}
+		require.LessOrEqual(t, ses.retrying.Load(), int32(maxRetryPerSession))
+		require.Equal(t, int64(20-2-maxRetryPerSession), h.Stats().OverflowDropNewest.Load())
+		// the retries aborted when close.
+		require.NoError(t, h.Close())
+		require.Equal(t, int32(0), ses.retrying.Load())
+	})
+}
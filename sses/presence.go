@@ -0,0 +1,317 @@
+package sses
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// PresenceJoinEventType is the event type broadcast to the channel when a user goes online,
+	// the data is PresenceEvent.
+	PresenceJoinEventType = "presence.join"
+	// PresenceLeaveEventType is the event type broadcast to the channel when a user goes offline,
+	// the data is PresenceEvent.
+	PresenceLeaveEventType = "presence.leave"
+)
+
+// Presence the online user of the channel.
+type Presence struct {
+	UserId      string    `json:"userId"`      // 用户id
+	Sessions    int       `json:"sessions"`    // 会话数量
+	ConnectedAt time.Time `json:"connectedAt"` // 最早的会话连接时间
+}
+
+// PresenceChange the user goes online or offline on the channel.
+type PresenceChange struct {
+	Channel string // 频道
+	UserId  string // 用户id
+	Joined  bool   // true: 上线, false: 下线
+}
+
+// PresenceEvent the data of the presence.join and presence.leave events.
+type PresenceEvent struct {
+	Channel string `json:"channel"`
+	UserId  string `json:"userId"`
+}
+
+// PresenceBackend aggregates the presence of the channels across the hub nodes.
+type PresenceBackend interface {
+	// Report replaces the node's presence of the channel, it expires after ttl unless reported again.
+	// the empty presences removes the node's presence of the channel.
+	Report(ctx context.Context, node, channel string, presences []*Presence, ttl time.Duration) error
+	// List the presence of the channel aggregated across the alive nodes, sorted by user id.
+	List(ctx context.Context, channel string) ([]*Presence, error)
+}
+
+// MergePresence merges the presences of the same user, the sessions are summed up and
+// the earliest connected time is kept, the result is sorted by user id.
+func MergePresence(presences []*Presence) []*Presence {
+	merged := make(map[string]*Presence, len(presences))
+	for _, p := range presences {
+		if v, ok := merged[p.UserId]; ok {
+			v.Sessions += p.Sessions
+			if p.ConnectedAt.Before(v.ConnectedAt) {
+				v.ConnectedAt = p.ConnectedAt
+			}
+		} else {
+			v := *p
+			merged[p.UserId] = &v
+		}
+	}
+	return slices.SortedFunc(maps.Values(merged), comparePresence)
+}
+
+func comparePresence(a, b *Presence) int { return cmp.Compare(a.UserId, b.UserId) }
+
+var _ PresenceBackend = (*MemoryPresence)(nil)
+
+// MemoryPresence in-process presence backend, aggregate the hubs in the same process.
+type MemoryPresence struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	channels map[string]map[string]*memoryPresenceNode // channel -> node -> 节点在线信息
+}
+
+type memoryPresenceNode struct {
+	presences []*Presence
+	expiresAt time.Time
+}
+
+// NewMemoryPresence new an in-process presence backend.
+func NewMemoryPresence() *MemoryPresence {
+	return &MemoryPresence{
+		now:      time.Now,
+		channels: make(map[string]map[string]*memoryPresenceNode),
+	}
+}
+
+// Report implements PresenceBackend.
+func (m *MemoryPresence) Report(_ context.Context, node, channel string, presences []*Presence, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nodes, ok := m.channels[channel]
+	if len(presences) == 0 {
+		if ok {
+			delete(nodes, node)
+			if len(nodes) == 0 {
+				delete(m.channels, channel)
+			}
+		}
+		return nil
+	}
+	if !ok {
+		nodes = make(map[string]*memoryPresenceNode)
+		m.channels[channel] = nodes
+	}
+	nodes[node] = &memoryPresenceNode{
+		presences: slices.Clone(presences),
+		expiresAt: m.now().Add(ttl),
+	}
+	return nil
+}
+
+// List implements PresenceBackend.
+func (m *MemoryPresence) List(_ context.Context, channel string) ([]*Presence, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	nodes := m.channels[channel]
+	presences := make([]*Presence, 0)
+	for node, v := range nodes {
+		if !now.Before(v.expiresAt) { // 节点心跳超时
+			delete(nodes, node)
+			continue
+		}
+		presences = append(presences, v.presences...)
+	}
+	if len(nodes) == 0 {
+		delete(m.channels, channel)
+	}
+	return MergePresence(presences), nil
+}
+
+// Presence returns the online users of the channel (may be wildcard), sorted by user id,
+// it aggregates across the hub nodes if the presence backend is set, otherwise only the local sessions.
+func (h *Hub) Presence(ctx context.Context, channel string) ([]*Presence, error) {
+	if h.presence == nil {
+		return h.sessions.Presence(channel), nil
+	}
+	return h.presence.List(ctx, channel)
+}
+
+// presenceReporter reports the local presence to the presence backend.
+type presenceReporter struct {
+	mu       sync.Mutex
+	reported map[string]struct{} // 已上报的channel
+	// 待处理的在线状态变更, 由后台协程批量处理, 不阻塞会话的注册和注销
+	changesMu sync.Mutex
+	changes   []PresenceChange
+	notify    chan struct{}
+}
+
+// reportPresence reports the local presence of the channel, the reports are serialized,
+// so that the latest snapshot always wins.
+func (h *Hub) reportPresence(ctx context.Context, channel string) error {
+	h.reporter.mu.Lock()
+	defer h.reporter.mu.Unlock()
+	presences := h.sessions.Presence(channel)
+	if err := h.presence.Report(ctx, h.node, channel, presences, h.presenceTTL); err != nil {
+		return err
+	}
+	if len(presences) == 0 {
+		delete(h.reporter.reported, channel)
+	} else {
+		h.reporter.reported[channel] = struct{}{}
+	}
+	return nil
+}
+
+// refreshPresence reports the local presence of all channels (include the channels become empty).
+func (h *Hub) refreshPresence(ctx context.Context) {
+	h.reporter.mu.Lock()
+	channels := slices.Collect(maps.Keys(h.reporter.reported))
+	h.reporter.mu.Unlock()
+	channels = append(channels, h.sessions.Channels()...)
+	slices.Sort(channels)
+	for _, channel := range slices.Compact(channels) {
+		if err := h.reportPresence(ctx, channel); err != nil {
+			slog.WarnContext(ctx, "sses: report presence failure", slog.String("channel", channel), slog.Any("error", err))
+		}
+	}
+}
+
+// presenceLoop processes the presence changes in batch, and refreshes the local presence periodically
+// if the presence backend is set, until the hub closed.
+func (h *Hub) presenceLoop() {
+	var heartbeat <-chan time.Time
+	if h.presence != nil {
+		t := time.NewTicker(max(h.presenceTTL/3, time.Millisecond))
+		defer t.Stop()
+		heartbeat = t.C
+	}
+	for {
+		select {
+		case <-h.reporter.notify:
+			h.reporter.changesMu.Lock()
+			changes := h.reporter.changes
+			h.reporter.changes = nil
+			h.reporter.changesMu.Unlock()
+			h.processPresenceChanges(context.Background(), changes)
+		case <-heartbeat:
+			h.refreshPresence(context.Background())
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// onPresenceChange queues the presence changes for the presenceLoop, it never blocks the session registration.
+func (h *Hub) onPresenceChange(changes []PresenceChange) {
+	if h.closed.Load() {
+		return
+	}
+	h.reporter.changesMu.Lock()
+	h.reporter.changes = append(h.reporter.changes, changes...)
+	h.reporter.changesMu.Unlock()
+	select {
+	case h.reporter.notify <- struct{}{}:
+	default:
+	}
+}
+
+// processPresenceChanges reports the changed channels to the presence backend, and broadcasts the presence events.
+// with the presence backend, a user online on the other nodes does not join or leave,
+// the backend is listed once per channel for the joined and the left changes respectively.
+func (h *Hub) processPresenceChanges(ctx context.Context, changes []PresenceChange) {
+	if h.closed.Load() || len(changes) == 0 {
+		return
+	}
+	// the joined users are broadcast only if not online on the other nodes, checked before reporting.
+	var broadcasts []PresenceChange
+	if h.presenceEvents {
+		broadcasts = h.localPresenceChanges(ctx, changes, true)
+	}
+	if h.presence != nil {
+		channels := make([]string, 0, len(changes))
+		for _, c := range changes {
+			channels = append(channels, c.Channel)
+		}
+		slices.Sort(channels)
+		for _, channel := range slices.Compact(channels) {
+			if err := h.reportPresence(ctx, channel); err != nil {
+				slog.Warn("sses: report presence failure", slog.String("channel", channel), slog.Any("error", err))
+			}
+		}
+	}
+	// the left users are broadcast only if not online on the other nodes, checked after reporting.
+	if h.presenceEvents {
+		broadcasts = append(broadcasts, h.localPresenceChanges(ctx, changes, false)...)
+	}
+	for _, c := range broadcasts {
+		eventType := PresenceLeaveEventType
+		if c.Joined {
+			eventType = PresenceJoinEventType
+		}
+		h.broadcastPresence(ctx, eventType, c)
+	}
+}
+
+// localPresenceChanges returns the distinct joined (or left) changes, excluding the users online on the other nodes.
+func (h *Hub) localPresenceChanges(ctx context.Context, changes []PresenceChange, joined bool) []PresenceChange {
+	online := make(map[string][]*Presence) // channel -> 后端的在线用户, 每个channel只查询一次
+	seen := make(map[PresenceChange]struct{})
+	result := make([]PresenceChange, 0, len(changes))
+	for _, c := range changes {
+		if c.Joined != joined {
+			continue
+		}
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = struct{}{}
+		if h.presence != nil {
+			presences, ok := online[c.Channel]
+			if !ok {
+				presences = h.listPresence(ctx, c.Channel)
+				online[c.Channel] = presences
+			}
+			if _, found := slices.BinarySearchFunc(presences, c.UserId, func(p *Presence, userId string) int {
+				return cmp.Compare(p.UserId, userId)
+			}); found {
+				continue
+			}
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// listPresence lists the online users of the channel in the presence backend, nil if failure.
+func (h *Hub) listPresence(ctx context.Context, channel string) []*Presence {
+	presences, err := h.presence.List(ctx, channel)
+	if err != nil {
+		slog.Warn("sses: list presence failure", slog.String("channel", channel), slog.Any("error", err))
+		return nil
+	}
+	return presences
+}
+
+// broadcastPresence broadcasts the presence event, it is not persisted to the store.
+func (h *Hub) broadcastPresence(ctx context.Context, eventType string, c PresenceChange) {
+	err := h.dispatch(ctx, &BrokerMessage{
+		Target:  BrokerTarget_Broadcast,
+		Channel: c.Channel,
+		Events: []*Event{{
+			Event: eventType,
+			Data:  &PresenceEvent{Channel: c.Channel, UserId: c.UserId},
+		}},
+	}, true, false)
+	if err != nil {
+		slog.Warn("sses: broadcast presence event failure", slog.String("channel", c.Channel), slog.Any("error", err))
+	}
+}
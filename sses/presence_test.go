@@ -0,0 +1,239 @@
+package sses
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SessionManager_Presence(t *testing.T) {
+	var changes []PresenceChange
+	sm := NewSessionManager()
+	sm.onPresence = func(cs []PresenceChange) { changes = append(changes, cs...) }
+	newSession := func(userId, sessionId string, at time.Time, channels ...string) *Session {
+		return &Session{
+			UserId:      userId,
+			SessionId:   sessionId,
+			Channel:     channels[0],
+			Channels:    channels,
+			ConnectedAt: at,
+			Message:     make(chan *Event, 1),
+		}
+	}
+	t0 := time.Now()
+	u1s1 := newSession("u1", "s1", t0, "ch1", "ch2")
+	u1s2 := newSession("u1", "s2", t0.Add(time.Second), "ch1")
+	u2s1 := newSession("u2", "s1", t0.Add(2*time.Second), "ch1")
+
+	sm.Add(u1s1)
+	sm.Add(u1s2)
+	sm.Add(u2s1)
+	require.Equal(t, []PresenceChange{
+		{Channel: "ch1", UserId: "u1", Joined: true},
+		{Channel: "ch2", UserId: "u1", Joined: true},
+		{Channel: "ch1", UserId: "u2", Joined: true},
+	}, changes)
+	require.Equal(t, []string{"ch1", "ch2"}, sm.Channels())
+	require.Equal(t, []*Presence{
+		{UserId: "u1", Sessions: 2, ConnectedAt: t0},
+		{UserId: "u2", Sessions: 1, ConnectedAt: t0.Add(2 * time.Second)},
+	}, sm.Presence("ch1"))
+
+	// replace the session, not leave and join again.
+	changes = nil
+	sm.Add(newSession("u2", "s1", t0.Add(3*time.Second), "ch1"))
+	require.Empty(t, changes)
+
+	// the earliest session left, recompute the connected time.
+	sm.Delete(u1s1)
+	require.Equal(t, []PresenceChange{{Channel: "ch2", UserId: "u1", Joined: false}}, changes)
+	require.Equal(t, []*Presence{
+		{UserId: "u1", Sessions: 1, ConnectedAt: t0.Add(time.Second)},
+		{UserId: "u2", Sessions: 1, ConnectedAt: t0.Add(3 * time.Second)},
+	}, sm.Presence("ch1"))
+	require.Empty(t, sm.Presence("ch2"))
+
+	changes = nil
+	sm.DeleteByUserId("u1")
+	require.Equal(t, []PresenceChange{{Channel: "ch1", UserId: "u1", Joined: false}}, changes)
+	require.Equal(t, []string{"ch1"}, sm.Channels())
+
+	changes = nil
+	sm.Close()
+	require.Empty(t, changes)
+	require.Empty(t, sm.Channels())
+}
+
+func Test_MemoryPresence(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	m := NewMemoryPresence()
+	m.now = func() time.Time { return now }
+
+	t0 := now.Add(-time.Minute)
+	require.NoError(t, m.Report(ctx, "n1", "ch1", []*Presence{
+		{UserId: "u1", Sessions: 1, ConnectedAt: t0.Add(time.Second)},
+		{UserId: "u2", Sessions: 2, ConnectedAt: t0},
+	}, time.Second*10))
+	require.NoError(t, m.Report(ctx, "n2", "ch1", []*Presence{
+		{UserId: "u1", Sessions: 2, ConnectedAt: t0},
+	}, time.Second*20))
+	ps, err := m.List(ctx, "ch1")
+	require.NoError(t, err)
+	require.Equal(t, []*Presence{
+		{UserId: "u1", Sessions: 3, ConnectedAt: t0},
+		{UserId: "u2", Sessions: 2, ConnectedAt: t0},
+	}, ps)
+
+	// n1 heartbeat expired
+	now = now.Add(time.Second * 10)
+	ps, err = m.List(ctx, "ch1")
+	require.NoError(t, err)
+	require.Equal(t, []*Presence{{UserId: "u1", Sessions: 2, ConnectedAt: t0}}, ps)
+
+	// n2 removed
+	require.NoError(t, m.Report(ctx, "n2", "ch1", nil, time.Second*20))
+	ps, err = m.List(ctx, "ch1")
+	require.NoError(t, err)
+	require.Empty(t, ps)
+}
+
+func Test_Hub_Presence(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryPresence()
+	broker := NewMemoryBroker()
+	h1 := NewHub(WithBroker(broker), WithPresence(backend), WithPresenceEvents(true))
+	defer h1.Close() // nolint: errcheck
+	h2 := NewHub(WithBroker(broker), WithPresence(backend), WithPresenceEvents(true))
+	defer h2.Close() // nolint: errcheck
+
+	newSession := func(userId string) *Session {
+		return &Session{UserId: userId, SessionId: NewSessionId(), Channel: "ch1", Message: make(chan *Event, 10)}
+	}
+	readPresence := func(ses *Session) (string, PresenceEvent) {
+		select {
+		case e := <-ses.Message:
+			var v PresenceEvent
+			data, err := MarshalData(e.Data)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(data, &v))
+			return e.Event, v
+		case <-time.After(time.Second):
+			require.FailNow(t, "no presence event")
+			return "", PresenceEvent{}
+		}
+	}
+	userIds := func(ps []*Presence) []string {
+		ids := make([]string, 0, len(ps))
+		for _, p := range ps {
+			ids = append(ids, fmt.Sprintf("%s:%d", p.UserId, p.Sessions))
+		}
+		return ids
+	}
+
+	watcher := newSession("w")
+	h1.sessions.Add(watcher)
+	eventType, pe := readPresence(watcher)
+	require.Equal(t, PresenceJoinEventType, eventType)
+	require.Equal(t, PresenceEvent{Channel: "ch1", UserId: "w"}, pe)
+
+	// u1 joins on h2, the watcher on h1 receives it through the broker.
+	u1s1 := newSession("u1")
+	h2.sessions.Add(u1s1)
+	eventType, pe = readPresence(watcher)
+	require.Equal(t, PresenceJoinEventType, eventType)
+	require.Equal(t, PresenceEvent{Channel: "ch1", UserId: "u1"}, pe)
+
+	// presence is reported in the background.
+	requirePresence := func(h *Hub, want ...string) {
+		require.Eventually(t, func() bool {
+			ps, err := h.Presence(ctx, "ch1")
+			require.NoError(t, err)
+			return slices.Equal(want, userIds(ps))
+		}, time.Second, time.Millisecond*10)
+	}
+
+	// u1 also joins on h1, but it is online on h2, no join event.
+	u1s2 := newSession("u1")
+	h1.sessions.Add(u1s2)
+	requirePresence(h1, "u1:2", "w:1")
+	local := h1.sessions.Presence("ch1")
+	require.Equal(t, []string{"u1:1", "w:1"}, userIds(local))
+
+	// u1 leaves h2, still online on h1, no leave event.
+	h2.sessions.Delete(u1s1)
+	requirePresence(h2, "u1:1", "w:1")
+	h1.sessions.Delete(u1s2)
+	eventType, pe = readPresence(watcher)
+	require.Equal(t, PresenceLeaveEventType, eventType)
+	require.Equal(t, PresenceEvent{Channel: "ch1", UserId: "u1"}, pe)
+	select {
+	case e := <-watcher.Message:
+		require.FailNow(t, "unexpected event", e.Event)
+	default:
+	}
+	ps, err := h2.Presence(ctx, "ch1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"w:1"}, userIds(ps))
+
+	// remove the local presence when shutdown
+	require.NoError(t, h1.Close())
+	ps, err = h2.Presence(ctx, "ch1")
+	require.NoError(t, err)
+	require.Empty(t, ps)
+}
+
+func Test_Hub_Presence_Heartbeat(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryPresence()
+	h := NewHub(WithPresence(backend), WithPresenceTTL(time.Millisecond*300))
+	defer h.Close() // nolint: errcheck
+
+	h.sessions.Add(&Session{UserId: "u1", SessionId: NewSessionId(), Channel: "ch1", Message: make(chan *Event, 1)})
+	// the heartbeat keeps the presence alive beyond the ttl.
+	time.Sleep(time.Millisecond * 600)
+	ps, err := h.Presence(ctx, "ch1")
+	require.NoError(t, err)
+	require.Len(t, ps, 1)
+	require.Equal(t, "u1", ps[0].UserId)
+}
+
+// countingPresence counts the List and Report calls of the backend.
+type countingPresence struct {
+	PresenceBackend
+	lists   atomic.Int64
+	reports atomic.Int64
+}
+
+func (c *countingPresence) Report(ctx context.Context, node, channel string, presences []*Presence, ttl time.Duration) error {
+	c.reports.Add(1)
+	return c.PresenceBackend.Report(ctx, node, channel, presences, ttl)
+}
+
+func (c *countingPresence) List(ctx context.Context, channel string) ([]*Presence, error) {
+	c.lists.Add(1)
+	return c.PresenceBackend.List(ctx, channel)
+}
+
+func Test_Hub_Presence_Batch(t *testing.T) {
+	backend := &countingPresence{PresenceBackend: NewMemoryPresence()}
+	h := NewHub(WithPresence(backend), WithPresenceEvents(true))
+	defer h.Close() // nolint: errcheck
+
+	// the backend is listed and reported once per channel in a batch.
+	h.processPresenceChanges(context.Background(), []PresenceChange{
+		{Channel: "ch1", UserId: "u1", Joined: true},
+		{Channel: "ch1", UserId: "u2", Joined: true},
+		{Channel: "ch1", UserId: "u1", Joined: true},
+		{Channel: "ch2", UserId: "u1", Joined: true},
+		{Channel: "ch2", UserId: "u2", Joined: false},
+	})
+	require.Equal(t, int64(3), backend.lists.Load())
+	require.Equal(t, int64(2), backend.reports.Load())
+}
@@ -0,0 +1,27 @@
+local key = KEYS[1]                -- 用户未确认事件的有序集合key, zset: 事件id -> 保存时间
+local events_key = KEYS[2]         -- 用户未确认事件的key, hash: 事件id -> 事件
+local expires = tonumber(ARGV[1])  -- 未确认事件过期时间, 单位: 毫秒
+local count_only = ARGV[2] == '1'  -- 是否只返回数量
+
+local time_res = redis.call('TIME') -- 获取redis节点当前时间.
+local now = tonumber(time_res[1]) * 1000 + math.floor(tonumber(time_res[2]) / 1000) -- 当前时间戳, 单位毫秒
+-- 清除过期的事件
+local expired = redis.call('ZRANGEBYSCORE', key, '-inf', '(' .. (now - expires))
+if #expired > 0 then
+    redis.call('ZREM', key, unpack(expired))
+    redis.call('HDEL', events_key, unpack(expired))
+end
+if count_only then
+    return redis.call('ZCARD', key)
+end
+local ids = redis.call('ZRANGE', key, 0, -1)
+if #ids == 0 then
+    return {}
+end
+local events = {}
+for _, event in ipairs(redis.call('HMGET', events_key, unpack(ids))) do
+    if event then
+        events[#events + 1] = event
+    end
+end
+return events
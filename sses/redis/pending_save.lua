@@ -0,0 +1,27 @@
+local key = KEYS[1]                -- 用户未确认事件的有序集合key, zset: 事件id -> 保存时间
+local events_key = KEYS[2]         -- 用户未确认事件的key, hash: 事件id -> 事件
+local max_pending = tonumber(ARGV[1]) -- 每个用户最多未确认的事件数量
+local expires = tonumber(ARGV[2])  -- 未确认事件过期时间, 单位: 毫秒
+local id = ARGV[3]                 -- 事件id
+local event = ARGV[4]              -- 事件
+
+local time_res = redis.call('TIME') -- 获取redis节点当前时间.
+local now = tonumber(time_res[1]) * 1000 + math.floor(tonumber(time_res[2]) / 1000) -- 当前时间戳, 单位毫秒
+redis.call('ZADD', key, now, id)
+redis.call('HSET', events_key, id, event)
+-- 清除过期的事件
+local expired = redis.call('ZRANGEBYSCORE', key, '-inf', '(' .. (now - expires))
+if #expired > 0 then
+    redis.call('ZREM', key, unpack(expired))
+    redis.call('HDEL', events_key, unpack(expired))
+end
+-- 清除超出数量的最旧事件
+local overflow = redis.call('ZCARD', key) - max_pending
+if overflow > 0 then
+    local oldest = redis.call('ZRANGE', key, 0, overflow - 1)
+    redis.call('ZREM', key, unpack(oldest))
+    redis.call('HDEL', events_key, unpack(oldest))
+end
+redis.call('PEXPIRE', key, expires)
+redis.call('PEXPIRE', events_key, expires)
+return 1
@@ -0,0 +1,17 @@
+local key = KEYS[1]                -- 频道在线状态key, hash: 节点id -> 过期时间|在线信息
+
+local time_res = redis.call('TIME') -- 获取redis节点当前时间.
+local now = tonumber(time_res[1]) * 1000 + math.floor(tonumber(time_res[2]) / 1000) -- 当前时间戳, 单位毫秒
+local result = {}
+local values = redis.call('HGETALL', key)
+for i = 1, #values, 2 do
+    local value = values[i + 1]
+    local sep = string.find(value, '|', 1, true)
+    local expires_at = tonumber(string.sub(value, 1, sep - 1))
+    if expires_at <= now then
+        redis.call('HDEL', key, values[i]) -- 清除心跳过期的节点
+    else
+        result[#result + 1] = string.sub(value, sep + 1)
+    end
+end
+return result
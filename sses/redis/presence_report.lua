@@ -0,0 +1,16 @@
+local key = KEYS[1]                -- 频道在线状态key, hash: 节点id -> 过期时间|在线信息
+local node = ARGV[1]               -- 节点id
+local ttl = tonumber(ARGV[2])      -- 心跳过期时间, 单位: 毫秒
+local presences = ARGV[3]          -- 节点的在线信息, 为空时删除节点的在线信息
+
+if presences == '' then
+    redis.call('HDEL', key, node)
+    return 0
+end
+local time_res = redis.call('TIME') -- 获取redis节点当前时间.
+local now = tonumber(time_res[1]) * 1000 + math.floor(tonumber(time_res[2]) / 1000) -- 当前时间戳, 单位毫秒
+redis.call('HSET', key, node, (now + ttl) .. '|' .. presences)
+if redis.call('PTTL', key) < ttl then
+    redis.call('PEXPIRE', key, ttl)
+end
+return 1
@@ -0,0 +1,18 @@
+package redis
+
+import _ "embed"
+
+//go:embed store_save.lua
+var ScriptStoreSave string
+
+//go:embed presence_report.lua
+var ScriptPresenceReport string
+
+//go:embed presence_list.lua
+var ScriptPresenceList string
+
+//go:embed pending_save.lua
+var ScriptPendingSave string
+
+//go:embed pending_list.lua
+var ScriptPendingList string
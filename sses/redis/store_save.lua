@@ -0,0 +1,26 @@
+local key = KEYS[1]                -- 频道事件流key
+local id_key = KEYS[2]             -- 事件id -> 流id 映射key
+local max_len = tonumber(ARGV[1])  -- 流最大长度(近似), 0 表示不限制
+local max_age = tonumber(ARGV[2])  -- 事件最大保留时间, 单位: 毫秒, 0 表示不限制
+local id_ttl = tonumber(ARGV[3])   -- 事件id映射过期时间, 单位: 毫秒
+-- ARGV[4...] 事件字段
+
+local fields = {}
+for i = 4, #ARGV do
+    fields[#fields + 1] = ARGV[i]
+end
+
+local stream_id
+if max_len > 0 then
+    stream_id = redis.call('XADD', key, 'MAXLEN', '~', max_len, '*', unpack(fields))
+else
+    stream_id = redis.call('XADD', key, '*', unpack(fields))
+end
+if max_age > 0 then
+    local time_res = redis.call('TIME') -- 获取redis节点当前时间.
+    local now = tonumber(time_res[1]) * 1000 + math.floor(tonumber(time_res[2]) / 1000) -- 当前时间戳, 单位毫秒
+    redis.call('XTRIM', key, 'MINID', '~', now - max_age)  -- 清除过期的事件
+    redis.call('PEXPIRE', key, max_age)
+end
+redis.call('SET', id_key, stream_id, 'PX', id_ttl)
+return stream_id
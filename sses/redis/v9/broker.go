@@ -0,0 +1,64 @@
+package v9
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/thinkgos/proc-extra/sses"
+)
+
+var _ sses.Broker = (*RedisBroker)(nil)
+
+// RedisBroker redis Pub/Sub broker for cross-instance fan-out.
+type RedisBroker struct {
+	client  *redis.Client
+	channel string
+}
+
+// NewRedisBroker new redis Pub/Sub broker, the default Pub/Sub channel is `sses:broker`.
+func NewRedisBroker(client *redis.Client) *RedisBroker {
+	return &RedisBroker{
+		client:  client,
+		channel: "sses:broker",
+	}
+}
+
+// SetChannel sets the Pub/Sub channel.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (b *RedisBroker) SetChannel(channel string) *RedisBroker {
+	b.channel = channel
+	return b
+}
+
+// Publish implements [sses.Broker].
+func (b *RedisBroker) Publish(ctx context.Context, msg *sses.BrokerMessage) error {
+	data, err := sses.EncodeBrokerMessage(msg)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+// Subscribe implements [sses.Broker].
+func (b *RedisBroker) Subscribe(ctx context.Context, handler func(*sses.BrokerMessage)) (func(), error) {
+	ps := b.client.Subscribe(ctx, b.channel)
+	// wait for the subscription is confirmed.
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+	ch := ps.Channel()
+	go func() {
+		for m := range ch {
+			msg, err := sses.DecodeBrokerMessage([]byte(m.Payload))
+			if err != nil {
+				slog.Warn("sses: decode broker message failure", slog.Any("error", err))
+				continue
+			}
+			handler(msg)
+		}
+	}()
+	return func() { _ = ps.Close() }, nil
+}
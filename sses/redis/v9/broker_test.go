@@ -0,0 +1,95 @@
+package v9
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thinkgos/proc-extra/sses"
+)
+
+type testServer struct {
+	srv    *httptest.Server
+	cancel context.CancelFunc
+	body   io.ReadCloser
+	r      *bufio.Reader
+}
+
+func newTestServer(t *testing.T, h *sses.Hub, userId, channel string) *testServer {
+	srv := httptest.NewServer(h.Serve(sses.WithServeExtractUserId(func(*http.Request) string { return userId })))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?channel="+channel, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	s := &testServer{srv: srv, cancel: cancel, body: resp.Body, r: bufio.NewReader(resp.Body)}
+	// wait for the session registered
+	require.Equal(t, ": heartbeat\n\n", s.Read(t, len(": heartbeat\n\n")))
+	return s
+}
+
+func (s *testServer) Read(t *testing.T, n int) string {
+	buf := make([]byte, n)
+	_, err := io.ReadFull(s.r, buf)
+	require.NoError(t, err)
+	return string(buf)
+}
+
+func (s *testServer) Close() {
+	s.cancel()
+	_ = s.body.Close()
+	s.srv.Close()
+}
+
+func Test_RedisBroker(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	h1 := sses.NewHub(sses.WithBroker(NewRedisBroker(client)))
+	defer h1.Close() // nolint: errcheck
+	h2 := sses.NewHub(sses.WithBroker(NewRedisBroker(client)))
+	defer h2.Close() // nolint: errcheck
+
+	srv1 := newTestServer(t, h1, "u1", "ch1")
+	defer srv1.Close()
+	srv2 := newTestServer(t, h2, "u1", "ch1")
+	defer srv2.Close()
+
+	type payload struct {
+		Name string `json:"name"`
+	}
+	// both nodes receive the events, the data rendered the same as the origin node.
+	err = h1.Broadcast(context.Background(), "ch1",
+		&sses.Event{Id: "e1", Event: "test", Data: "hello"},
+		&sses.Event{Id: "e2", Event: "test", Data: payload{Name: "world"}},
+	)
+	require.NoError(t, err)
+	want := "id:e1\nevent:test\ndata:hello\n\n" +
+		"id:e2\nevent:test\ndata:{\"name\":\"world\"}\n\n"
+	require.Equal(t, want, srv1.Read(t, len(want)))
+	require.Equal(t, want, srv2.Read(t, len(want)))
+
+	err = h2.Publish(context.Background(), "ch1", "u1", &sses.Event{Id: "e3", Event: "test", Data: []byte("bytes")})
+	require.NoError(t, err)
+	want = "id:e3\nevent:test\ndata:bytes\n\n"
+	require.Equal(t, want, srv1.Read(t, len(want)))
+	require.Equal(t, want, srv2.Read(t, len(want)))
+
+	// the origin node never delivers twice, the next event is the sentinel.
+	time.Sleep(time.Millisecond * 100)
+	err = h1.Broadcast(context.Background(), "ch1", &sses.Event{Id: "e4", Event: "test", Data: "sentinel"})
+	require.NoError(t, err)
+	want = "id:e4\nevent:test\ndata:sentinel\n\n"
+	require.Equal(t, want, srv1.Read(t, len(want)))
+	require.Equal(t, want, srv2.Read(t, len(want)))
+}
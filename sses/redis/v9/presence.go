@@ -0,0 +1,80 @@
+package v9
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/thinkgos/proc-extra/sses"
+	redis_script "github.com/thinkgos/proc-extra/sses/redis"
+)
+
+var _ sses.PresenceBackend = (*RedisPresence)(nil)
+
+// RedisPresence redis presence backend, each node's presence of the channel is a field of the channel hash,
+// it expires with the heartbeat ttl (by redis time).
+type RedisPresence struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisPresence new redis presence backend, the default key prefix is `sses:presence:`.
+func NewRedisPresence(client *redis.Client) *RedisPresence {
+	return &RedisPresence{
+		client:    client,
+		keyPrefix: "sses:presence:",
+	}
+}
+
+// SetKeyPrefix sets the key prefix.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (p *RedisPresence) SetKeyPrefix(keyPrefix string) *RedisPresence {
+	p.keyPrefix = keyPrefix
+	return p
+}
+
+// Report implements [sses.PresenceBackend].
+func (p *RedisPresence) Report(ctx context.Context, node, channel string, presences []*sses.Presence, ttl time.Duration) error {
+	payload := ""
+	if len(presences) > 0 {
+		b, err := json.Marshal(presences)
+		if err != nil {
+			return err
+		}
+		payload = string(b)
+	}
+	return p.client.Eval(ctx,
+		redis_script.ScriptPresenceReport,
+		[]string{p.formatKey(channel)},
+		node,
+		strconv.FormatInt(ttl.Milliseconds(), 10),
+		payload,
+	).Err()
+}
+
+// List implements [sses.PresenceBackend].
+func (p *RedisPresence) List(ctx context.Context, channel string) ([]*sses.Presence, error) {
+	payloads, err := p.client.Eval(ctx,
+		redis_script.ScriptPresenceList,
+		[]string{p.formatKey(channel)},
+	).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	presences := make([]*sses.Presence, 0, len(payloads))
+	for _, payload := range payloads {
+		var ps []*sses.Presence
+		if err := json.Unmarshal([]byte(payload), &ps); err != nil {
+			return nil, err
+		}
+		presences = append(presences, ps...)
+	}
+	return sses.MergePresence(presences), nil
+}
+
+func (p *RedisPresence) formatKey(channel string) string {
+	return p.keyPrefix + "{" + channel + "}"
+}
@@ -0,0 +1,63 @@
+package v9
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thinkgos/proc-extra/sses"
+)
+
+func Test_RedisPresence(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	p := NewRedisPresence(client)
+
+	now := time.Now()
+	mr.SetTime(now)
+	t0 := now.Add(-time.Minute).UTC().Truncate(time.Millisecond)
+	err = p.Report(ctx, "n1", "ch1", []*sses.Presence{
+		{UserId: "u1", Sessions: 1, ConnectedAt: t0.Add(time.Second)},
+		{UserId: "u2", Sessions: 2, ConnectedAt: t0},
+	}, time.Second*10)
+	require.NoError(t, err)
+	err = p.Report(ctx, "n2", "ch1", []*sses.Presence{
+		{UserId: "u1", Sessions: 2, ConnectedAt: t0},
+	}, time.Second*20)
+	require.NoError(t, err)
+
+	ps, err := p.List(ctx, "ch1")
+	require.NoError(t, err)
+	require.Equal(t, []*sses.Presence{
+		{UserId: "u1", Sessions: 3, ConnectedAt: t0},
+		{UserId: "u2", Sessions: 2, ConnectedAt: t0},
+	}, ps)
+
+	// n1 heartbeat expired
+	mr.SetTime(now.Add(time.Second * 10))
+	ps, err = p.List(ctx, "ch1")
+	require.NoError(t, err)
+	require.Equal(t, []*sses.Presence{{UserId: "u1", Sessions: 2, ConnectedAt: t0}}, ps)
+	nodes, err := mr.HKeys("sses:presence:{ch1}")
+	require.NoError(t, err)
+	require.Equal(t, []string{"n2"}, nodes)
+
+	// n2 removed
+	require.NoError(t, p.Report(ctx, "n2", "ch1", nil, time.Second*20))
+	ps, err = p.List(ctx, "ch1")
+	require.NoError(t, err)
+	require.Empty(t, ps)
+
+	// the channel key expires if all nodes stop reporting.
+	require.NoError(t, p.Report(ctx, "n1", "ch2", []*sses.Presence{{UserId: "u1", Sessions: 1, ConnectedAt: t0}}, time.Second*10))
+	mr.FastForward(time.Second * 10)
+	require.False(t, mr.Exists("sses:presence:{ch2}"))
+}
@@ -0,0 +1,359 @@
+package v9
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/thinkgos/proc-extra/sses"
+	redis_script "github.com/thinkgos/proc-extra/sses/redis"
+)
+
+var (
+	_ sses.Store       = (*RedisStore)(nil)
+	_ sses.AckStore    = (*RedisStore)(nil)
+	_ sses.LastIdStore = (*RedisStore)(nil)
+)
+
+// RedisStore redis Streams store, keyed by channel.
+type RedisStore struct {
+	client    *redis.Client
+	keyPrefix string
+	maxLen    int64
+	maxAge    time.Duration
+	idExpires time.Duration
+	// 投递确认
+	pendingKeyPrefix string
+	maxPending       int64
+	pendingExpires   time.Duration
+}
+
+// NewRedisStore new redis Streams store, the default key prefix is `sses:stream:`,
+// keep up to 10000 events per channel. the pending events (see sses.AckStore) are keyed by user,
+// the default key prefix is `sses:pending:`, keep up to 1000 events per user for 24 hours.
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		client:    client,
+		keyPrefix: "sses:stream:",
+		maxLen:    10000,
+		maxAge:    0,
+		idExpires: time.Hour * 24,
+
+		pendingKeyPrefix: "sses:pending:",
+		maxPending:       1000,
+		pendingExpires:   time.Hour * 24,
+	}
+}
+
+// SetKeyPrefix sets the key prefix.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (s *RedisStore) SetKeyPrefix(keyPrefix string) *RedisStore {
+	s.keyPrefix = keyPrefix
+	return s
+}
+
+// SetMaxLen sets the max length (approximately) of each channel stream, 0 means unlimited.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (s *RedisStore) SetMaxLen(n int64) *RedisStore {
+	s.maxLen = max(n, 0)
+	return s
+}
+
+// SetMaxAge sets the max age of the events, 0 means unlimited.
+// the event id mapping expires with the max age too, default 24 hours if the max age is unlimited.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (s *RedisStore) SetMaxAge(d time.Duration) *RedisStore {
+	s.maxAge = max(d, 0)
+	if d > 0 {
+		s.idExpires = d
+	}
+	return s
+}
+
+// SetPendingKeyPrefix sets the key prefix of the pending events.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (s *RedisStore) SetPendingKeyPrefix(keyPrefix string) *RedisStore {
+	s.pendingKeyPrefix = keyPrefix
+	return s
+}
+
+// SetMaxPending sets the max pending events per user, the oldest are dropped beyond it.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (s *RedisStore) SetMaxPending(n int64) *RedisStore {
+	if n > 0 {
+		s.maxPending = n
+	}
+	return s
+}
+
+// SetPendingExpires sets the max age of the pending events.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (s *RedisStore) SetPendingExpires(d time.Duration) *RedisStore {
+	if d > 0 {
+		s.pendingExpires = d
+	}
+	return s
+}
+
+// Save implements [sses.Store].
+func (s *RedisStore) Save(ctx context.Context, channel string, e *sses.Event) error {
+	data, err := sses.MarshalData(e.Data)
+	if err != nil {
+		return err
+	}
+	args := []string{
+		strconv.FormatInt(s.maxLen, 10),
+		strconv.FormatInt(s.maxAge.Milliseconds(), 10),
+		strconv.FormatInt(s.idExpires.Milliseconds(), 10),
+		"id", e.Id,
+		"event", e.Event,
+		"retry", strconv.FormatUint(uint64(e.Retry), 10),
+		"data", string(data),
+	}
+	if len(e.Meta) > 0 {
+		if args, err = appendJSONField(args, "meta", e.Meta); err != nil {
+			return err
+		}
+	}
+	if len(e.Comments) > 0 {
+		if args, err = appendJSONField(args, "comments", e.Comments); err != nil {
+			return err
+		}
+	}
+	if len(e.Fields) > 0 {
+		if args, err = appendJSONField(args, "fields", e.Fields); err != nil {
+			return err
+		}
+	}
+	return s.client.Eval(ctx,
+		redis_script.ScriptStoreSave,
+		[]string{
+			s.formatKey(channel),
+			s.formatIdKey(channel, e.Id),
+		},
+		args,
+	).Err()
+}
+
+// ListByLastId implements [sses.Store].
+// the events after lastId are listed, if lastId is empty, list from the oldest event in the stream,
+// if lastId is not found (expired or trimmed), return sses.ErrGapDetected.
+// if eventType is not empty, only the events of the type are listed.
+func (s *RedisStore) ListByLastId(ctx context.Context, channel, eventType, lastId string, pageSize int) ([]*sses.Event, error) {
+	key := s.formatKey(channel)
+	start := "-"
+	lastStreamId := "" // 最后事件的stream id, 从它开始(包含)查询, 以检查它是否已被裁剪
+	if lastId != "" {
+		streamId, err := s.client.Get(ctx, s.formatIdKey(channel, lastId)).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, sses.ErrGapDetected
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, lastStreamId = streamId, streamId
+	}
+	events := make([]*sses.Event, 0, pageSize)
+	for len(events) < pageSize {
+		msgs, err := s.client.XRangeN(ctx, key, start, "+", int64(pageSize)).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			start = "(" + msg.ID
+			if lastStreamId != "" {
+				if msg.ID != lastStreamId {
+					return nil, sses.ErrGapDetected
+				}
+				lastStreamId = ""
+				continue
+			}
+			e := decodeEvent(msg.Values)
+			if eventType != "" && e.Event != eventType {
+				continue
+			}
+			events = append(events, e)
+			if len(events) >= pageSize {
+				break
+			}
+		}
+		if len(msgs) < pageSize {
+			break
+		}
+	}
+	if lastStreamId != "" { // 最后事件及之后的事件均已被裁剪
+		return nil, sses.ErrGapDetected
+	}
+	return events, nil
+}
+
+// LastId implements [sses.LastIdStore].
+func (s *RedisStore) LastId(ctx context.Context, channel string) (string, error) {
+	msgs, err := s.client.XRevRangeN(ctx, s.formatKey(channel), "+", "-", 1).Result()
+	if err != nil || len(msgs) == 0 {
+		return "", err
+	}
+	return decodeEvent(msgs[0].Values).Id, nil
+}
+
+// pendingEvent the pending event saved in the hash.
+type pendingEvent struct {
+	Channel  string            `json:"channel"`
+	Id       string            `json:"id"`
+	Event    string            `json:"event"`
+	Retry    uint              `json:"retry,omitempty"`
+	Data     string            `json:"data"`
+	Meta     map[string]string `json:"meta,omitempty"`
+	Comments []string          `json:"comments,omitempty"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// SavePending implements [sses.AckStore].
+func (s *RedisStore) SavePending(ctx context.Context, userId, channel string, e *sses.Event) error {
+	data, err := sses.MarshalData(e.Data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(&pendingEvent{
+		Channel:  channel,
+		Id:       e.Id,
+		Event:    e.Event,
+		Retry:    e.Retry,
+		Data:     string(data),
+		Meta:     e.Meta,
+		Comments: e.Comments,
+		Fields:   e.Fields,
+	})
+	if err != nil {
+		return err
+	}
+	return s.client.Eval(ctx,
+		redis_script.ScriptPendingSave,
+		[]string{
+			s.formatPendingKey(userId),
+			s.formatPendingEventsKey(userId),
+		},
+		strconv.FormatInt(s.maxPending, 10),
+		strconv.FormatInt(s.pendingExpires.Milliseconds(), 10),
+		e.Id,
+		string(b),
+	).Err()
+}
+
+// ListPending implements [sses.AckStore].
+func (s *RedisStore) ListPending(ctx context.Context, userId string) ([]*sses.Event, error) {
+	payloads, err := s.client.Eval(ctx,
+		redis_script.ScriptPendingList,
+		[]string{
+			s.formatPendingKey(userId),
+			s.formatPendingEventsKey(userId),
+		},
+		strconv.FormatInt(s.pendingExpires.Milliseconds(), 10),
+		"0",
+	).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*sses.Event, 0, len(payloads))
+	for _, payload := range payloads {
+		var pe pendingEvent
+		if err = json.Unmarshal([]byte(payload), &pe); err != nil {
+			return nil, err
+		}
+		events = append(events, &sses.Event{
+			Channel:  pe.Channel,
+			Id:       pe.Id,
+			Event:    pe.Event,
+			Retry:    pe.Retry,
+			Data:     []byte(pe.Data),
+			Meta:     pe.Meta,
+			Comments: pe.Comments,
+			Fields:   pe.Fields,
+		})
+	}
+	return events, nil
+}
+
+// Ack implements [sses.AckStore].
+func (s *RedisStore) Ack(ctx context.Context, userId string, eventIds ...string) (int, error) {
+	if len(eventIds) == 0 {
+		return 0, nil
+	}
+	members := make([]any, 0, len(eventIds))
+	for _, id := range eventIds {
+		members = append(members, id)
+	}
+	pipe := s.client.TxPipeline()
+	removed := pipe.ZRem(ctx, s.formatPendingKey(userId), members...)
+	pipe.HDel(ctx, s.formatPendingEventsKey(userId), eventIds...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(removed.Val()), nil
+}
+
+// PendingCount implements [sses.AckStore].
+func (s *RedisStore) PendingCount(ctx context.Context, userId string) (int, error) {
+	n, err := s.client.Eval(ctx,
+		redis_script.ScriptPendingList,
+		[]string{
+			s.formatPendingKey(userId),
+			s.formatPendingEventsKey(userId),
+		},
+		strconv.FormatInt(s.pendingExpires.Milliseconds(), 10),
+		"1",
+	).Int()
+	return n, err
+}
+
+func (s *RedisStore) formatKey(channel string) string {
+	return s.keyPrefix + "{" + channel + "}"
+}
+
+func (s *RedisStore) formatIdKey(channel, id string) string {
+	return s.keyPrefix + "{" + channel + "}:id:" + id
+}
+
+func (s *RedisStore) formatPendingKey(userId string) string {
+	return s.pendingKeyPrefix + "{" + userId + "}"
+}
+
+func (s *RedisStore) formatPendingEventsKey(userId string) string {
+	return s.pendingKeyPrefix + "{" + userId + "}:events"
+}
+
+func appendJSONField(args []string, name string, v any) ([]string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(args, name, string(b)), nil
+}
+
+func decodeEvent(values map[string]any) *sses.Event {
+	str := func(k string) string {
+		v, _ := values[k].(string)
+		return v
+	}
+	retry, _ := strconv.ParseUint(str("retry"), 10, 64)
+	e := &sses.Event{
+		Event: str("event"),
+		Id:    str("id"),
+		Retry: uint(retry),
+		Data:  []byte(str("data")),
+	}
+	if meta := str("meta"); meta != "" {
+		_ = json.Unmarshal([]byte(meta), &e.Meta)
+	}
+	if comments := str("comments"); comments != "" {
+		_ = json.Unmarshal([]byte(comments), &e.Comments)
+	}
+	if fields := str("fields"); fields != "" {
+		_ = json.Unmarshal([]byte(fields), &e.Fields)
+	}
+	return e
+}
@@ -0,0 +1,208 @@
+package v9
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thinkgos/proc-extra/sses"
+)
+
+func eventIds(events []*sses.Event) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
+
+func Test_RedisStore(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	t.Run("list by last id", func(t *testing.T) {
+		s := NewRedisStore(client)
+		for i := range 10 {
+			eventType := "message"
+			if i%2 == 1 {
+				eventType = "other"
+			}
+			err := s.Save(ctx, "ch1", &sses.Event{
+				Id:    "e" + strconv.Itoa(i),
+				Event: eventType,
+				Retry: 1000,
+				Data:  map[string]int{"seq": i},
+			})
+			require.NoError(t, err)
+		}
+		// metadata saved
+		err = s.Save(ctx, "ch1", &sses.Event{Id: "m1", Event: "meta", Data: "hello", Meta: map[string]string{"region": "eu"}})
+		require.NoError(t, err)
+		events, err := s.ListByLastId(ctx, "ch1", "meta", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []*sses.Event{{Id: "m1", Event: "meta", Data: []byte("hello"), Meta: map[string]string{"region": "eu"}}}, events)
+		// comments and extra fields saved
+		err = s.Save(ctx, "ch3", &sses.Event{Id: "x1", Event: "extra", Data: "hello", Comments: []string{"c"}, Fields: map[string]string{"f": "v"}})
+		require.NoError(t, err)
+		events, err = s.ListByLastId(ctx, "ch3", "", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []*sses.Event{{Id: "x1", Event: "extra", Data: []byte("hello"), Comments: []string{"c"}, Fields: map[string]string{"f": "v"}}}, events)
+		// another channel not affected
+		err = s.Save(ctx, "ch2", &sses.Event{Id: "x1", Event: "message", Data: "hello"})
+		require.NoError(t, err)
+
+		events, err = s.ListByLastId(ctx, "ch1", "", "e3", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e4", "e5", "e6", "e7", "e8", "e9", "m1"}, eventIds(events))
+		require.Equal(t, &sses.Event{Id: "e4", Event: "message", Retry: 1000, Data: []byte(`{"seq":4}`)}, events[0])
+
+		// filter by event type, and paging
+		events, err = s.ListByLastId(ctx, "ch1", "message", "e1", 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e2", "e4"}, eventIds(events))
+		events, err = s.ListByLastId(ctx, "ch1", "message", "e4", 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e6", "e8"}, eventIds(events))
+		events, err = s.ListByLastId(ctx, "ch1", "message", "e8", 2)
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		// empty last id, list from the oldest
+		events, err = s.ListByLastId(ctx, "ch1", "other", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e1", "e3", "e5", "e7", "e9"}, eventIds(events))
+		// unknown last id
+		_, err = s.ListByLastId(ctx, "ch1", "other", "unknown", 100)
+		require.ErrorIs(t, err, sses.ErrGapDetected)
+
+		events, err = s.ListByLastId(ctx, "ch2", "", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []*sses.Event{{Id: "x1", Event: "message", Data: []byte("hello")}}, events)
+
+		// the last event id
+		id, err := s.LastId(ctx, "ch1")
+		require.NoError(t, err)
+		require.Equal(t, "m1", id)
+		id, err = s.LastId(ctx, "unknown")
+		require.NoError(t, err)
+		require.Empty(t, id)
+	})
+
+	t.Run("trim by max len", func(t *testing.T) {
+		s := NewRedisStore(client).SetMaxLen(3)
+		for i := range 5 {
+			err := s.Save(ctx, "ch3", &sses.Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: "hello"})
+			require.NoError(t, err)
+		}
+		events, err := s.ListByLastId(ctx, "ch3", "", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e2", "e3", "e4"}, eventIds(events))
+		events, err = s.ListByLastId(ctx, "ch3", "", "e2", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e3", "e4"}, eventIds(events))
+		// the last event trimmed, but the id mapping not expired.
+		_, err = s.ListByLastId(ctx, "ch3", "", "e1", 100)
+		require.ErrorIs(t, err, sses.ErrGapDetected)
+	})
+
+	t.Run("trim by max age", func(t *testing.T) {
+		s := NewRedisStore(client).SetKeyPrefix("sses:age:").SetMaxLen(0).SetMaxAge(time.Minute)
+		now := time.Now()
+		for i := range 5 {
+			mr.SetTime(now.Add(time.Duration(i) * 20 * time.Second))
+			err := s.Save(ctx, "ch4", &sses.Event{Id: "e" + strconv.Itoa(i), Event: "message", Data: "hello"})
+			require.NoError(t, err)
+		}
+		// now+80s, events older than now+20s trimmed.
+		events, err := s.ListByLastId(ctx, "ch4", "", "", 100)
+		require.NoError(t, err)
+		require.Equal(t, []string{"e1", "e2", "e3", "e4"}, eventIds(events))
+
+		// the stream and id mapping expires with max age.
+		mr.FastForward(time.Minute * 2)
+		_, err = s.ListByLastId(ctx, "ch4", "", "e2", 100)
+		require.ErrorIs(t, err, sses.ErrGapDetected)
+	})
+	t.Run("pending", func(t *testing.T) {
+		s := NewRedisStore(client).SetMaxPending(3).SetPendingExpires(time.Minute)
+		now := time.Now()
+		for i := range 4 {
+			mr.SetTime(now.Add(time.Duration(i) * 10 * time.Second))
+			err := s.SavePending(ctx, "u1", "ch"+strconv.Itoa(i), &sses.Event{
+				Id:       "p" + strconv.Itoa(i),
+				Event:    "order",
+				Data:     map[string]int{"seq": i},
+				Comments: []string{"c"},
+			})
+			require.NoError(t, err)
+		}
+		// the oldest is dropped beyond the max pending.
+		events, err := s.ListPending(ctx, "u1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"p1", "p2", "p3"}, eventIds(events))
+		require.Equal(t, &sses.Event{Channel: "ch1", Id: "p1", Event: "order", Data: []byte(`{"seq":1}`), Comments: []string{"c"}}, events[0])
+
+		n, err := s.Ack(ctx, "u1", "p2", "unknown")
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		n, err = s.PendingCount(ctx, "u1")
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+		n, err = s.PendingCount(ctx, "u2")
+		require.NoError(t, err)
+		require.Zero(t, n)
+
+		// expired with the pending expires.
+		mr.SetTime(now.Add(time.Second * 75))
+		events, err = s.ListPending(ctx, "u1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"p3"}, eventIds(events))
+	})
+}
+
+func Test_RedisStore_Resend(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	h := sses.NewHub(sses.WithStore(NewRedisStore(client)))
+	defer h.Close() // nolint: errcheck
+
+	err = h.Broadcast(context.Background(), "ch1",
+		&sses.Event{Id: "e1", Event: "message", Data: "one"},
+		&sses.Event{Id: "e2", Event: "other", Data: "two"},
+		&sses.Event{Id: "e3", Event: "message", Data: "three"},
+	)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(h.Serve(sses.WithServeExtractUserId(func(*http.Request) string { return "u1" })))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?channel=ch1", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "e1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint: errcheck
+
+	// resend the persisted events of the default event type, then the heartbeat.
+	want := "id:e3\nevent:message\ndata:three\n\n: heartbeat\n\n"
+	buf := make([]byte, len(want))
+	_, err = io.ReadFull(resp.Body, buf)
+	require.NoError(t, err)
+	require.Equal(t, want, string(buf))
+}
@@ -4,6 +4,10 @@ import (
 	"maps"
 	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thinkgos/proc/topic"
 )
 
 // Session
@@ -11,16 +15,52 @@ type Session struct {
 	UserId    string // user id, 所属用户id
 	SessionId string // session id, 会话id
 	Channel   string // channel, 会话订阅的频道
+	// channels, 会话订阅的频道列表, 支持通配符(`+` 匹配单层, `#` 匹配多层), 为空时仅订阅 Channel
+	Channels []string
+	// filter, 事件过滤器, 仅推送过滤器接受的事件, 为空时不过滤
+	Filter EventFilter
+	// connected at, 会话连接时间, 为空时添加会话时设置为当前时间
+	ConnectedAt time.Time
 	//* NOTE: 这个chan会被关闭, 在写时要注意处理panic
 	Message chan *Event // message chan, 消息通道.
+
+	mu       sync.Mutex   // 缓冲区满时, 丢弃最旧/合并事件的锁
+	retrying atomic.Int32 // 进行中的重试推送数量
+}
+
+// Subscriptions returns the channels (may be wildcard) the session subscribes.
+func (s *Session) Subscriptions() []string {
+	if len(s.Channels) == 0 {
+		return []string{s.Channel}
+	}
+	return s.Channels
+}
+
+// Subscribes reports whether the session subscribes the channel.
+func (s *Session) Subscribes(channel string) bool {
+	return slices.ContainsFunc(s.Subscriptions(), func(filter string) bool {
+		return MatchChannel(filter, channel)
+	})
+}
+
+// Accept reports whether the event is accepted by the session filter.
+func (s *Session) Accept(e *Event) bool {
+	return s.Filter == nil || s.Filter(e)
 }
 
 // SessionManager session manager
 type SessionManager struct {
 	locker    sync.RWMutex                     // 会话锁
+	closed    bool                             // 是否已关闭, 关闭后新增的会话会被立即关闭
 	total     int                              // session total, 会话总数
 	byUserId  map[string][]*Session            // userId -> []*Session, 用户的会话列表
 	byChannel map[string]map[*Session]struct{} // channel -> map[*Session]struct{}// 订阅了channel的会话集合
+	wildcards *topic.Tree                      // wildcard channel -> *Session, 订阅了通配符channel的会话
+	presence  map[string]map[string]*Presence  // channel -> userId -> 在线信息
+	changes   []PresenceChange                 // 本次操作的在线状态变更, 解锁后通知
+	limits    sessionLimits                    // 会话数量限制
+	// on presence change, 用户在channel上线或下线时的回调, 在解锁后调用
+	onPresence func([]PresenceChange)
 }
 
 // NewSessionManager 创建会话管理
@@ -30,6 +70,8 @@ func NewSessionManager() *SessionManager {
 		total:     0,
 		byUserId:  make(map[string][]*Session),
 		byChannel: make(map[string]map[*Session]struct{}),
+		wildcards: topic.NewStandardTree(),
+		presence:  make(map[string]map[string]*Presence),
 	}
 }
 
@@ -40,59 +82,76 @@ func (sm *SessionManager) SessionTotal() int {
 	return sm.total
 }
 
-// Total 获取会话总数
+// SessionTotalByChannel 获取订阅了channel(可以是通配符)的会话总数
 func (sm *SessionManager) SessionTotalByChannel(channel string) int {
 	sm.locker.RLock()
 	defer sm.locker.RUnlock()
 	return len(sm.byChannel[channel])
 }
 
-// Add 添加会话
+// Add 添加会话, 相同sessionId的旧会话会被替换.
+// 超出会话数量限制时, 按限制策略淘汰最旧的会话或拒绝并关闭该会话, 需要获知拒绝原因时使用 TryAdd.
 func (sm *SessionManager) Add(ses *Session) {
+	if err := sm.TryAdd(ses); err != nil {
+		close(ses.Message) // 被拒绝, 关闭会话
+	}
+}
+
+// TryAdd 添加会话, 相同sessionId的旧会话会被替换.
+// 超出会话数量限制时, 按限制策略拒绝(返回 ErrTooManySessions, 会话未添加也未关闭)或淘汰最旧的会话.
+func (sm *SessionManager) TryAdd(ses *Session) error {
+	defer sm.notifyPresence()
 	sm.locker.Lock()
 	defer sm.locker.Unlock()
+	if sm.closed {
+		close(ses.Message) // 已关闭, 关闭会话
+		return nil
+	}
 	sessions := sm.byUserId[ses.UserId]
 	idx := slices.IndexFunc(sessions, func(v *Session) bool {
 		return v.SessionId == ses.SessionId
 	})
-	//* 如果找到旧会话, 则关闭旧会话
+	var found *Session
 	if idx >= 0 {
+		found = sessions[idx]
+	}
+	if ses.ConnectedAt.IsZero() {
+		ses.ConnectedAt = time.Now()
+	}
+	//* 检查会话数量限制
+	if err := sm.admit(ses, found); err != nil {
+		return err
+	}
+	//* 如果找到旧会话, 则关闭旧会话
+	if found != nil {
+		sessions = sm.byUserId[ses.UserId]
+		idx = slices.Index(sessions, found)
 		sm.total--
-		found := sessions[idx]
 		// userId -> 删除对应sessionId的session
 		sessions = slices.Delete(sessions, idx, idx+1)
+		sm.byUserId[ses.UserId] = sessions
 		// channel -> 删除对应session
-		cs, ok := sm.byChannel[found.Channel]
-		if ok {
-			if len(cs) == 1 {
-				delete(sm.byChannel, found.Channel)
-			} else {
-				delete(cs, found)
-			}
-		}
+		sm.unindex(found)
 		close(found.Message) // 关闭会话
 	}
 	//* 添加新的会话
 	sm.total++
 	// userId -> 增加新的session
-	sessions = append(sessions, ses)
+	sessions = append(sm.byUserId[ses.UserId], ses)
 	sm.byUserId[ses.UserId] = sessions
 	// channel -> 增加新的session
-	cs, ok := sm.byChannel[ses.Channel]
-	if !ok {
-		cs = make(map[*Session]struct{})
-		sm.byChannel[ses.Channel] = cs
-	}
-	cs[ses] = struct{}{}
+	sm.index(ses)
+	return nil
 }
 
 // Delete 删除会话
 func (sm *SessionManager) Delete(ses *Session) {
+	defer sm.notifyPresence()
 	sm.locker.Lock()
 	defer sm.locker.Unlock()
 	sessions := sm.byUserId[ses.UserId]
 	idx := slices.IndexFunc(sessions, func(v *Session) bool {
-		return v.SessionId == ses.SessionId
+		return v == ses
 	})
 	if idx >= 0 {
 		//* 找到对应会话
@@ -106,22 +165,15 @@ func (sm *SessionManager) Delete(ses *Session) {
 			sm.byUserId[ses.UserId] = sessions
 		}
 		// channel -> 删除对应的session
-		sp, ok := sm.byChannel[found.Channel]
-		if ok {
-			if len(sp) == 1 {
-				delete(sm.byChannel, found.Channel)
-			} else {
-				delete(sp, found)
-			}
-		}
+		sm.unindex(found)
 		close(found.Message) // 关闭会话
-	} else {
-		close(ses.Message) // 关闭会话
 	}
+	//* 未找到, 会话已被替换或关闭
 }
 
 // DeleteByUserId 删除用户的所有会话
 func (sm *SessionManager) DeleteByUserId(userId string) {
+	defer sm.notifyPresence()
 	sm.locker.Lock()
 	defer sm.locker.Unlock()
 	sessions, ok := sm.byUserId[userId]
@@ -133,25 +185,201 @@ func (sm *SessionManager) DeleteByUserId(userId string) {
 	delete(sm.byUserId, userId)
 	// channel -> 删除对应的session
 	for _, v := range sessions {
-		sp, ok := sm.byChannel[v.Channel]
+		sm.unindex(v)
+		close(v.Message) // 关闭会话
+	}
+}
+
+// Close 关闭所有会话, 关闭后新增的会话会被立即关闭
+func (sm *SessionManager) Close() {
+	sm.locker.Lock()
+	defer sm.locker.Unlock()
+	if sm.closed {
+		return
+	}
+	sm.closed = true
+	for _, sessions := range sm.byUserId {
+		for _, v := range sessions {
+			close(v.Message) // 关闭会话
+		}
+	}
+	sm.total = 0
+	sm.byUserId = make(map[string][]*Session)
+	sm.byChannel = make(map[string]map[*Session]struct{})
+	sm.wildcards.Reset()
+	sm.presence = make(map[string]map[string]*Presence)
+}
+
+// index 增加会话订阅的channel索引
+func (sm *SessionManager) index(ses *Session) {
+	for _, channel := range ses.Subscriptions() {
+		cs, ok := sm.byChannel[channel]
+		if !ok {
+			cs = make(map[*Session]struct{})
+			sm.byChannel[channel] = cs
+		}
+		cs[ses] = struct{}{}
+		if IsWildcardChannel(channel) {
+			sm.wildcards.Add(channel, ses)
+		}
+		sm.join(channel, ses)
+	}
+}
+
+// unindex 删除会话订阅的channel索引
+func (sm *SessionManager) unindex(ses *Session) {
+	for _, channel := range ses.Subscriptions() {
+		cs, ok := sm.byChannel[channel]
 		if ok {
-			if len(sp) == 1 {
-				delete(sm.byChannel, v.Channel)
+			if len(cs) == 1 {
+				delete(sm.byChannel, channel)
 			} else {
-				delete(sp, v)
+				delete(cs, ses)
 			}
 		}
-		close(v.Message) // 关闭会话
+		if IsWildcardChannel(channel) {
+			sm.wildcards.Remove(channel, ses)
+		}
+		sm.leave(channel, ses)
 	}
 }
 
+// join 增加会话在channel上的在线信息
+func (sm *SessionManager) join(channel string, ses *Session) {
+	ps, ok := sm.presence[channel]
+	if !ok {
+		ps = make(map[string]*Presence)
+		sm.presence[channel] = ps
+	}
+	p, ok := ps[ses.UserId]
+	if !ok {
+		p = &Presence{UserId: ses.UserId, ConnectedAt: ses.ConnectedAt}
+		ps[ses.UserId] = p
+		sm.changes = append(sm.changes, PresenceChange{Channel: channel, UserId: ses.UserId, Joined: true})
+	}
+	p.Sessions++
+	if ses.ConnectedAt.Before(p.ConnectedAt) {
+		p.ConnectedAt = ses.ConnectedAt
+	}
+}
+
+// leave 减少会话在channel上的在线信息
+func (sm *SessionManager) leave(channel string, ses *Session) {
+	p, ok := sm.presence[channel][ses.UserId]
+	if !ok {
+		return
+	}
+	p.Sessions--
+	if p.Sessions > 0 {
+		// 重新计算剩余会话的最早连接时间
+		p.ConnectedAt = time.Time{}
+		for _, v := range sm.byUserId[ses.UserId] {
+			if v != ses && slices.Contains(v.Subscriptions(), channel) &&
+				(p.ConnectedAt.IsZero() || v.ConnectedAt.Before(p.ConnectedAt)) {
+				p.ConnectedAt = v.ConnectedAt
+			}
+		}
+		return
+	}
+	if len(sm.presence[channel]) == 1 {
+		delete(sm.presence, channel)
+	} else {
+		delete(sm.presence[channel], ses.UserId)
+	}
+	sm.changes = append(sm.changes, PresenceChange{Channel: channel, UserId: ses.UserId, Joined: false})
+}
+
+// notifyPresence 通知本次操作的在线状态变更, 同一用户在同一channel上的下线又上线(如替换会话)相互抵消.
+func (sm *SessionManager) notifyPresence() {
+	sm.locker.Lock()
+	changes := sm.changes
+	sm.changes = nil
+	sm.locker.Unlock()
+	if sm.onPresence == nil || len(changes) == 0 {
+		return
+	}
+	type key struct{ channel, userId string }
+	net := make(map[key]int, len(changes))
+	for _, c := range changes {
+		if c.Joined {
+			net[key{c.Channel, c.UserId}]++
+		} else {
+			net[key{c.Channel, c.UserId}]--
+		}
+	}
+	changes = slices.DeleteFunc(changes, func(c PresenceChange) bool {
+		k := key{c.Channel, c.UserId}
+		n, ok := net[k]
+		delete(net, k) // 仅保留第一次出现的变更
+		return !ok || n == 0
+	})
+	if len(changes) > 0 {
+		sm.onPresence(changes)
+	}
+}
+
+// Presence 获取订阅了channel(可以是通配符)的在线用户, 按用户id排序
+func (sm *SessionManager) Presence(channel string) []*Presence {
+	sm.locker.RLock()
+	defer sm.locker.RUnlock()
+	ps := make([]*Presence, 0, len(sm.presence[channel]))
+	for _, p := range sm.presence[channel] {
+		v := *p
+		ps = append(ps, &v)
+	}
+	slices.SortFunc(ps, comparePresence)
+	return ps
+}
+
+// Channels 获取有在线用户的channel(可以是通配符)列表
+func (sm *SessionManager) Channels() []string {
+	sm.locker.RLock()
+	defer sm.locker.RUnlock()
+	return slices.Sorted(maps.Keys(sm.presence))
+}
+
+// channelStat 订阅了channel的会话数量及缓冲的事件数量
+type channelStat struct {
+	sessions int
+	depth    int
+}
+
+// channelStats 获取订阅的channel(可以是通配符)的会话数量及缓冲的事件数量
+func (sm *SessionManager) channelStats() map[string]channelStat {
+	sm.locker.RLock()
+	defer sm.locker.RUnlock()
+	stats := make(map[string]channelStat, len(sm.byChannel))
+	for channel, sessions := range sm.byChannel {
+		st := channelStat{sessions: len(sessions)}
+		for ses := range sessions {
+			st.depth += len(ses.Message)
+		}
+		stats[channel] = st
+	}
+	return stats
+}
+
+// CollectAll 获取所有会话
+func (sm *SessionManager) CollectAll() []*Session {
+	sm.locker.RLock()
+	defer sm.locker.RUnlock()
+	sess := make([]*Session, 0, sm.total)
+	for _, sessions := range sm.byUserId {
+		sess = append(sess, sessions...)
+	}
+	return sess
+}
+
 // Collect 获取channel的所有会话
 func (sm *SessionManager) Collect(channel string) []*Session {
 	sm.locker.RLock()
 	defer sm.locker.RUnlock()
-	sessions, ok := sm.byChannel[channel]
-	if !ok {
-		return []*Session{}
+	sessions := maps.Clone(sm.byChannel[channel])
+	if sessions == nil {
+		sessions = make(map[*Session]struct{})
+	}
+	for _, v := range sm.wildcards.Match(channel) {
+		sessions[v.(*Session)] = struct{}{}
 	}
 	return slices.Collect(maps.Keys(sessions))
 }
@@ -166,7 +394,7 @@ func (sm *SessionManager) CollectForUser(channel, userId string) []*Session {
 	}
 	sess := make([]*Session, 0, len(sessions))
 	for _, v := range sessions {
-		if v.Channel == channel {
+		if v.Subscribes(channel) {
 			sess = append(sess, v)
 		}
 	}
@@ -183,7 +411,7 @@ func (sm *SessionManager) CollectForUserSession(channel, userId, sessionId strin
 	}
 	sess := make([]*Session, 0, len(sessions))
 	for _, v := range sessions {
-		if v.Channel == channel && v.SessionId == sessionId {
+		if v.SessionId == sessionId && v.Subscribes(channel) {
 			sess = append(sess, v)
 		}
 	}
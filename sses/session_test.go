@@ -188,3 +188,44 @@ func Test_SessionManager_Delete_SessionNotFound(t *testing.T) {
 	sm.Delete(s1)
 	require.Equal(t, 0, sm.SessionTotal())
 }
+
+func Test_SessionManager_MultiChannel(t *testing.T) {
+	sm := NewSessionManager()
+	s1 := &Session{
+		UserId:    "u1",
+		SessionId: NewSessionId(),
+		Channel:   "ch1",
+		Channels:  []string{"ch1", "orders/+/status"},
+		Message:   make(chan *Event, 1),
+	}
+	s2 := &Session{
+		UserId:    "u2",
+		SessionId: NewSessionId(),
+		Channel:   "orders/#",
+		Message:   make(chan *Event, 1),
+	}
+	s3 := &Session{
+		UserId:    "u1",
+		SessionId: NewSessionId(),
+		Channel:   "orders/1/status",
+		Message:   make(chan *Event, 1),
+	}
+	sm.Add(s1)
+	sm.Add(s2)
+	sm.Add(s3)
+
+	require.ElementsMatch(t, []*Session{s1}, sm.Collect("ch1"))
+	require.ElementsMatch(t, []*Session{s1, s2, s3}, sm.Collect("orders/1/status"))
+	require.ElementsMatch(t, []*Session{s2}, sm.Collect("orders/1/other"))
+	require.ElementsMatch(t, []*Session{s1, s3}, sm.CollectForUser("orders/1/status", "u1"))
+	require.ElementsMatch(t, []*Session{s1}, sm.CollectForUser("orders/2/status", "u1"))
+	require.ElementsMatch(t, []*Session{s1}, sm.CollectForUserSession("orders/2/status", "u1", s1.SessionId))
+	require.Equal(t, 1, sm.SessionTotalByChannel("orders/+/status"))
+
+	sm.Delete(s1)
+	require.Empty(t, sm.Collect("ch1"))
+	require.ElementsMatch(t, []*Session{s2, s3}, sm.Collect("orders/1/status"))
+	sm.DeleteByUserId("u2")
+	require.ElementsMatch(t, []*Session{s3}, sm.Collect("orders/1/status"))
+	require.Equal(t, 1, sm.SessionTotal())
+}
@@ -0,0 +1,125 @@
+package sses
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrHubClosed the hub has been closed.
+var ErrHubClosed = errors.New("sses: hub closed")
+
+// ShutdownEventType is the final event type sent to every session when the hub shutdown,
+// the event carries the `retry:` hint, the client reconnects after it (such as to another instance).
+const ShutdownEventType = "shutdown"
+
+// Shutdown gracefully shuts down the hub, all within the context deadline:
+//   - stop accepting new Serve connections and new pushes (return ErrHubClosed).
+//   - drain the in-flight pushes.
+//   - send a final event with the `retry:` hint to every session, after all the pushed events,
+//     the oldest buffered event is dropped if the session buffer is still full when the deadline exceeded.
+//   - close all sessions, remove the local presence from the presence backend,
+//     and wait for the Serve connections to finish.
+//
+// it returns the context error if the deadline exceeded before finishing,
+// the sessions are closed whatever.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	return h.shutdown(ctx, true)
+}
+
+// Close immediately closes the hub, it aborts the in-flight pushes, sends the final event (dropping
+// the oldest buffered event if the buffer is full) but does not wait for the Serve connections.
+// use Shutdown for gracefully shutdown.
+func (h *Hub) Close() error {
+	return h.shutdown(context.Background(), false)
+}
+
+func (h *Hub) shutdown(ctx context.Context, graceful bool) error {
+	if !h.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	h.unsubscribe()
+
+	var err error
+	if graceful {
+		err = h.pending.Wait(ctx)
+	}
+	// abort the remaining pushes, they return immediately, then close the sessions safely.
+	close(h.done)
+	_ = h.pending.Wait(context.Background())
+	final := &Event{Event: ShutdownEventType, Retry: uint(h.shutdownRetry.Milliseconds()), Data: "shutdown"}
+	var wait <-chan struct{} = h.done // closed, never wait
+	if graceful {
+		wait = ctx.Done()
+	}
+	for _, ses := range h.sessions.CollectAll() {
+		pushFinal(ses, final, wait)
+	}
+	h.sessions.Close()
+	h.closeTelemetry()
+	if h.presence != nil {
+		// remove the local presence from the backend
+		h.refreshPresence(ctx)
+	}
+	if graceful && err == nil {
+		err = h.serving.Wait(ctx)
+	}
+	return err
+}
+
+// pushFinal push the final event, wait for the buffer space until wait closed,
+// then push it like pushEvicted, the session may have been closed by its connection.
+func pushFinal(ses *Session, e *Event, wait <-chan struct{}) {
+	defer func() {
+		_ = recover() // the session has been closed
+	}()
+	select {
+	case ses.Message <- e:
+		return
+	case <-wait:
+	}
+	pushEvicted(ses, e)
+}
+
+// pendingGroup counts the pending tasks, like sync.WaitGroup, but it can be waited with context,
+// and Add is allowed to be called concurrently with Wait.
+type pendingGroup struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{}
+}
+
+func (g *pendingGroup) Add() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.n == 0 {
+		g.idle = make(chan struct{})
+	}
+	g.n++
+}
+
+func (g *pendingGroup) Done() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.n--
+	if g.n == 0 {
+		close(g.idle)
+	}
+}
+
+// Wait until there are no pending tasks or the context done.
+func (g *pendingGroup) Wait(ctx context.Context) error {
+	g.mu.Lock()
+	if g.n == 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	idle := g.idle
+	g.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,107 @@
+package sses
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Hub_Shutdown(t *testing.T) {
+	h := NewHub(WithShutdownRetry(time.Second * 5))
+	srv := httptest.NewServer(h.Serve(WithServeExtractUserId(func(*http.Request) string { return "u1" })))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?channel=ch1")
+	require.NoError(t, err)
+	defer resp.Body.Close() // nolint: errcheck
+	buf := make([]byte, len(heartbeat))
+	_, err = io.ReadFull(resp.Body, buf)
+	require.NoError(t, err)
+	require.Equal(t, heartbeat, string(buf))
+
+	require.NoError(t, h.Broadcast(context.Background(), "ch1", &Event{Id: "e1", Event: "message", Data: "hello"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	require.NoError(t, h.Shutdown(ctx))
+	require.Equal(t, 0, h.SessionTotal())
+
+	// the pending event, then the final event, then the connection closed.
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t,
+		"id:e1\nevent:message\ndata:hello\n\n"+
+			"event:shutdown\nretry:5000\ndata:shutdown\n\n",
+		string(b),
+	)
+
+	// not accept new connections and pushes.
+	resp2, err := http.Get(srv.URL + "?channel=ch1")
+	require.NoError(t, err)
+	defer resp2.Body.Close() // nolint: errcheck
+	require.Equal(t, http.StatusServiceUnavailable, resp2.StatusCode)
+	require.ErrorIs(t, h.Broadcast(context.Background(), "ch1", &Event{Event: "message", Data: "hello"}), ErrHubClosed)
+	// shutdown twice
+	require.NoError(t, h.Shutdown(ctx))
+	require.NoError(t, h.Close())
+}
+
+func Test_Hub_Shutdown_Drain(t *testing.T) {
+	newSession := func() *Session {
+		return &Session{UserId: "u1", SessionId: NewSessionId(), Channel: "ch1", Message: make(chan *Event, 1)}
+	}
+
+	t.Run("drain in-flight pushes", func(t *testing.T) {
+		h := NewHub(WithRetryTimeout(time.Second))
+		s1 := newSession()
+		h.sessions.Add(s1)
+		// e1 buffered, e2 in-flight.
+		require.NoError(t, h.Broadcast(context.Background(), "ch1",
+			&Event{Id: "e1", Event: "message", Data: "1"},
+			&Event{Id: "e2", Event: "message", Data: "2"},
+		))
+		got := make(chan []string, 1)
+		go func() {
+			ids := []string{}
+			for e := range s1.Message {
+				ids = append(ids, e.Id+e.Event)
+				time.Sleep(time.Millisecond * 50)
+			}
+			got <- ids
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer cancel()
+		require.NoError(t, h.Shutdown(ctx))
+		// the final event after the in-flight pushes.
+		ids := <-got
+		require.Equal(t, []string{"e1message", "e2message", ShutdownEventType}, ids)
+		require.Equal(t, int64(2), h.Stats().ReqSuccess.Load())
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		h := NewHub(WithRetryTimeout(time.Second))
+		s1 := newSession()
+		h.sessions.Add(s1)
+		require.NoError(t, h.Broadcast(context.Background(), "ch1",
+			&Event{Id: "e1", Event: "message", Data: "1"},
+			&Event{Id: "e2", Event: "message", Data: "2"},
+		))
+		// nobody reads, the in-flight push can not finish.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+		defer cancel()
+		require.ErrorIs(t, h.Shutdown(ctx), context.DeadlineExceeded)
+		// the sessions are closed whatever, the final event replaces the oldest buffered event.
+		require.Equal(t, 0, h.SessionTotal())
+		e, ok := <-s1.Message
+		require.True(t, ok)
+		require.Equal(t, ShutdownEventType, e.Event)
+		require.Equal(t, uint(h.shutdownRetry.Milliseconds()), e.Retry)
+		_, ok = <-s1.Message
+		require.False(t, ok)
+	})
+}
@@ -2,13 +2,14 @@ package sses
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"net/http"
 	"sync/atomic"
 	"time"
 
-	"github.com/panjf2000/ants/v2"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const heartbeat = ": heartbeat\n\n"
@@ -16,6 +17,10 @@ const heartbeat = ": heartbeat\n\n"
 // DefaultEventType is the default event type if not provided in the event
 const DefaultEventType = "message"
 
+// GapEventType is the event type sent to the client if the last event id has been evicted from the store,
+// the data is the last event id, the retained events are resent after it.
+const GapEventType = "gap"
+
 // Stats stats
 type Stats struct {
 	ReqSuccess  atomic.Int64 // request success count
@@ -23,6 +28,12 @@ type Stats struct {
 	ReqTimeout  atomic.Int64 // request timeout count
 	SendSuccess atomic.Int64 // send success count
 	SendFailure atomic.Int64 // send failure count
+	Filtered    atomic.Int64 // filtered out by the session filter count
+
+	OverflowDropNewest atomic.Int64 // overflow, the newest event dropped count
+	OverflowDropOldest atomic.Int64 // overflow, the oldest buffered event dropped count
+	OverflowCoalesce   atomic.Int64 // overflow, the buffered event replaced by the newest event of the same type count
+	OverflowDisconnect atomic.Int64 // overflow, the slow session disconnected count
 }
 
 // Store defines the interface for storing and retrieving events
@@ -37,20 +48,43 @@ type Store interface {
 type Option func(*options)
 
 type options struct {
-	store        Store         // 数据存储, 如果设置了, 断开连接后, 会重新发送持久化的事件
-	bufferSize   int           // 消息缓冲区大小
-	heartbeat    time.Duration // 心跳间隔
-	retryLimit   int           // 重试次数
-	retryTimeout time.Duration // 重试超时时间
+	store           Store                     // 数据存储, 如果设置了, 断开连接后, 会重新发送持久化的事件
+	broker          Broker                    // 跨实例广播, 如果设置了, 事件会通过 broker 分发到所有实例
+	bufferSize      int                       // 消息缓冲区大小
+	heartbeat       time.Duration             // 心跳间隔
+	retryLimit      int                       // 重试次数
+	retryTimeout    time.Duration             // 重试超时时间
+	shutdownRetry   time.Duration             // 关闭时最终事件的重连间隔提示
+	overflowPolicy  OverflowPolicy            // 会话消息缓冲区满时的处理策略
+	channelOverflow map[string]OverflowPolicy // channel -> 会话消息缓冲区满时的处理策略
+	presence        PresenceBackend           // 在线状态后端, 如果设置了, 在线状态会跨实例聚合
+	presenceTTL     time.Duration             // 在线状态心跳过期时间
+	presenceEvents  bool                      // 用户上线/下线时是否广播 presence.join/presence.leave 事件
+	limits          sessionLimits             // 会话数量限制
+	encoder         Encoder                   // 事件数据编码器, 为空时在推送时按默认方式渲染
+	eventEncoders   map[string]Encoder        // event type -> 事件数据编码器, 覆盖 encoder
+	ack             bool                      // 是否开启投递确认
+	ackEventTypes   map[string]struct{}       // 需要确认的事件类型, 为空时所有事件都需要确认
+	meterProvider   metric.MeterProvider      // 指标, 为空时使用全局的 MeterProvider
+	tracerProvider  trace.TracerProvider      // 链路追踪, 为空时使用全局的 TracerProvider
+	metricsLabel    func(string) string       // 指标的频道属性映射, 为空时使用频道本身
+	metricsMaxLabel int                       // 指标的频道属性最多的不同值, 超出时为 MetricsChannelOther
 }
 
 func defaultOptions() *options {
 	return &options{
-		store:        nil,
-		bufferSize:   1000,
-		heartbeat:    time.Second * 30,
-		retryLimit:   3,
-		retryTimeout: time.Second * 3,
+		store:           nil,
+		bufferSize:      1000,
+		heartbeat:       time.Second * 30,
+		retryLimit:      3,
+		retryTimeout:    time.Second * 3,
+		shutdownRetry:   time.Second * 3,
+		overflowPolicy:  OverflowPolicy_Retry,
+		channelOverflow: make(map[string]OverflowPolicy),
+		presenceTTL:     time.Second * 30,
+		eventEncoders:   make(map[string]Encoder),
+		ackEventTypes:   make(map[string]struct{}),
+		metricsMaxLabel: 1000,
 	}
 }
 
@@ -68,6 +102,13 @@ func WithStore(store Store) Option {
 	}
 }
 
+// WithBroker set broker for cross-instance fan-out.
+func WithBroker(broker Broker) Option {
+	return func(h *options) {
+		h.broker = broker
+	}
+}
+
 // WithBufferSize set message events buffer size
 func WithBufferSize(size int) Option {
 	return func(h *options) {
@@ -104,21 +145,165 @@ func WithRetryTimeout(t time.Duration) Option {
 	}
 }
 
+// WithShutdownRetry set the `retry:` hint of the final event sent when the hub shutdown.
+func WithShutdownRetry(t time.Duration) Option {
+	return func(h *options) {
+		if t > 0 {
+			h.shutdownRetry = t
+		}
+	}
+}
+
+// WithOverflowPolicy set the overflow policy when the session message buffer is full.
+func WithOverflowPolicy(p OverflowPolicy) Option {
+	return func(h *options) {
+		h.overflowPolicy = p
+	}
+}
+
+// WithChannelOverflowPolicy set the overflow policy of the channel, it overrides the hub's.
+func WithChannelOverflowPolicy(channel string, p OverflowPolicy) Option {
+	return func(h *options) {
+		h.channelOverflow[channel] = p
+	}
+}
+
+// WithPresence set the presence backend for aggregating the presence across the hub nodes.
+func WithPresence(backend PresenceBackend) Option {
+	return func(h *options) {
+		h.presence = backend
+	}
+}
+
+// WithPresenceTTL set the presence heartbeat ttl, the local presence is reported every ttl/3.
+func WithPresenceTTL(t time.Duration) Option {
+	return func(h *options) {
+		if t > 0 {
+			h.presenceTTL = t
+		}
+	}
+}
+
+// WithPresenceEvents set whether to broadcast the presence.join/presence.leave events to the channel
+// when the user goes online or offline, the events are not persisted to the store.
+func WithPresenceEvents(enabled bool) Option {
+	return func(h *options) {
+		h.presenceEvents = enabled
+	}
+}
+
+// WithMaxSessionsPerUser set the max sessions of each user, 0 means unlimited.
+// the policy decides to reject the new session or evict the oldest sessions of the user.
+func WithMaxSessionsPerUser(n int, policy LimitPolicy) Option {
+	return func(h *options) {
+		h.limits.perUser = sessionLimit{max: max(n, 0), policy: policy}
+	}
+}
+
+// WithMaxSessionsPerChannel set the max sessions which subscribe each channel (may be wildcard, counted by the
+// subscribed channel), 0 means unlimited. the policy decides to reject the new session or evict the oldest sessions
+// of the channel.
+func WithMaxSessionsPerChannel(n int, policy LimitPolicy) Option {
+	return func(h *options) {
+		h.limits.perChannel = sessionLimit{max: max(n, 0), policy: policy}
+	}
+}
+
+// WithMaxSessions set the max sessions of the hub, 0 means unlimited.
+// the policy decides to reject the new session or evict the oldest sessions.
+func WithMaxSessions(n int, policy LimitPolicy) Option {
+	return func(h *options) {
+		h.limits.global = sessionLimit{max: max(n, 0), policy: policy}
+	}
+}
+
+// WithEncoder set the event data encoder of the hub, the data is encoded once when publishing.
+func WithEncoder(enc Encoder) Option {
+	return func(h *options) {
+		h.encoder = enc
+	}
+}
+
+// WithEventEncoder set the event data encoder of the event type, it overrides the hub's.
+func WithEventEncoder(eventType string, enc Encoder) Option {
+	return func(h *options) {
+		h.eventEncoders[eventType] = enc
+	}
+}
+
+// WithAck enable the delivery acknowledgements (at-least-once) of the events published to the users
+// (Publish, PublishSession), the events of the types (all types if empty) are kept pending per user until acked,
+// and redelivered on reconnect. the store must implement AckStore.
+func WithAck(eventTypes ...string) Option {
+	return func(h *options) {
+		h.ack = true
+		for _, eventType := range eventTypes {
+			h.ackEventTypes[eventType] = struct{}{}
+		}
+	}
+}
+
 // Hub event center, manage client connections, receive user events, and broadcast them to online users
 type Hub struct {
-	sessions *SessionManager
-	stats    *Stats
+	sessions    *SessionManager
+	stats       *Stats
+	node        string        // 节点id, 用于 broker 跳过自己发布的消息及在线状态上报
+	unsubscribe func()        // 取消 broker 订阅
+	closed      atomic.Bool   // 是否已关闭
+	pending     pendingGroup  // 进行中的推送
+	done        chan struct{} // 关闭时关闭, 中止进行中的推送
+	serving     pendingGroup  // 进行中的连接
+	reporter    presenceReporter
+	ackStore    AckStore     // 投递确认存储, 开启投递确认时有效
+	metrics     hubMetrics   // 指标
+	tracer      trace.Tracer // 链路追踪
+	lockOnSend  bool         // 是否有重写会话缓冲区的背压策略, 推送时需要持有会话锁
 	options
 }
 
 // NewHub create a new event center
 func NewHub(opts ...Option) *Hub {
 	o := defaultOptions().apply(opts...)
-	return &Hub{
-		sessions: NewSessionManager(),
-		stats:    &Stats{},
-		options:  *o,
+	h := &Hub{
+		sessions:    NewSessionManager(),
+		stats:       &Stats{},
+		node:        NextId(),
+		unsubscribe: func() {},
+		done:        make(chan struct{}),
+		reporter: presenceReporter{
+			reported: make(map[string]struct{}),
+			notify:   make(chan struct{}, 1),
+		},
+		options: *o,
+	}
+	h.sessions.limits = h.limits
+	h.lockOnSend = h.overflowPolicy.rewritesBuffer()
+	for _, p := range h.channelOverflow {
+		h.lockOnSend = h.lockOnSend || p.rewritesBuffer()
+	}
+	if err := h.initTelemetry(); err != nil {
+		slog.Error("sses: init telemetry failure", slog.Any("error", err))
+	}
+	if h.ack {
+		if as, ok := h.store.(AckStore); ok {
+			h.ackStore = as
+		} else {
+			slog.Error("sses: ack disabled", slog.Any("error", ErrAckUnsupported))
+		}
+	}
+	if h.presence != nil || h.presenceEvents {
+		h.sessions.onPresence = h.onPresenceChange
+		go h.presenceLoop()
+	}
+	if h.broker != nil {
+		unsubscribe, err := h.broker.Subscribe(context.Background(), h.onBrokerMessage)
+		if err != nil {
+			slog.Error("sses: broker subscribe failure", slog.Any("error", err))
+		} else {
+			h.unsubscribe = unsubscribe
+		}
 	}
+	return h
 }
 
 // SessionTotal get session total
@@ -132,29 +317,13 @@ func (h *Hub) SessionTotalByChannel(channel string) int {
 // Stats return the stats
 func (h *Hub) Stats() *Stats { return h.stats }
 
-// Close event center and stop all worker
-func (h *Hub) Close() error { return nil }
-
 // Broadcast events to the channel.
 func (h *Hub) Broadcast(ctx context.Context, channel string, events ...*Event) error {
-	sessions := h.sessions.Collect(channel)
-	for _, e := range events {
-		if !isValidEvent(e) {
-			continue
-		}
-		if e.Id == "" {
-			e.Id = NewEventId()
-		}
-		if h.store != nil {
-			if err := h.store.Save(ctx, channel, e); err != nil {
-				return fmt.Errorf("save event failure, %w", err)
-			}
-		}
-		for _, ses := range sessions {
-			h.tryPublish(ctx, ses, e, true)
-		}
-	}
-	return nil
+	return h.dispatch(ctx, &BrokerMessage{
+		Target:  BrokerTarget_Broadcast,
+		Channel: channel,
+		Events:  events,
+	}, true, true)
 }
 
 // Publish events to specified users who subscribe the channel.
@@ -168,25 +337,14 @@ func (h *Hub) PublishSync(ctx context.Context, channel, userId string, events ..
 }
 
 func (h *Hub) publish(ctx context.Context, channel, userId string, async bool, events ...*Event) error {
-	sessions := h.sessions.CollectForUser(channel, userId)
-	for _, e := range events {
-		if !isValidEvent(e) {
-			continue
-		}
-		if e.Id == "" {
-			e.Id = NewEventId()
-		}
-		if h.store != nil {
-			if err := h.store.Save(ctx, channel, e); err != nil {
-				return fmt.Errorf("save event failure, %w", err)
-			}
-		}
-		for _, ses := range sessions {
-			h.tryPublish(ctx, ses, e, async)
-		}
-	}
-	return nil
+	return h.dispatch(ctx, &BrokerMessage{
+		Target:  BrokerTarget_User,
+		Channel: channel,
+		UserId:  userId,
+		Events:  events,
+	}, async, true)
 }
+
 func (h *Hub) PublishSession(ctx context.Context, channel, userId, sessionId string, events ...*Event) error {
 	return h.publishSession(ctx, channel, userId, sessionId, true, events...)
 }
@@ -196,26 +354,128 @@ func (h *Hub) PublishSessionSync(ctx context.Context, channel, userId, sessionId
 }
 
 func (h *Hub) publishSession(ctx context.Context, channel, userId, sessionId string, async bool, events ...*Event) error {
-	for _, e := range events {
+	return h.dispatch(ctx, &BrokerMessage{
+		Target:    BrokerTarget_Session,
+		Channel:   channel,
+		UserId:    userId,
+		SessionId: sessionId,
+		Events:    events,
+	}, async, true)
+}
+
+// dispatch save the events if persist, push them to the local sessions,
+// then fan-out to the other hub nodes through the broker.
+func (h *Hub) dispatch(ctx context.Context, msg *BrokerMessage, async, persist bool) (err error) {
+	ctx, span := h.startPublishSpan(ctx, msg)
+	defer func() { endSpan(span, err) }()
+	h.pending.Add()
+	defer h.pending.Done()
+	if h.closed.Load() {
+		return ErrHubClosed
+	}
+	sessions := h.collect(msg)
+	events := make([]*Event, 0, len(msg.Events))
+	for _, e := range msg.Events {
 		if !isValidEvent(e) {
 			continue
 		}
 		if e.Id == "" {
 			e.Id = NewEventId()
 		}
-		if h.store != nil {
-			err := h.store.Save(ctx, channel, e)
-			if err != nil {
-				return fmt.Errorf("save event failure: %v", err)
+		encoded, err := h.encode(e)
+		if err != nil {
+			return fmt.Errorf("encode event data failure, %w", err)
+		}
+		if persist && h.store != nil {
+			if err := h.store.Save(ctx, msg.Channel, encoded); err != nil {
+				return fmt.Errorf("save event failure, %w", err)
+			}
+		}
+		if persist && msg.Target != BrokerTarget_Broadcast && h.requireAck(encoded) {
+			if err := h.ackStore.SavePending(ctx, msg.UserId, msg.Channel, encoded); err != nil {
+				return fmt.Errorf("save pending event failure, %w", err)
 			}
 		}
-		for _, ses := range h.sessions.CollectForUserSession(channel, userId, sessionId) {
-			h.tryPublish(ctx, ses, e, async)
+		pushed := *encoded
+		pushed.Channel = msg.Channel
+		for _, ses := range sessions {
+			h.tryPublishAccepted(ctx, ses, &pushed, async)
+		}
+		events = append(events, encoded)
+	}
+	if h.broker != nil && len(events) > 0 {
+		m := *msg
+		m.Origin = h.node
+		m.Events = events
+		if err := h.broker.Publish(ctx, &m); err != nil {
+			return fmt.Errorf("broker publish failure, %w", err)
 		}
 	}
 	return nil
 }
 
+// encode the event data with the encoder of the event type, it returns the encoded copy,
+// or the event itself if no encoder.
+func (h *Hub) encode(e *Event) (*Event, error) {
+	enc, ok := h.eventEncoders[e.Event]
+	if !ok {
+		enc = h.encoder
+	}
+	if enc == nil {
+		return e, nil
+	}
+	data, err := enc.Encode(e.Data)
+	if err != nil {
+		return nil, err
+	}
+	encoded := *e
+	encoded.Data = data
+	return &encoded, nil
+}
+
+// onBrokerMessage push the events from the broker to the local sessions,
+// the messages published by the node itself are skipped, they have been pushed locally.
+func (h *Hub) onBrokerMessage(msg *BrokerMessage) {
+	h.pending.Add()
+	defer h.pending.Done()
+	if h.closed.Load() || msg.Origin == h.node {
+		return
+	}
+	ctx := context.Background()
+	sessions := h.collect(msg)
+	for _, e := range msg.Events {
+		if e == nil {
+			continue
+		}
+		pushed := *e
+		pushed.Channel = msg.Channel
+		for _, ses := range sessions {
+			h.tryPublishAccepted(ctx, ses, &pushed, true)
+		}
+	}
+}
+
+// collect the local sessions of the message target.
+func (h *Hub) collect(msg *BrokerMessage) []*Session {
+	switch msg.Target {
+	case BrokerTarget_User:
+		return h.sessions.CollectForUser(msg.Channel, msg.UserId)
+	case BrokerTarget_Session:
+		return h.sessions.CollectForUserSession(msg.Channel, msg.UserId, msg.SessionId)
+	default:
+		return h.sessions.Collect(msg.Channel)
+	}
+}
+
+// tryPublishAccepted publish the event if the session filter accepts it.
+func (h *Hub) tryPublishAccepted(ctx context.Context, ses *Session, e *Event, async bool) {
+	if !ses.Accept(e) {
+		h.stats.Filtered.Add(1)
+		return
+	}
+	h.tryPublish(ctx, ses, e, async)
+}
+
 func (h *Hub) tryPublish(ctx context.Context, ses *Session, e *Event, async bool) {
 	defer func() {
 		if e := recover(); e != nil {
@@ -223,25 +483,16 @@ func (h *Hub) tryPublish(ctx context.Context, ses *Session, e *Event, async bool
 			slog.ErrorContext(ctx, "tryPublish cause panic", slog.Any("error", e))
 		}
 	}()
-	select {
-	case ses.Message <- e:
-		h.stats.ReqSuccess.Add(1)
-	default:
-		if async {
-			err := ants.Submit(func() {
-				h.tryPushWithTimeout(context.Background(), ses, e)
-			})
-			if err != nil {
-				slog.ErrorContext(ctx, "tryPublish submit task failure", slog.Any("error", err))
-			}
-		} else {
-			h.tryPushWithTimeout(ctx, ses, e)
-		}
+	start := time.Now()
+	if h.trySend(ses, e) {
+		h.onEnqueued(ctx, e, start)
+		return
 	}
+	h.overflow(ctx, ses, e, start, async)
 }
 
 // Asynchronous retry push with timeout logic
-func (h *Hub) tryPushWithTimeout(ctx context.Context, ses *Session, e *Event) {
+func (h *Hub) tryPushWithTimeout(ctx context.Context, ses *Session, e *Event, start time.Time) {
 	defer func() {
 		if e := recover(); e != nil {
 			h.stats.ReqFailure.Add(1)
@@ -253,10 +504,13 @@ func (h *Hub) tryPushWithTimeout(ctx context.Context, ses *Session, e *Event) {
 	for range h.retryLimit {
 		select {
 		case ses.Message <- e:
-			h.stats.ReqSuccess.Add(1)
+			h.onEnqueued(ctx, e, start)
 			return
 		case <-t.C:
 			t.Reset(h.retryTimeout)
+		case <-h.done: // 已关闭, 放弃推送
+			h.onDropped(ctx, e, DropReason_Closed, 1)
+			return
 		}
 	}
 	slog.WarnContext(ctx, "push timeout",
@@ -266,15 +520,23 @@ func (h *Hub) tryPushWithTimeout(ctx context.Context, ses *Session, e *Event) {
 		slog.String("eventId", e.Id),
 		slog.String("eventType", e.Event),
 	)
-	h.stats.ReqFailure.Add(1)
-	h.stats.ReqTimeout.Add(1)
+	h.onTimeout(ctx, e)
 }
 
 // resend events to specified client after reconnecting
-func (h *Hub) resendEvents(ctx context.Context, writer http.ResponseWriter, channel, eventType, lastEventId string) {
+func (h *Hub) resendEvents(ctx context.Context, sw *sessionWriter, channel, eventType, lastEventId string) {
 	pageSize := 100
 	for {
 		events, err := h.store.ListByLastId(ctx, channel, eventType, lastEventId, pageSize)
+		if errors.Is(err, ErrGapDetected) && lastEventId != "" {
+			// 事件已被淘汰, 通知客户端后从最旧的事件开始重发
+			gap := &Event{Event: GapEventType, Data: lastEventId}
+			if err = sw.write(gap); err != nil {
+				slog.Warn("publish gap event failure!", slog.Any("error", err))
+			}
+			lastEventId = ""
+			continue
+		}
 		if err != nil {
 			slog.Warn("ListByLastId events error", slog.Any("error", err))
 			return
@@ -284,13 +546,20 @@ func (h *Hub) resendEvents(ctx context.Context, writer http.ResponseWriter, chan
 		}
 		for _, e := range events {
 			lastEventId = e.Id
-			if err = e.Render(writer); err != nil {
+			resent := *e
+			resent.Channel = channel
+			if !sw.session.Accept(&resent) {
+				continue
+			}
+			if err = sw.Render(&resent); err != nil {
 				slog.Warn("publish event failure!",
 					slog.Any("error", err),
 					slog.String("eventId", e.Id),
 					slog.String("eventType", e.Event),
 				)
+				continue
 			}
+			h.onReplayed(ctx, &resent, ReplaySource_Store)
 		}
 		if len(events) < pageSize {
 			return
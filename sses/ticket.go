@@ -0,0 +1,221 @@
+package sses
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/thinkgos/proc/lookup"
+
+	"github.com/thinkgos/proc-extra/limiter/verified"
+)
+
+// ErrInvalidTicket the ticket is invalid, expired, consumed or not bound to the channel.
+var ErrInvalidTicket = errors.New("sses: invalid ticket")
+
+// TicketScene the scene of the connection ticket.
+type TicketScene string
+
+// Value implements verified.SceneValuer.
+func (s TicketScene) Value() string { return string(s) }
+
+// DefaultTicketScene the default scene of the connection ticket.
+const DefaultTicketScene TicketScene = "sse-ticket"
+
+// Ticket issues and consumes the connection tickets, the browsers' EventSource can't set
+// the `Authorization` header, the client requests a ticket with the credential first,
+// then connects with the ticket in the query string.
+// the ticket is short-lived, single-use, and bound to the user id and channel, it is granted by
+// verified.TempGranter, such as verified.TempGrant (stateful) or verified.StatelessTempGrant (HMAC signed).
+type Ticket struct {
+	granter        verified.TempGranter[TicketScene]
+	scene          TicketScene
+	expires        time.Duration
+	maxActive      int
+	extractTicket  *lookup.Lookup
+	extractChannel *lookup.Lookup
+}
+
+// NewTicket new the connection ticket with the granter, the ticket expires in 30 seconds,
+// up to 10 active tickets per user and channel.
+func NewTicket(granter verified.TempGranter[TicketScene]) *Ticket {
+	return &Ticket{
+		granter:        granter,
+		scene:          DefaultTicketScene,
+		expires:        time.Second * 30,
+		maxActive:      10,
+		extractTicket:  lookup.NewLookup("query:ticket"),
+		extractChannel: lookup.NewLookup("query:channel"),
+	}
+}
+
+// NewHmacTicket new the connection ticket signed via HMAC, see verified.NewHmacSigner.
+// the nonce backend makes the ticket single-use, such as the redis store of verified, it panics if nil.
+func NewHmacTicket(method string, key []byte, nonce verified.NonceBackend) *Ticket {
+	if nonce == nil {
+		panic("sses: hmac ticket requires the nonce backend to be single-use")
+	}
+	granter := verified.NewStatelessTempGrant[TicketScene](verified.NewHmacSigner(method, key)).
+		SetKeyPrefix("sses:ticket:nonce:").
+		SetNonceBackend(nonce)
+	return NewTicket(granter)
+}
+
+// SetScene sets the scene of the granter.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (t *Ticket) SetScene(scene TicketScene) *Ticket {
+	t.scene = scene
+	return t
+}
+
+// SetExpires sets the ticket lifetime.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (t *Ticket) SetExpires(d time.Duration) *Ticket {
+	if d > 0 {
+		t.expires = d
+	}
+	return t
+}
+
+// SetMaxActive sets the max active tickets per user and channel, the oldest tickets beyond it are revoked,
+// only the stateful granter supports it.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (t *Ticket) SetMaxActive(n int) *Ticket {
+	if n > 0 {
+		t.maxActive = n
+	}
+	return t
+}
+
+// SetExtractTicket sets the lookup to extract the ticket from the request, default `query:ticket`.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (t *Ticket) SetExtractTicket(l *lookup.Lookup) *Ticket {
+	t.extractTicket = l
+	return t
+}
+
+// SetExtractChannel sets the lookup to extract the channel from the request, default `query:channel`,
+// it should be the same as WithServeExtractChannel.
+// NOTE: This method is NOT safe for concurrent use. It should only be called during initialization.
+func (t *Ticket) SetExtractChannel(l *lookup.Lookup) *Ticket {
+	t.extractChannel = l
+	return t
+}
+
+// ticketClaims the user id and channel which the ticket bound to.
+type ticketClaims struct {
+	UserId  string `json:"u"`
+	Channel string `json:"c"`
+}
+
+// Issue a ticket bound to the user id and channel (the raw channel value of the request, may be comma separated).
+func (t *Ticket) Issue(ctx context.Context, userId, channel string) (string, error) {
+	claims := &ticketClaims{UserId: userId, Channel: channel}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	// the encoded claims as the grant id, the token is bound to the whole claims.
+	// the claims are not secret, a wrong token must never revoke the other tickets of the grant id,
+	// which TempGrantStorageBackend guarantees.
+	encodedClaims := base64.RawURLEncoding.EncodeToString(b)
+	token, err := t.granter.Issue(ctx, t.scene, encodedClaims,
+		verified.WithKeyExpires(t.expires),
+		verified.WithMaxActive(t.maxActive),
+	)
+	if err != nil {
+		return "", err
+	}
+	return encodedClaims + "." + token, nil
+}
+
+// Consume the ticket for the channel, it returns the user id which the ticket bound to.
+// it returns ErrInvalidTicket if the ticket is invalid, expired, consumed or not bound to the channel.
+func (t *Ticket) Consume(ctx context.Context, ticket, channel string) (string, error) {
+	encodedClaims, token, ok := strings.Cut(ticket, ".")
+	if !ok {
+		return "", ErrInvalidTicket
+	}
+	b, err := base64.RawURLEncoding.DecodeString(encodedClaims)
+	if err != nil {
+		return "", ErrInvalidTicket
+	}
+	var claims ticketClaims
+	if err = json.Unmarshal(b, &claims); err != nil || claims.UserId == "" || claims.Channel != channel {
+		return "", ErrInvalidTicket
+	}
+	ok, err = t.granter.Consume(ctx, t.scene, encodedClaims, token)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrInvalidTicket
+	}
+	return claims.UserId, nil
+}
+
+// ExtractUserId returns the extractor for WithServeExtractUserId, it validates and consumes the ticket
+// of the request, returns the user id which the ticket bound to, or empty if the ticket is invalid.
+func (t *Ticket) ExtractUserId() func(*http.Request) string {
+	return func(r *http.Request) string {
+		ticket := t.extractTicket.ExtractValueOr(r, "")
+		if ticket == "" {
+			return ""
+		}
+		userId, err := t.Consume(r.Context(), ticket, t.extractChannel.ExtractValueOr(r, ""))
+		if err != nil {
+			if !errors.Is(err, ErrInvalidTicket) {
+				slog.WarnContext(r.Context(), "sses: consume ticket failure", slog.Any("error", err))
+			}
+			return ""
+		}
+		return userId
+	}
+}
+
+// TicketResponse the response of the ticket issue endpoint.
+type TicketResponse struct {
+	Ticket    string `json:"ticket"`    // 连接票据
+	ExpiresIn int64  `json:"expiresIn"` // 有效期, 单位: 秒
+}
+
+// IssueHandler returns the ticket issue endpoint, the request should be authenticated,
+// extractUserId extracts the user id from the credential (such as the `Authorization` header),
+// the channel is extracted the same as the connection. it responds the TicketResponse JSON,
+// 405 if the method is not POST, 401 if the user id is empty, 400 if the channel is empty.
+func (t *Ticket) IssueHandler(extractUserId func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userId := extractUserId(r)
+		if userId == "" {
+			http.Error(w, "userId is empty", http.StatusUnauthorized)
+			return
+		}
+		channel := t.extractChannel.ExtractValueOr(r, "")
+		if len(splitChannels(channel)) == 0 {
+			http.Error(w, "channel is empty", http.StatusBadRequest)
+			return
+		}
+		ticket, err := t.Issue(r.Context(), userId, channel)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "sses: issue ticket failure", slog.Any("error", err))
+			http.Error(w, "issue ticket failure", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(&TicketResponse{
+			Ticket:    ticket,
+			ExpiresIn: int64(t.expires / time.Second),
+		})
+	})
+}
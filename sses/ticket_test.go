@@ -0,0 +1,175 @@
+package sses
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thinkgos/proc-extra/limiter/verified"
+	redisV9 "github.com/thinkgos/proc-extra/limiter/verified/redis/v9"
+	"github.com/thinkgos/proc-extra/limiter/verified/tests"
+)
+
+func Test_Ticket(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	ctx := context.Background()
+	store := redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	grantStore := redisV9.NewRedisTempGrantStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	for name, ticket := range map[string]*Ticket{
+		"temp grant": NewTicket(verified.NewTempGrant[TicketScene](new(tests.TestTempGrantProvider), grantStore)),
+		"hmac":       NewHmacTicket("hmacsha256", []byte("secret"), store),
+	} {
+		t.Run(name, func(t *testing.T) {
+			tk, err := ticket.Issue(ctx, "u1", "ch1")
+			require.NoError(t, err)
+			// bound to the channel
+			_, err = ticket.Consume(ctx, tk, "ch2")
+			require.ErrorIs(t, err, ErrInvalidTicket)
+			// tampered
+			_, err = ticket.Consume(ctx, "x"+tk, "ch1")
+			require.ErrorIs(t, err, ErrInvalidTicket)
+			_, err = ticket.Consume(ctx, "invalid", "ch1")
+			require.ErrorIs(t, err, ErrInvalidTicket)
+			// the claims rewritten to the same `userId:channel` concatenation.
+			colonTk, err := ticket.Issue(ctx, "u1", "b:c")
+			require.NoError(t, err)
+			_, token, _ := strings.Cut(colonTk, ".")
+			forged := base64.RawURLEncoding.EncodeToString([]byte(`{"u":"u1:b","c":"c"}`)) + "." + token
+			_, err = ticket.Consume(ctx, forged, "c")
+			require.ErrorIs(t, err, ErrInvalidTicket)
+
+			userId, err := ticket.Consume(ctx, tk, "ch1")
+			require.NoError(t, err)
+			require.Equal(t, "u1", userId)
+			// single-use
+			_, err = ticket.Consume(ctx, tk, "ch1")
+			require.ErrorIs(t, err, ErrInvalidTicket)
+
+			// multiple active tickets
+			tk1, err := ticket.Issue(ctx, "u1", "ch1")
+			require.NoError(t, err)
+			tk2, err := ticket.Issue(ctx, "u1", "ch1")
+			require.NoError(t, err)
+			// the claims of the victim with a garbage token never revoke the active tickets.
+			victim := base64.RawURLEncoding.EncodeToString([]byte(`{"u":"u1","c":"ch1"}`))
+			for range 3 {
+				_, err = ticket.Consume(ctx, victim+".garbage", "ch1")
+				require.ErrorIs(t, err, ErrInvalidTicket)
+			}
+			for _, v := range []string{tk2, tk1} {
+				userId, err = ticket.Consume(ctx, v, "ch1")
+				require.NoError(t, err)
+				require.Equal(t, "u1", userId)
+			}
+		})
+	}
+
+	t.Run("nil nonce", func(t *testing.T) {
+		require.Panics(t, func() { NewHmacTicket("hmacsha256", []byte("secret"), nil) })
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		ticket := NewTicket(verified.NewTempGrant[TicketScene](new(tests.TestTempGrantProvider), store)).
+			SetExpires(time.Second)
+		tk, err := ticket.Issue(ctx, "u1", "ch1")
+		require.NoError(t, err)
+		mr.FastForward(time.Second * 2)
+		_, err = ticket.Consume(ctx, tk, "ch1")
+		require.ErrorIs(t, err, ErrInvalidTicket)
+	})
+}
+
+func Test_Serve_Ticket(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	store := redisV9.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ticket := NewHmacTicket("hmacsha256", []byte("secret"), store)
+	h := NewHub()
+	defer h.Close() // nolint: errcheck
+
+	mux := http.NewServeMux()
+	mux.Handle("/ticket", ticket.IssueHandler(func(r *http.Request) string {
+		if r.Header.Get("Authorization") != "Bearer token-u1" {
+			return ""
+		}
+		return "u1"
+	}))
+	mux.Handle("/events", h.Serve(WithServeExtractUserId(ticket.ExtractUserId())))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	issue := func(authorization string) (int, *TicketResponse) {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/ticket?channel=ch1", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", authorization)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint: errcheck
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		var v TicketResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&v))
+		return resp.StatusCode, &v
+	}
+	connect := func(ctx context.Context, channel, tk string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			srv.URL+"/events?channel="+channel+"&ticket="+url.QueryEscape(tk), nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// only POST is accepted.
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/ticket?channel=ch1", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer token-u1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	require.Equal(t, http.MethodPost, resp.Header.Get("Allow"))
+
+	status, _ := issue("Bearer other")
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, v := issue("Bearer token-u1")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, int64(30), v.ExpiresIn)
+
+	// the ticket is bound to the channel.
+	resp = connect(context.Background(), "ch2", v.Ticket)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp = connect(ctx, "ch1", v.Ticket)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	buf := make([]byte, len(heartbeat))
+	_, err = io.ReadFull(resp.Body, buf)
+	require.NoError(t, err)
+	require.Equal(t, 1, h.SessionTotalByChannel("ch1"))
+	cancel()
+	_ = resp.Body.Close()
+
+	// the ticket has been consumed.
+	resp = connect(context.Background(), "ch1", v.Ticket)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
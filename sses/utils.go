@@ -2,6 +2,7 @@ package sses
 
 import (
 	crand "crypto/rand"
+	"strings"
 	"time"
 
 	"github.com/oklog/ulid/v2"
@@ -16,3 +17,26 @@ func NextId() string {
 func NewEventId() string { return NextId() }
 
 func NewSessionId() string { return "sse-session-" + NextId() }
+
+// IsWildcardChannel reports whether the channel contains the wildcard `+` or `#`.
+func IsWildcardChannel(channel string) bool {
+	return strings.ContainsAny(channel, "+#")
+}
+
+// MatchChannel reports whether the channel matches the filter,
+// the filter levels are separated by `/`, `+` matches a single level, `#` matches the remaining levels.
+func MatchChannel(filter, channel string) bool {
+	if !IsWildcardChannel(filter) {
+		return filter == channel
+	}
+	fs, cs := strings.Split(filter, "/"), strings.Split(channel, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(cs) || (f != "+" && f != cs[i]) {
+			return false
+		}
+	}
+	return len(fs) == len(cs)
+}
@@ -9,3 +9,27 @@ func Test_Utils(t *testing.T) {
 	t.Log(NewEventId())
 	t.Log(NewSessionId())
 }
+
+func Test_MatchChannel(t *testing.T) {
+	tests := []struct {
+		filter  string
+		channel string
+		want    bool
+	}{
+		{"orders", "orders", true},
+		{"orders", "orders/1", false},
+		{"orders/+/status", "orders/1/status", true},
+		{"orders/+/status", "orders/1/other", false},
+		{"orders/+/status", "orders/status", false},
+		{"orders/+", "orders/1/status", false},
+		{"orders/#", "orders", true},
+		{"orders/#", "orders/1/status", true},
+		{"#", "orders/1", true},
+		{"+/+", "orders/1", true},
+	}
+	for _, tt := range tests {
+		if got := MatchChannel(tt.filter, tt.channel); got != tt.want {
+			t.Errorf("MatchChannel(%q, %q) = %v, want %v", tt.filter, tt.channel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package sses
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// ErrOriginNotAllowed the Origin of the WebSocket handshake is not allowed.
+var ErrOriginNotAllowed = errors.New("sses: origin not allowed")
+
+// SameOrigin reports whether the Origin of the request is the same host as the request,
+// the request without Origin (non-browser client) is allowed.
+func SameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// WebSocket frame types.
+const (
+	FrameType_Event     = "event"     // server -> client, the event
+	FrameType_Heartbeat = "heartbeat" // server -> client, the heartbeat
+	FrameType_Ping      = "ping"      // client -> server, the ping
+	FrameType_Pong      = "pong"      // server -> client, the pong reply
+)
+
+// Frame the WebSocket JSON frame.
+type Frame struct {
+	Type  string `json:"type"`            // 帧类型
+	Id    string `json:"id,omitempty"`    // 事件id, 多频道会话时为游标
+	Event string `json:"event,omitempty"` // 事件类型
+	Retry uint   `json:"retry,omitempty"` // 重连间隔, 单位: 毫秒
+	Data  string `json:"data,omitempty"`  // 事件数据, 与SSE的 data 字段相同
+}
+
+// NewEventFrame new the event frame, the data is the same as it rendered in the SSE data field.
+func NewEventFrame(e *Event) (*Frame, error) {
+	data, err := MarshalData(e.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &Frame{
+		Type:  FrameType_Event,
+		Id:    e.Id,
+		Event: e.Event,
+		Retry: e.Retry,
+		Data:  string(data),
+	}, nil
+}
+
+// ServeWebSocket serves a client connection over WebSocket, the session is registered the same as Serve,
+// the events are sent as the JSON Frame, the client can send the `{"type":"ping"}` frame, the server replies
+// the `{"type":"pong"}` frame. the Store replay and Stats are shared with Serve.
+func (h *Hub) ServeWebSocket(opts ...ServeOption) http.Handler {
+	opt := defaultServeOptions().apply(opts...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.closed.Load() {
+			opt.errFallback(w, r, ErrHubClosed)
+			return
+		}
+		h.serving.Add()
+		defer h.serving.Done()
+
+		//* 先检查 Origin, 防止跨站劫持, 且不消费请求中的票据
+		if !opt.checkOrigin(r) {
+			opt.errFallback(w, r, ErrOriginNotAllowed)
+			return
+		}
+		sr, err := h.extractSession(r, opt)
+		if err != nil {
+			opt.errFallback(w, r, err)
+			return
+		}
+		session := sr.session
+		//* 注册用户会话, 超出会话数量限制时拒绝握手
+		if err = h.sessions.TryAdd(session); err != nil {
+			opt.errFallback(w, r, err)
+			return
+		}
+		opt.onRegister(session)
+		defer func() {
+			//* 注销用户会话
+			opt.onDeregister(session)
+			h.sessions.Delete(session)
+		}()
+		srv := websocket.Server{
+			Handler: func(ws *websocket.Conn) {
+				h.serveWebSocket(ws, sr, opt)
+			},
+		}
+		srv.ServeHTTP(w, r)
+	})
+}
+
+func (h *Hub) serveWebSocket(ws *websocket.Conn, sr *sessionRequest, opt *serveOptions) {
+	ctx := ws.Request().Context()
+	session := sr.session
+	sw := newSessionWriter(session, func(e *Event) error {
+		f, err := NewEventFrame(e)
+		if err != nil {
+			return err
+		}
+		return websocket.JSON.Send(ws, f)
+	})
+
+	//* 读取客户端的帧, 回复ping
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		for {
+			var f Frame
+			if err := websocket.JSON.Receive(ws, &f); err != nil {
+				return
+			}
+			if f.Type == FrameType_Ping {
+				if err := websocket.JSON.Send(ws, &Frame{Type: FrameType_Pong}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	//* 重发旧的消息
+	h.resend(ctx, sw, sr)
+	// 会话连接成功, 发送一次心跳, 客户端连接成功
+	heartbeatFrame := &Frame{Type: FrameType_Heartbeat}
+	if err := websocket.JSON.Send(ws, heartbeatFrame); err != nil {
+		return
+	}
+
+	t := time.NewTimer(h.heartbeat)
+	defer t.Stop()
+	for {
+		select {
+		case e, ok := <-session.Message:
+			if !ok { // 关闭
+				return
+			}
+			if err := sw.Render(e); err != nil {
+				h.stats.SendFailure.Add(1)
+				slog.WarnContext(ctx, "websocket send event failure",
+					slog.Any("error", err),
+					slog.String("eventId", e.Id),
+					slog.String("eventType", e.Event),
+				)
+				return
+			}
+			h.stats.SendSuccess.Add(1)
+		case <-t.C:
+			// 发送心跳
+			if err := websocket.JSON.Send(ws, heartbeatFrame); err != nil {
+				return
+			}
+		case <-readDone:
+			return
+		}
+		t.Reset(h.heartbeat)
+	}
+}
@@ -0,0 +1,105 @@
+package sses
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func Test_ServeWebSocket(t *testing.T) {
+	h := NewHub(WithStore(NewMemoryStore(100)))
+	srv := httptest.NewServer(h.ServeWebSocket(WithServeExtractUserId(func(*http.Request) string { return "u1" })))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	ctx := context.Background()
+	require.NoError(t, h.Broadcast(ctx, "ch1",
+		&Event{Id: "e1", Event: "message", Data: "1"},
+		&Event{Id: "e2", Event: "message", Data: map[string]string{"name": "hello"}},
+	))
+
+	ws, err := websocket.Dial(wsURL+"?channel=ch1&lastEventId=e1", "", srv.URL)
+	require.NoError(t, err)
+	defer ws.Close() // nolint: errcheck
+	receive := func() *Frame {
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(time.Second*5)))
+		var f Frame
+		require.NoError(t, websocket.JSON.Receive(ws, &f))
+		return &f
+	}
+
+	// resend from the store, then the heartbeat.
+	require.Equal(t, &Frame{Type: FrameType_Event, Id: "e2", Event: "message", Data: `{"name":"hello"}`}, receive())
+	require.Equal(t, &Frame{Type: FrameType_Heartbeat}, receive())
+	require.Equal(t, 1, h.SessionTotal())
+
+	// client ping
+	require.NoError(t, websocket.JSON.Send(ws, &Frame{Type: FrameType_Ping}))
+	require.Equal(t, &Frame{Type: FrameType_Pong}, receive())
+
+	// live events
+	require.NoError(t, h.Publish(ctx, "ch1", "u1", &Event{Id: "e3", Event: "other", Data: "multi\nline"}))
+	require.Equal(t, &Frame{Type: FrameType_Event, Id: "e3", Event: "other", Data: "multi\nline"}, receive())
+	require.Equal(t, int64(1), h.Stats().SendSuccess.Load())
+
+	// shutdown, the final event then closed.
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+	require.NoError(t, h.Shutdown(shutdownCtx))
+	require.Equal(t, &Frame{Type: FrameType_Event, Event: ShutdownEventType, Retry: 3000, Data: "shutdown"}, receive())
+	var f Frame
+	require.Error(t, websocket.JSON.Receive(ws, &f))
+}
+
+func Test_ServeWebSocket_Reject(t *testing.T) {
+	h := NewHub()
+	defer h.Close() // nolint: errcheck
+	srv := httptest.NewServer(h.ServeWebSocket(
+		WithServeExtractUserId(func(*http.Request) string { return "u1" }),
+		WithServeCheckOrigin(func(r *http.Request) bool { return r.Header.Get("Origin") == "http://allowed" }),
+	))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	// channel is empty
+	_, err := websocket.Dial(wsURL, "", "http://allowed")
+	require.Error(t, err)
+	// origin not allowed
+	_, err = websocket.Dial(wsURL+"?channel=ch1", "", "http://other")
+	require.Error(t, err)
+
+	ws, err := websocket.Dial(wsURL+"?channel=ch1", "", "http://allowed")
+	require.NoError(t, err)
+	require.NoError(t, ws.Close())
+
+	t.Run("same origin by default", func(t *testing.T) {
+		srv := httptest.NewServer(h.ServeWebSocket(WithServeExtractUserId(func(*http.Request) string { return "u1" })))
+		defer srv.Close()
+		wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+		_, err := websocket.Dial(wsURL+"?channel=ch1", "", "http://evil.example")
+		require.Error(t, err)
+		// rejected by errFallback before extracting the session.
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "http://evil.example")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint: errcheck
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+		require.Equal(t, ErrOriginNotAllowed.Error(), string(body))
+
+		ws, err := websocket.Dial(wsURL+"?channel=ch1", "", srv.URL)
+		require.NoError(t, err)
+		require.NoError(t, ws.Close())
+	})
+}